@@ -0,0 +1,1115 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// MicroserviceSpec defines the desired state of Microservice
+type MicroserviceSpec struct {
+	// +optional
+	Ingress []Ingress `json:"ingress,omitempty"`
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+	// Environment variables of the container of the Microservice, set in
+	// the order of their names.
+	// +optional
+	Env map[string]string `json:"env,omitempty"`
+	// Environment variables of the container of the Microservice, set in the
+	// given order after those of env. Unlike env, values can be taken from
+	// Secrets, ConfigMaps or fields of the pod.
+	// +optional
+	EnvVars []corev1.EnvVar `json:"envVars,omitempty"`
+	// Secrets and ConfigMaps whose keys are all set as environment variables
+	// of the container of the Microservice. Variables of env and envVars
+	// take precedence.
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+	Image   string                 `json:"image"`
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+	// Number of replicas of the Deployment or StatefulSet. Ignored once the
+	// workload exists if the Microservice is autoscaled, and with the
+	// DaemonSet, CronJob and Job workload kinds. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Kind of the workload that runs the pods of the Microservice. When it
+	// changes, the workload of the previous kind is deleted once the new one
+	// rolled out. Defaults to Deployment.
+	// +optional
+	WorkloadKind WorkloadKind `json:"workloadKind,omitempty"`
+	// StatefulSet parameters. Only allowed with the StatefulSet workload
+	// kind.
+	// +optional
+	StatefulSet *StatefulSetSpec `json:"statefulSet,omitempty"`
+	// DaemonSet parameters. Only allowed with the DaemonSet workload kind.
+	// +optional
+	DaemonSet *DaemonSetSpec `json:"daemonSet,omitempty"`
+	// CronJob parameters. Required with the CronJob workload kind and not
+	// allowed otherwise.
+	// +optional
+	CronJob *CronJobSpec `json:"cronJob,omitempty"`
+	// Parameters of the Job, or of the Jobs started by the CronJob. Only
+	// allowed with the Job and CronJob workload kinds.
+	// +optional
+	Job *JobSpec `json:"job,omitempty"`
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Containers that run next to the container of the Microservice, such
+	// as log shippers or proxies. Their images are not part of canary or
+	// blue/green rollouts.
+	// +optional
+	Sidecars []Container `json:"sidecars,omitempty"`
+	// Containers that run to completion, in order, before the other
+	// containers of a pod are started.
+	// +optional
+	InitContainers []Container `json:"initContainers,omitempty"`
+	// Volumes of the pods, mounted into containers with volumeMounts.
+	// +optional
+	Volumes []Volume `json:"volumes,omitempty"`
+	// Volumes mounted into the container of the Microservice.
+	// +optional
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+	// Config files rendered into a ConfigMap owned by the Microservice and
+	// mounted into its container, keyed by file name. Changes to the files
+	// restart the pods.
+	// +optional
+	ConfigFiles map[string]string `json:"configFiles,omitempty"`
+	// Directory the config files are mounted at. Defaults to /etc/config.
+	// +optional
+	ConfigFilesMountPath string `json:"configFilesMountPath,omitempty"`
+	// Secrets and ConfigMaps referenced by env or volumes whose changes do
+	// not restart the pods. Changes to all other referenced Secrets and
+	// ConfigMaps roll out new pods.
+	// +optional
+	IgnoreChanges []DependencyReference `json:"ignoreChanges,omitempty"`
+	// PersistentVolumeClaims created and owned by the Microservice. They are
+	// mounted through a volume that names them in
+	// persistentVolumeClaim.claimName.
+	// +optional
+	PersistentVolumeClaims []PersistentVolumeClaim `json:"persistentVolumeClaims,omitempty"`
+	// Labels applied to every generated resource and used as the pod
+	// selector. Defaults to the app.kubernetes.io/name label set to the name
+	// of the Microservice.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// +optional
+	IngressEnabled bool `json:"ingressEnabled,omitempty"`
+	// Raw HorizontalPodAutoscaler spec. The scale target is always the
+	// Deployment of the Microservice. Prefer scaling unless a feature of the
+	// HorizontalPodAutoscaler is needed that it does not offer.
+	// +optional
+	Autoscaling *autoscalingv2.HorizontalPodAutoscalerSpec `json:"autoscaling,omitempty"`
+	// Simplified autoscaling configuration that is translated into a
+	// HorizontalPodAutoscaler. Mutually exclusive with autoscaling.
+	// +optional
+	Scaling *ScalingSpec `json:"scaling,omitempty"`
+	// How the Deployment replaces old pods with new ones. Defaults to a
+	// rolling update with a surge of one pod and no unavailable pods.
+	// +optional
+	Strategy *StrategySpec `json:"strategy,omitempty"`
+	// Roll the Deployment back to the pod template of its last successful
+	// rollout when a rollout fails, until the spec changes again.
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
+	// Canary rollout of new images. A new image is first run by a separate
+	// canary Deployment that receives a growing share of the ingress traffic
+	// before it is rolled out to the Deployment of the Microservice.
+	// +optional
+	Canary *CanarySpec `json:"canary,omitempty"`
+	// Job run with a new image before it is rolled out, for example to
+	// migrate a database. The Deployment keeps running the previous image
+	// until the hook succeeded. Only allowed with the Deployment workload
+	// kind.
+	// +optional
+	PreDeploy *PreDeployHook `json:"preDeploy,omitempty"`
+	// +optional
+	DisableServiceAccountCreation bool `json:"disableServiceAccountCreation,omitempty"`
+}
+
+// WorkloadKind is the kind of the workload that runs the pods of a
+// Microservice.
+// +kubebuilder:validation:Enum=Deployment;StatefulSet;DaemonSet;CronJob;Job
+type WorkloadKind string
+
+const (
+	// WorkloadKindDeployment runs the pods with a Deployment.
+	WorkloadKindDeployment WorkloadKind = "Deployment"
+	// WorkloadKindStatefulSet runs the pods with a StatefulSet, which gives
+	// each pod a stable name and its own PersistentVolumeClaims.
+	WorkloadKindStatefulSet WorkloadKind = "StatefulSet"
+	// WorkloadKindDaemonSet runs one pod on each eligible node with a
+	// DaemonSet, for node agents.
+	WorkloadKindDaemonSet WorkloadKind = "DaemonSet"
+	// WorkloadKindCronJob runs the pods to completion on a schedule with a
+	// CronJob.
+	WorkloadKindCronJob WorkloadKind = "CronJob"
+	// WorkloadKindJob runs the pods to completion once with a Job. The Job
+	// runs again whenever its pod template changes.
+	WorkloadKindJob WorkloadKind = "Job"
+)
+
+// DaemonSetSpec describes the DaemonSet of a Microservice.
+type DaemonSetSpec struct {
+	// Maximum number of nodes whose pod can be unavailable during an
+	// update, as a number or a percentage of the nodes. Defaults to 1.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// CronJobSpec describes the CronJob of a Microservice.
+type CronJobSpec struct {
+	// Schedule in cron format, for example "0 3 * * *"
+	Schedule string `json:"schedule"`
+	// What to do when a Job is due while the previous one is still
+	// running. Defaults to Allow.
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	// +optional
+	ConcurrencyPolicy batchv1.ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	// Do not start new Jobs. Running Jobs are not stopped.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// Seconds after its scheduled time within which a missed Job may still
+	// be started. Missed Jobs are not started late by default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+	// Number of successful Jobs to keep. Defaults to 3.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit,omitempty"`
+	// Number of failed Jobs to keep. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+}
+
+// JobSpec describes the Job of a Microservice, or the Jobs started by its
+// CronJob.
+type JobSpec struct {
+	// Number of times a failed pod is retried before the Job fails.
+	// Defaults to 6.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	// Seconds a Job may run before its pods are stopped and it fails.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+	// Whether a failed container is restarted in the same pod or a new pod
+	// is started. Defaults to OnFailure.
+	// +kubebuilder:validation:Enum=OnFailure;Never
+	// +optional
+	RestartPolicy corev1.RestartPolicy `json:"restartPolicy,omitempty"`
+}
+
+// StatefulSetSpec describes the StatefulSet of a Microservice.
+type StatefulSetSpec struct {
+	// Whether pods are created and deleted one at a time, in order, or all
+	// at once. Defaults to OrderedReady. Cannot be changed once set.
+	// +kubebuilder:validation:Enum=OrderedReady;Parallel
+	// +optional
+	PodManagementPolicy appsv1.PodManagementPolicyType `json:"podManagementPolicy,omitempty"`
+	// PersistentVolumeClaims created for every pod and mounted through
+	// volume mounts that name them. The claims are kept when pods or the
+	// StatefulSet are deleted. Cannot be changed once set.
+	// +optional
+	VolumeClaimTemplates []VolumeClaimTemplate `json:"volumeClaimTemplates,omitempty"`
+}
+
+// VolumeClaimTemplate is a PersistentVolumeClaim created for every pod of a
+// StatefulSet.
+type VolumeClaimTemplate struct {
+	// Name of the claim template, referenced by volume mounts
+	Name string `json:"name"`
+	// Requested storage size
+	Size resource.Quantity `json:"size"`
+	// Defaults to ReadWriteOnce.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	// Defaults to the default storage class of the cluster.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+// StrategySpec describes the rollout of a new version of a Microservice.
+type StrategySpec struct {
+	// Type of the rollout. Defaults to RollingUpdate.
+	// +optional
+	Type StrategyType `json:"type,omitempty"`
+	// Rolling update parameters. Not allowed with the Recreate type. With
+	// the BlueGreen type, they apply to changes of the spec other than the
+	// image, which are rolled out to the active Deployment in place.
+	// +optional
+	RollingUpdate *RollingUpdateStrategy `json:"rollingUpdate,omitempty"`
+	// Blue/green parameters. Only allowed with the BlueGreen type.
+	// +optional
+	BlueGreen *BlueGreenStrategy `json:"blueGreen,omitempty"`
+	// Minimum number of seconds a new pod must be ready before it counts as
+	// available. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+	// Maximum number of seconds a rollout may take to make progress before
+	// it is considered failed. Defaults to 600.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// Number of old ReplicaSets kept to allow a rollback. Defaults to 5.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+}
+
+// StrategyType is the way old pods are replaced during a rollout.
+// +kubebuilder:validation:Enum=RollingUpdate;Recreate;BlueGreen
+type StrategyType string
+
+const (
+	// RollingUpdateStrategyType gradually replaces old pods with new ones.
+	RollingUpdateStrategyType StrategyType = "RollingUpdate"
+	// RecreateStrategyType removes all old pods before new ones are created.
+	RecreateStrategyType StrategyType = "Recreate"
+	// BlueGreenStrategyType runs a new image on the idle one of two
+	// Deployments and switches the Service over to it once promoted.
+	BlueGreenStrategyType StrategyType = "BlueGreen"
+)
+
+// BlueGreenStrategy configures the BlueGreen strategy.
+type BlueGreenStrategy struct {
+	// Switch the Service to the preview as soon as it is available instead
+	// of waiting for the rollout annotation.
+	// +optional
+	AutoPromote bool `json:"autoPromote,omitempty"`
+	// How long the previously active Deployment keeps running after the
+	// Service switched away from it. Defaults to 30s.
+	// +optional
+	ScaleDownDelay *metav1.Duration `json:"scaleDownDelay,omitempty"`
+}
+
+// Volume is a volume of the pods of a Microservice. Exactly one source must
+// be set.
+type Volume struct {
+	// Name of the volume, referenced by volume mounts
+	Name string `json:"name"`
+	// +optional
+	ConfigMap *corev1.ConfigMapVolumeSource `json:"configMap,omitempty"`
+	// +optional
+	Secret *corev1.SecretVolumeSource `json:"secret,omitempty"`
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+	// +optional
+	Projected *corev1.ProjectedVolumeSource `json:"projected,omitempty"`
+	// +optional
+	PersistentVolumeClaim *corev1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+}
+
+// DependencyReference references a Secret or ConfigMap in the namespace of a
+// Microservice.
+type DependencyReference struct {
+	Kind DependencyKind `json:"kind"`
+	Name string         `json:"name"`
+}
+
+// DependencyKind is the kind of a referenced object.
+// +kubebuilder:validation:Enum=Secret;ConfigMap
+type DependencyKind string
+
+const (
+	// DependencyKindSecret references a Secret.
+	DependencyKindSecret DependencyKind = "Secret"
+	// DependencyKindConfigMap references a ConfigMap.
+	DependencyKindConfigMap DependencyKind = "ConfigMap"
+)
+
+// PersistentVolumeClaim is a PersistentVolumeClaim created and owned by a
+// Microservice.
+type PersistentVolumeClaim struct {
+	// Name of the PersistentVolumeClaim
+	Name string `json:"name"`
+	// Requested storage size
+	Size resource.Quantity `json:"size"`
+	// Defaults to ReadWriteOnce.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	// Defaults to the default storage class of the cluster.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// What happens to the claim when the Microservice is deleted or the claim
+	// is removed from the spec. Defaults to Retain.
+	// +optional
+	RetentionPolicy RetentionPolicy `json:"retentionPolicy,omitempty"`
+}
+
+// RetentionPolicy controls what happens to a PersistentVolumeClaim of a
+// Microservice once it is no longer needed.
+// +kubebuilder:validation:Enum=Retain;Delete
+type RetentionPolicy string
+
+const (
+	// RetentionPolicyRetain releases the claim, which is kept with its data.
+	RetentionPolicyRetain RetentionPolicy = "Retain"
+	// RetentionPolicyDelete deletes the claim.
+	RetentionPolicyDelete RetentionPolicy = "Delete"
+)
+
+// PreDeployHook describes the Job that is run with a new image before it is
+// rolled out. Its pod has the env, volumes and service account of the pods of
+// the Microservice.
+type PreDeployHook struct {
+	// Command of the hook. Defaults to the entrypoint of the image.
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Number of times a failed hook is retried. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	// Seconds the hook may run before it is stopped and fails.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+}
+
+// CanarySpec describes the canary rollout of a new image.
+type CanarySpec struct {
+	// Number of replicas of the canary Deployment. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Steps of the rollout, in order. The new image is promoted once the
+	// last step completes.
+	// +kubebuilder:validation:MinItems=1
+	Steps []CanaryStep `json:"steps"`
+}
+
+// CanaryStep routes a share of the traffic to the canary for some time.
+type CanaryStep struct {
+	// Percentage of the ingress traffic routed to the canary
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+	// How long to stay at this step once the canary is available. Only the
+	// last step may leave it out, in which case the rollout waits to be
+	// promoted.
+	// +optional
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
+// RollingUpdateStrategy bounds the number of pods during a rolling update.
+type RollingUpdateStrategy struct {
+	// Maximum number of pods that can be created over the desired number of
+	// replicas, as a number or a percentage of the replicas. Defaults to 1.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// Maximum number of pods that can be unavailable, as a number or a
+	// percentage of the replicas. Defaults to 0.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// ScalingSpec describes how the Deployment of a Microservice is scaled by a
+// HorizontalPodAutoscaler.
+type ScalingSpec struct {
+	// Lower limit for the number of replicas. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// Upper limit for the number of replicas
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// Target average CPU utilization, in percent of the requested CPU
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// Target average memory utilization, in percent of the requested memory
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+	// How quickly to add replicas. Defaults to Default.
+	// +optional
+	ScaleUp ScalingBehavior `json:"scaleUp,omitempty"`
+	// How quickly to remove replicas. Defaults to Default.
+	// +optional
+	ScaleDown ScalingBehavior `json:"scaleDown,omitempty"`
+}
+
+// ScalingBehavior is a preset for the scaling behavior of a
+// HorizontalPodAutoscaler in one direction.
+// +kubebuilder:validation:Enum=Default;Fast;Conservative;Disabled
+type ScalingBehavior string
+
+const (
+	// ScalingBehaviorDefault keeps the default behavior of the
+	// HorizontalPodAutoscaler.
+	ScalingBehaviorDefault ScalingBehavior = "Default"
+	// ScalingBehaviorFast reacts to load changes without stabilization.
+	ScalingBehaviorFast ScalingBehavior = "Fast"
+	// ScalingBehaviorConservative changes one replica at a time after a
+	// long stabilization window.
+	ScalingBehaviorConservative ScalingBehavior = "Conservative"
+	// ScalingBehaviorDisabled never scales in this direction.
+	ScalingBehaviorDisabled ScalingBehavior = "Disabled"
+)
+
+type Ingress struct {
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// +optional
+	Paths         []string `json:"paths,omitempty"`
+	Name          string   `json:"name"`
+	ContainerPort int32    `json:"containerPort"`
+	// Name of the sidecar that listens on containerPort, which must be one
+	// of its ports. Defaults to the container of the Microservice.
+	// +optional
+	Container string `json:"container,omitempty"`
+}
+
+// Container is an additional container of the pods of a Microservice.
+type Container struct {
+	// Name of the container, unique within the pod
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+	// Volumes of the Microservice mounted into the container.
+	// +optional
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+	// +optional
+	Ports []corev1.ContainerPort `json:"ports,omitempty"`
+	// Not allowed on init containers.
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// Not allowed on init containers.
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// MicroserviceStatus defines the observed state of Microservice
+type MicroserviceStatus struct {
+	// Represents the running state of the Microservice
+	// +optional
+	State RunningState `json:"state,omitempty"`
+	// The last observed error in the deployment of this Microservice
+	// +optional
+	Error string `json:"error,omitempty"`
+	// The generation of the Microservice that was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Number of pods of the Deployment
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// Label selector of the pods of the Deployment, for the scale
+	// subresource
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// Number of pods of the Deployment with a Ready condition
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// Number of pods of the Deployment running the desired pod template
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// The image of the last Deployment rollout that completed
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Conditions of the Microservice and the resources it owns
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// Changes made to generated resources outside of the Microservice spec
+	// +optional
+	Drift []ResourceDrift `json:"drift,omitempty"`
+	// Scaling state of the HorizontalPodAutoscaler, if autoscaling is enabled
+	// +optional
+	Autoscaling *AutoscalingStatus `json:"autoscaling,omitempty"`
+	// Revision of the Deployment that last rolled out successfully
+	// +optional
+	LastGoodRevision string `json:"lastGoodRevision,omitempty"`
+	// Last automatic rollback of a failed rollout
+	// +optional
+	Rollback *RollbackStatus `json:"rollback,omitempty"`
+	// Progress of the canary rollout, if canary is set
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+	// Progress of the blue/green rollout, if the BlueGreen strategy is used
+	// +optional
+	BlueGreen *BlueGreenStatus `json:"blueGreen,omitempty"`
+	// Last run of the pre-deploy hook, if one is configured
+	// +optional
+	PreDeploy *PreDeployStatus `json:"preDeploy,omitempty"`
+	// Runs of the Job or of the Jobs started by the CronJob, with the Job
+	// and CronJob workload kinds
+	// +optional
+	Job *JobStatus `json:"job,omitempty"`
+}
+
+// PreDeployStatus describes the last run of the pre-deploy hook of a
+// Microservice.
+type PreDeployStatus struct {
+	// Image the hook ran with
+	Image string `json:"image"`
+	// Name of the Job of the hook
+	JobName string `json:"jobName"`
+	// Progress of the hook
+	Phase PreDeployPhase `json:"phase"`
+	// Why the hook failed
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Command that prints the logs of the hook
+	Logs string `json:"logs"`
+	// When the Job of the hook started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// When the hook succeeded
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// PreDeployPhase is the progress of the pre-deploy hook.
+type PreDeployPhase string
+
+const (
+	// PreDeployRunning is the phase of a hook that has not finished yet.
+	PreDeployRunning PreDeployPhase = "Running"
+	// PreDeploySucceeded is the phase of a hook that completed, after which
+	// its image is rolled out.
+	PreDeploySucceeded PreDeployPhase = "Succeeded"
+	// PreDeployFailed is the phase of a hook that failed. Its image is not
+	// rolled out.
+	PreDeployFailed PreDeployPhase = "Failed"
+)
+
+// JobStatus describes the runs of the Job of a Microservice or of the Jobs
+// started by its CronJob.
+type JobStatus struct {
+	// Number of running pods of the Job, or of running Jobs of the CronJob
+	// +optional
+	Active int32 `json:"active,omitempty"`
+	// Number of pods of the Job that succeeded
+	// +optional
+	Succeeded int32 `json:"succeeded,omitempty"`
+	// Number of pods of the Job that failed
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+	// When the Job completed successfully
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// When the CronJob last started a Job
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// When a Job of the CronJob last completed successfully
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+}
+
+// BlueGreenStatus is the progress of the blue/green rollout of a
+// Microservice.
+type BlueGreenStatus struct {
+	// Phase of the rollout
+	Phase BlueGreenPhase `json:"phase"`
+	// Color of the Deployment that receives the traffic of the Microservice
+	// +optional
+	ActiveColor BlueGreenColor `json:"activeColor,omitempty"`
+	// Image run by the active Deployment
+	ActiveImage string `json:"activeImage"`
+	// Image of the last preview
+	// +optional
+	PreviewImage string `json:"previewImage,omitempty"`
+	// When the Service last switched to the active Deployment
+	// +optional
+	SwitchedAt *metav1.Time `json:"switchedAt,omitempty"`
+}
+
+// BlueGreenColor is one of the two Deployments of a blue/green rollout.
+// +kubebuilder:validation:Enum=Blue;Green
+type BlueGreenColor string
+
+const (
+	// BlueGreenBlue is the Deployment named after the Microservice.
+	BlueGreenBlue BlueGreenColor = "Blue"
+	// BlueGreenGreen is the Deployment named after the Microservice with a
+	// -green suffix.
+	BlueGreenGreen BlueGreenColor = "Green"
+)
+
+// BlueGreenPhase is the phase of a blue/green rollout.
+type BlueGreenPhase string
+
+const (
+	// BlueGreenActive is the phase while only the active Deployment runs.
+	BlueGreenActive BlueGreenPhase = "Active"
+	// BlueGreenPreview is the phase while the idle Deployment runs the
+	// preview image and waits to be promoted.
+	BlueGreenPreview BlueGreenPhase = "Preview"
+	// BlueGreenScalingDown is the phase after the Service switched to the
+	// promoted Deployment, until the previously active one is scaled down
+	// after the scale down delay.
+	BlueGreenScalingDown BlueGreenPhase = "ScalingDown"
+	// BlueGreenAborted is the phase once the preview was aborted. The
+	// active image keeps running until the image changes.
+	BlueGreenAborted BlueGreenPhase = "Aborted"
+)
+
+// CanaryStatus is the progress of the canary rollout of a Microservice.
+type CanaryStatus struct {
+	// Phase of the rollout
+	Phase CanaryPhase `json:"phase"`
+	// Image run by the Deployment of the Microservice
+	StableImage string `json:"stableImage"`
+	// Image of the last canary
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Index of the current step
+	// +optional
+	Step int32 `json:"step,omitempty"`
+	// Percentage of the ingress traffic routed to the canary
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
+	// When the current step started, once the canary was available
+	// +optional
+	StepStartedAt *metav1.Time `json:"stepStartedAt,omitempty"`
+}
+
+// CanaryPhase is the phase of a canary rollout.
+type CanaryPhase string
+
+const (
+	// CanaryProgressing is the phase while the canary goes through the
+	// steps of the rollout.
+	CanaryProgressing CanaryPhase = "Progressing"
+	// CanaryPaused is the phase while the canary waits to be promoted at
+	// the last step.
+	CanaryPaused CanaryPhase = "Paused"
+	// CanaryPromoted is the phase once the canary image was rolled out to
+	// the Deployment of the Microservice, or when there is nothing to roll
+	// out.
+	CanaryPromoted CanaryPhase = "Promoted"
+	// CanaryAborted is the phase once the canary was aborted. The stable
+	// image keeps running until the image changes.
+	CanaryAborted CanaryPhase = "Aborted"
+)
+
+// RollbackStatus describes an automatic rollback of a failed Deployment
+// rollout.
+type RollbackStatus struct {
+	// Generation of the Microservice whose rollout failed. The restored pod
+	// template is kept until the generation changes.
+	Generation int64 `json:"generation"`
+	// Revision of the Deployment whose rollout failed
+	FailedRevision string `json:"failedRevision"`
+	// Image of the failed rollout
+	// +optional
+	FailedImage string `json:"failedImage,omitempty"`
+	// Revision of the Deployment whose pod template was restored
+	RestoredRevision string `json:"restoredRevision"`
+	// Image of the restored pod template
+	// +optional
+	RestoredImage string `json:"restoredImage,omitempty"`
+	// Why the rollout was considered failed
+	// +optional
+	Message string `json:"message,omitempty"`
+	// When the rollback was started
+	Time metav1.Time `json:"time"`
+}
+
+// AutoscalingStatus is the scaling state reported by the
+// HorizontalPodAutoscaler of a Microservice.
+type AutoscalingStatus struct {
+	// Number of replicas of the Deployment last seen by the autoscaler
+	CurrentReplicas int32 `json:"currentReplicas"`
+	// Number of replicas the autoscaler last computed for the Deployment
+	DesiredReplicas int32 `json:"desiredReplicas"`
+	// When the autoscaler last changed the number of replicas
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+}
+
+// ResourceDrift describes a change made to a generated resource outside of
+// the Microservice spec.
+type ResourceDrift struct {
+	// Kind of the changed resource
+	Kind string `json:"kind"`
+	// Name of the changed resource
+	Name string `json:"name"`
+	// Paths of the fields that differ from the last applied configuration
+	Paths []string `json:"paths"`
+	// When the change was detected
+	DetectedAt metav1.Time `json:"detectedAt"`
+	// Whether the change was reverted, as opposed to only reported
+	// +optional
+	Reverted bool `json:"reverted,omitempty"`
+}
+
+// Condition types of a Microservice.
+const (
+	// ConditionAvailable is true when the Deployment has the minimum number
+	// of available replicas.
+	ConditionAvailable = "Available"
+	// ConditionProgressing is true while the Deployment is rolling out a
+	// new pod template or scaling.
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded is true when a child resource could not be
+	// reconciled or the Deployment rollout failed.
+	ConditionDegraded = "Degraded"
+
+	// ConditionServiceAccountReconciled is true when the ServiceAccount and
+	// its token Secret match the spec.
+	ConditionServiceAccountReconciled = "ServiceAccountReconciled"
+	// ConditionDeploymentReconciled is true when the Deployment matches the
+	// spec.
+	ConditionDeploymentReconciled = "DeploymentReconciled"
+	// ConditionStatefulSetReconciled is true when the StatefulSet and its
+	// headless Service match the spec.
+	ConditionStatefulSetReconciled = "StatefulSetReconciled"
+	// ConditionDaemonSetReconciled is true when the DaemonSet matches the
+	// spec.
+	ConditionDaemonSetReconciled = "DaemonSetReconciled"
+	// ConditionCronJobReconciled is true when the CronJob matches the spec.
+	ConditionCronJobReconciled = "CronJobReconciled"
+	// ConditionJobReconciled is true when the Job matches the spec.
+	ConditionJobReconciled = "JobReconciled"
+	// ConditionAutoscalingReconciled is true when the HorizontalPodAutoscaler
+	// matches the spec.
+	ConditionAutoscalingReconciled = "HorizontalPodAutoscalerReconciled"
+	// ConditionServiceReconciled is true when the Service matches the spec.
+	ConditionServiceReconciled = "ServiceReconciled"
+	// ConditionIngressReconciled is true when the Ingresses match the spec.
+	ConditionIngressReconciled = "IngressReconciled"
+	// ConditionCanaryReconciled is true when the canary resources match the
+	// canary rollout.
+	ConditionCanaryReconciled = "CanaryReconciled"
+	// ConditionBlueGreenReconciled is true when the preview resources match
+	// the blue/green rollout.
+	ConditionBlueGreenReconciled = "BlueGreenReconciled"
+	// ConditionPreDeployReconciled is true when the Job of the pre-deploy
+	// hook matches the spec.
+	ConditionPreDeployReconciled = "PreDeployReconciled"
+	// ConditionPersistentVolumeClaimsReconciled is true when the
+	// PersistentVolumeClaims match the spec.
+	ConditionPersistentVolumeClaimsReconciled = "PersistentVolumeClaimsReconciled"
+	// ConditionConfigMapReconciled is true when the ConfigMap of the config
+	// files matches the spec.
+	ConditionConfigMapReconciled = "ConfigMapReconciled"
+)
+
+// Condition reasons of a Microservice.
+const (
+	ReasonReconcileSucceeded = "ReconcileSucceeded"
+	ReasonReconcileFailed    = "ReconcileFailed"
+	ReasonRolloutComplete    = "RolloutComplete"
+	ReasonRolloutInProgress  = "RolloutInProgress"
+	ReasonRolloutFailed      = "RolloutFailed"
+	ReasonRolledBack         = "RolledBack"
+	ReasonPreDeployFailed    = "PreDeployFailed"
+	ReasonAsExpected         = "AsExpected"
+)
+
+// RunningState is the state of the Microservice
+type RunningState string
+
+// Running States:
+// If any changes are being made on the Microservice, the state will be
+// set to reconciling. Once all resources are applied the state follows the
+// rollout of the Deployment: ready when the minimum number of replicas is
+// available and stable when every replica runs the current pod template. A
+// failed rollout keeps the state at reconciling and sets the error, unless it
+// is rolled back automatically.
+const (
+	// Reconciling is the state when the Microservice is being updated
+	Reconciling RunningState = "reconciling"
+	// Ready is the state when the Microservice is ready to start serving
+	// traffic but not fully stable.
+	Ready RunningState = "ready"
+	// Stable is the state when the Microservice is fully running
+	Stable RunningState = "stable"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+//+kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.status.image`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Microservice is the Schema for the microservices API
+type Microservice struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MicroserviceSpec   `json:"spec,omitempty"`
+	Status MicroserviceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// MicroserviceList contains a list of Microservice
+type MicroserviceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Microservice `json:"items"`
+}
+
+const (
+	// DefaultReplicas is the number of replicas used when spec.replicas is
+	// not set.
+	DefaultReplicas int32 = 1
+	// NameLabel is the selector label derived from the Microservice name
+	// when spec.labels is empty.
+	NameLabel = "app.kubernetes.io/name"
+	// TrackLabel tells the pods of the canary or green Deployment apart
+	// from the stable pods of a Microservice.
+	TrackLabel = "microservice.example.com/track"
+	// TrackStable is the TrackLabel value of the stable pods.
+	TrackStable = "stable"
+	// TrackCanary is the TrackLabel value of the canary pods.
+	TrackCanary = "canary"
+	// TrackGreen is the TrackLabel value of the pods of the green
+	// Deployment of a blue/green rollout. The pods of the blue Deployment
+	// are the stable pods.
+	TrackGreen = "green"
+	// ClaimOwnerLabel marks the PersistentVolumeClaims of a Microservice,
+	// with its name as value, so that they are listed without listing every
+	// claim of the namespace.
+	ClaimOwnerLabel = "microservice.example.com/claim-owner"
+	// PreDeployLabel marks the pod of the pre-deploy hook of a
+	// Microservice, with its name as value. The pod does not have the labels
+	// of the Microservice, so that it receives no traffic.
+	PreDeployLabel = "microservice.example.com/pre-deploy"
+)
+
+// DriftPolicy controls what the operator does when a generated resource was
+// changed outside of the Microservice spec.
+type DriftPolicy string
+
+const (
+	// DriftPolicyAnnotation selects the DriftPolicy of a Microservice.
+	DriftPolicyAnnotation = "microservice.example.com/drift-policy"
+	// DriftPolicyEnforce reverts changes made to generated resources. This
+	// is the default.
+	DriftPolicyEnforce DriftPolicy = "enforce"
+	// DriftPolicyReportOnly logs changes made to generated resources but
+	// leaves them in place until the Microservice spec changes.
+	DriftPolicyReportOnly DriftPolicy = "report-only"
+)
+
+// GetDriftPolicy returns the DriftPolicy selected by the drift policy
+// annotation, defaulting to DriftPolicyEnforce.
+func (d *Microservice) GetDriftPolicy() DriftPolicy {
+	if DriftPolicy(d.GetAnnotations()[DriftPolicyAnnotation]) == DriftPolicyReportOnly {
+		return DriftPolicyReportOnly
+	}
+
+	return DriftPolicyEnforce
+}
+
+// ConfigHashAnnotation is set on the pod template to the hash of the config
+// files, so that changing them rolls out new pods.
+const ConfigHashAnnotation = "microservice.example.com/config-hash"
+
+// DependencyHashAnnotation is set on the pod template to the hash of the
+// referenced Secrets and ConfigMaps, so that changing them rolls out new
+// pods.
+const DependencyHashAnnotation = "microservice.example.com/dependency-hash"
+
+// ConfigFilesVolume is the name of the pod volume of the config files. Volumes
+// of the spec may not use it.
+const ConfigFilesVolume = "config-files"
+
+// RetentionPolicyAnnotation records the RetentionPolicy on a
+// PersistentVolumeClaim of a Microservice, so that it can be applied once the
+// claim was removed from the spec.
+const RetentionPolicyAnnotation = "microservice.example.com/retention-policy"
+
+// GetRetentionPolicy returns the RetentionPolicy of the claim, defaulting to
+// RetentionPolicyRetain.
+func (p *PersistentVolumeClaim) GetRetentionPolicy() RetentionPolicy {
+	if p.RetentionPolicy == RetentionPolicyDelete {
+		return RetentionPolicyDelete
+	}
+
+	return RetentionPolicyRetain
+}
+
+// GetAccessModes returns the access modes of the claim, defaulting to
+// ReadWriteOnce.
+func (p *PersistentVolumeClaim) GetAccessModes() []corev1.PersistentVolumeAccessMode {
+	if len(p.AccessModes) == 0 {
+		return []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+
+	return p.AccessModes
+}
+
+// RolloutAction is a manual decision on a canary or blue/green rollout.
+type RolloutAction string
+
+const (
+	// RolloutActionAnnotation requests a RolloutAction. It is removed by the
+	// operator once the action was taken.
+	RolloutActionAnnotation = "microservice.example.com/rollout"
+	// RolloutActionPromote rolls the new image out to the Deployment of the
+	// Microservice, skipping the remaining canary steps or switching the
+	// Service to the blue/green preview.
+	RolloutActionPromote RolloutAction = "promote"
+	// RolloutActionAbort removes the canary or preview and keeps the stable
+	// image.
+	RolloutActionAbort RolloutAction = "abort"
+)
+
+// GetRolloutAction returns the RolloutAction requested by the rollout
+// annotation, if any.
+func (d *Microservice) GetRolloutAction() RolloutAction {
+	return RolloutAction(d.GetAnnotations()[RolloutActionAnnotation])
+}
+
+// SetDefaults fills in the fields of the Microservice spec that the
+// operator would otherwise have to guess when rendering child resources.
+func (d *Microservice) SetDefaults() {
+	if d.Spec.Replicas == nil {
+		replicas := DefaultReplicas
+		d.Spec.Replicas = &replicas
+	}
+
+	if len(d.Spec.Labels) == 0 {
+		d.Spec.Labels = map[string]string{
+			NameLabel: d.GetName(),
+		}
+	}
+
+	for _, ing := range d.Spec.Ingress {
+		if ing.Container == "" {
+			port := intstr.FromInt(int(ing.ContainerPort))
+			setProbePortDefault(d.Spec.LivenessProbe, port)
+			setProbePortDefault(d.Spec.ReadinessProbe, port)
+			break
+		}
+	}
+
+	for i := range d.Spec.Sidecars {
+		sidecar := &d.Spec.Sidecars[i]
+		if len(sidecar.Ports) > 0 {
+			port := intstr.FromInt(int(sidecar.Ports[0].ContainerPort))
+			setProbePortDefault(sidecar.LivenessProbe, port)
+			setProbePortDefault(sidecar.ReadinessProbe, port)
+		}
+	}
+
+	if d.Spec.Autoscaling != nil {
+		d.Spec.Autoscaling.ScaleTargetRef = d.ScaleTargetRef()
+	}
+}
+
+// AutoscalingEnabled returns true if the Microservice is scaled by a
+// HorizontalPodAutoscaler.
+func (d *Microservice) AutoscalingEnabled() bool {
+	return d.Spec.Autoscaling != nil || d.Spec.Scaling != nil
+}
+
+// GetWorkloadKind returns the kind of the workload of the Microservice,
+// defaulting to WorkloadKindDeployment.
+func (d *Microservice) GetWorkloadKind() WorkloadKind {
+	if d.Spec.WorkloadKind == "" {
+		return WorkloadKindDeployment
+	}
+
+	return d.Spec.WorkloadKind
+}
+
+// Scalable returns true if the number of pods of the Microservice can be
+// set, by its replicas or a HorizontalPodAutoscaler.
+func (d *Microservice) Scalable() bool {
+	kind := d.GetWorkloadKind()
+	return kind == WorkloadKindDeployment || kind == WorkloadKindStatefulSet
+}
+
+// BatchWorkload returns true if the pods of the Microservice run to
+// completion, with the CronJob or Job workload kind.
+func (d *Microservice) BatchWorkload() bool {
+	kind := d.GetWorkloadKind()
+	return kind == WorkloadKindCronJob || kind == WorkloadKindJob
+}
+
+// BlueGreenEnabled returns true if new images are rolled out with the
+// BlueGreen strategy.
+func (d *Microservice) BlueGreenEnabled() bool {
+	return d.Spec.Strategy != nil && d.Spec.Strategy.Type == BlueGreenStrategyType
+}
+
+// ScaleTargetRef returns the reference to the workload of the Microservice
+// that its HorizontalPodAutoscaler scales.
+func (d *Microservice) ScaleTargetRef() autoscalingv2.CrossVersionObjectReference {
+	return autoscalingv2.CrossVersionObjectReference{
+		APIVersion: "apps/v1",
+		Kind:       string(d.GetWorkloadKind()),
+		Name:       d.GetName(),
+	}
+}
+
+// setProbePortDefault sets the port of a probe handler that was left empty
+// to the given port.
+func setProbePortDefault(probe *corev1.Probe, port intstr.IntOrString) {
+	if probe == nil {
+		return
+	}
+
+	var empty intstr.IntOrString
+	if probe.HTTPGet != nil && probe.HTTPGet.Port == empty {
+		probe.HTTPGet.Port = port
+	}
+	if probe.TCPSocket != nil && probe.TCPSocket.Port == empty {
+		probe.TCPSocket.Port = port
+	}
+	if probe.GRPC != nil && probe.GRPC.Port == 0 {
+		probe.GRPC.Port = port.IntVal
+	}
+}
+
+func init() {
+	SchemeBuilder.Register(&Microservice{}, &MicroserviceList{})
+}
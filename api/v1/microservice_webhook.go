@@ -0,0 +1,872 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	cron "github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// maxCronJobNameLength is the longest name of a CronJob whose Jobs can be
+// named after it.
+const maxCronJobNameLength = 52
+
+// maxPreDeployNameLength is the longest name of a Microservice whose
+// pre-deploy Job, named <name>-predeploy, can be used as a label value.
+const maxPreDeployNameLength = 53
+
+// log is for logging in this package.
+var microservicelog = logf.Log.WithName("microservice-resource")
+
+func (r *Microservice) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-microservice-microservice-example-com-v1-microservice,mutating=true,failurePolicy=fail,sideEffects=None,groups=microservice.microservice.example.com,resources=microservices,verbs=create;update,versions=v1,name=mmicroservice.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Microservice{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Microservice) Default() {
+	microservicelog.Info("default", "name", r.Name)
+
+	r.SetDefaults()
+}
+
+//+kubebuilder:webhook:path=/validate-microservice-microservice-example-com-v1-microservice,mutating=false,failurePolicy=fail,sideEffects=None,groups=microservice.microservice.example.com,resources=microservices,verbs=create;update,versions=v1,name=vmicroservice.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Microservice{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Microservice) ValidateCreate() error {
+	microservicelog.Info("validate create", "name", r.Name)
+
+	return r.validateMicroservice(nil)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Microservice) ValidateUpdate(old runtime.Object) error {
+	microservicelog.Info("validate update", "name", r.Name)
+
+	oldMicroservice, _ := old.(*Microservice)
+	return r.validateMicroservice(oldMicroservice)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Microservice) ValidateDelete() error {
+	return nil
+}
+
+// validateMicroservice validates the Microservice. On update, old is the
+// Microservice before the update, and nil otherwise.
+func (r *Microservice) validateMicroservice(old *Microservice) error {
+	allErrs := r.Spec.validate(field.NewPath("spec"))
+	allErrs = append(allErrs, r.validateContainers(field.NewPath("spec"))...)
+	allErrs = append(allErrs, r.validateWorkload(old, field.NewPath("spec"))...)
+	allErrs = append(allErrs, r.validatePersistentVolumeClaimUpdate(old, field.NewPath("spec"))...)
+	allErrs = append(allErrs, r.validatePreDeploy(field.NewPath("spec"))...)
+
+	if policy, ok := r.GetAnnotations()[DriftPolicyAnnotation]; ok {
+		switch DriftPolicy(policy) {
+		case DriftPolicyEnforce, DriftPolicyReportOnly:
+		default:
+			allErrs = append(allErrs, field.NotSupported(
+				field.NewPath("metadata", "annotations").Key(DriftPolicyAnnotation),
+				policy,
+				[]string{string(DriftPolicyEnforce), string(DriftPolicyReportOnly)},
+			))
+		}
+	}
+
+	if action, ok := r.GetAnnotations()[RolloutActionAnnotation]; ok {
+		switch RolloutAction(action) {
+		case RolloutActionPromote, RolloutActionAbort:
+		default:
+			allErrs = append(allErrs, field.NotSupported(
+				field.NewPath("metadata", "annotations").Key(RolloutActionAnnotation),
+				action,
+				[]string{string(RolloutActionPromote), string(RolloutActionAbort)},
+			))
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return k8sErrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "Microservice"}, r.Name, allErrs)
+}
+
+func (s *MicroserviceSpec) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if s.Image == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("image"), "image must not be empty"))
+	}
+
+	envNames := map[string]bool{}
+	for name := range s.Env {
+		envNames[name] = true
+	}
+	allErrs = append(allErrs, validateEnvVars(s.EnvVars, envNames, fldPath.Child("envVars"))...)
+	allErrs = append(allErrs, validateEnvFrom(s.EnvFrom, fldPath.Child("envFrom"))...)
+
+	for i, ref := range s.IgnoreChanges {
+		idxPath := fldPath.Child("ignoreChanges").Index(i)
+
+		switch ref.Kind {
+		case DependencyKindSecret, DependencyKindConfigMap:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("kind"), ref.Kind, []string{string(DependencyKindSecret), string(DependencyKindConfigMap)}))
+		}
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "name must not be empty"))
+		}
+	}
+
+	allErrs = append(allErrs, s.validateVolumes(fldPath)...)
+	allErrs = append(allErrs, validateVolumeMounts(s.VolumeMounts, s.volumeNames(), fldPath.Child("volumeMounts"))...)
+
+	if s.Autoscaling != nil && s.Scaling != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("scaling"), "scaling and autoscaling are mutually exclusive"))
+	}
+	if s.Scaling != nil {
+		allErrs = append(allErrs, s.Scaling.validate(fldPath.Child("scaling"))...)
+	}
+	if s.Strategy != nil {
+		allErrs = append(allErrs, s.Strategy.validate(fldPath.Child("strategy"))...)
+	}
+	if s.Canary != nil {
+		allErrs = append(allErrs, s.Canary.validate(fldPath.Child("canary"))...)
+		if s.Strategy != nil && s.Strategy.Type == BlueGreenStrategyType {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("canary"), "canary and the BlueGreen strategy are mutually exclusive"))
+		}
+	}
+
+	ingressNames := map[string]bool{}
+	for i, ing := range s.Ingress {
+		idxPath := fldPath.Child("ingress").Index(i)
+
+		if ing.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "ingress name must not be empty"))
+		} else {
+			for _, msg := range validation.IsValidPortName(ing.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), ing.Name, msg))
+			}
+		}
+
+		if ingressNames[ing.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), ing.Name))
+		}
+		ingressNames[ing.Name] = true
+
+		for _, msg := range validation.IsValidPortNum(int(ing.ContainerPort)) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("containerPort"), ing.ContainerPort, msg))
+		}
+	}
+
+	return allErrs
+}
+
+// validateWorkload validates the fields of the spec that depend on the
+// workload kind.
+func (r *Microservice) validateWorkload(old *Microservice, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	s := &r.Spec
+	kind := r.GetWorkloadKind()
+
+	if s.StatefulSet != nil && kind != WorkloadKindStatefulSet {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("statefulSet"), "may only be specified when workloadKind is StatefulSet"))
+	}
+	if s.DaemonSet != nil && kind != WorkloadKindDaemonSet {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("daemonSet"), "may only be specified when workloadKind is DaemonSet"))
+	}
+	if s.CronJob != nil && kind != WorkloadKindCronJob {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("cronJob"), "may only be specified when workloadKind is CronJob"))
+	}
+	if s.Job != nil && !r.BatchWorkload() {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("job"), "may only be specified when workloadKind is Job or CronJob"))
+	}
+
+	switch kind {
+	case WorkloadKindStatefulSet:
+		allErrs = append(allErrs, r.validateStatefulSet(old, fldPath)...)
+	case WorkloadKindDaemonSet:
+		allErrs = append(allErrs, r.validateDaemonSet(fldPath)...)
+	case WorkloadKindCronJob, WorkloadKindJob:
+		allErrs = append(allErrs, r.validateBatch(fldPath)...)
+	}
+
+	return allErrs
+}
+
+// validateStatefulSet validates a Microservice with the StatefulSet workload
+// kind. The StatefulSet fields that Kubernetes does not allow to change are
+// compared with old, if set.
+func (r *Microservice) validateStatefulSet(old *Microservice, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	s := &r.Spec
+
+	// canary and blue/green rollouts run Deployments next to the workload,
+	// and rollbacks restore the ReplicaSets of a Deployment
+	if s.Canary != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("canary"), "is not supported by StatefulSets"))
+	}
+	if s.AutoRollback {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("autoRollback"), "is not supported by StatefulSets"))
+	}
+	if s.Strategy != nil {
+		if s.Strategy.Type != "" && s.Strategy.Type != RollingUpdateStrategyType {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("strategy", "type"), s.Strategy.Type, []string{string(RollingUpdateStrategyType)}))
+		}
+		if s.Strategy.RollingUpdate != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("strategy", "rollingUpdate"), "is not supported by StatefulSets"))
+		}
+		if s.Strategy.ProgressDeadlineSeconds != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("strategy", "progressDeadlineSeconds"), "is not supported by StatefulSets"))
+		}
+	}
+
+	statefulSet := s.StatefulSet
+	if statefulSet == nil {
+		statefulSet = &StatefulSetSpec{}
+	}
+	allErrs = append(allErrs, statefulSet.validate(s, fldPath.Child("statefulSet"))...)
+
+	if old == nil || old.GetWorkloadKind() != WorkloadKindStatefulSet {
+		return allErrs
+	}
+	oldStatefulSet := old.Spec.StatefulSet
+	if oldStatefulSet == nil {
+		oldStatefulSet = &StatefulSetSpec{}
+	}
+	if statefulSet.PodManagementPolicy != oldStatefulSet.PodManagementPolicy {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("statefulSet", "podManagementPolicy"), "may not be changed"))
+	}
+	if !reflect.DeepEqual(statefulSet.VolumeClaimTemplates, oldStatefulSet.VolumeClaimTemplates) {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("statefulSet", "volumeClaimTemplates"), "may not be changed"))
+	}
+
+	return allErrs
+}
+
+// validatePreDeploy validates the pre-deploy hook of a Microservice. Canary
+// and blue/green rollouts run a new image before the Deployment does, so
+// they cannot wait for the hook.
+func (r *Microservice) validatePreDeploy(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	s := &r.Spec
+	if s.PreDeploy == nil {
+		return allErrs
+	}
+
+	if kind := r.GetWorkloadKind(); kind != WorkloadKindDeployment {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("preDeploy"), fmt.Sprintf("is not supported by %ss", kind)))
+	}
+	if s.Canary != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("preDeploy"), "may not be specified with a canary rollout"))
+	}
+	if r.BlueGreenEnabled() {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("preDeploy"), "may not be specified with the BlueGreen strategy"))
+	}
+	if len(r.Name) > maxPreDeployNameLength {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "name"), r.Name, fmt.Sprintf("must be no more than %d characters with a pre-deploy hook", maxPreDeployNameLength)))
+	}
+
+	return allErrs
+}
+
+// validateDaemonSet validates a Microservice with the DaemonSet workload
+// kind. The number of its pods follows the number of nodes, so it cannot be
+// scaled, and node agents are not exposed through an Ingress.
+func (r *Microservice) validateDaemonSet(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	s := &r.Spec
+
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"ingress", len(s.Ingress) > 0},
+		{"ingressEnabled", s.IngressEnabled},
+		{"autoscaling", s.Autoscaling != nil},
+		{"scaling", s.Scaling != nil},
+		{"autoRollback", s.AutoRollback},
+		{"canary", s.Canary != nil},
+	} {
+		if f.set {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child(f.name), "is not supported by DaemonSets"))
+		}
+	}
+	if s.Strategy != nil {
+		if s.Strategy.Type != "" && s.Strategy.Type != RollingUpdateStrategyType {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("strategy", "type"), s.Strategy.Type, []string{string(RollingUpdateStrategyType)}))
+		}
+		if s.Strategy.RollingUpdate != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("strategy", "rollingUpdate"), "is not supported by DaemonSets, use daemonSet.maxUnavailable"))
+		}
+		if s.Strategy.ProgressDeadlineSeconds != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("strategy", "progressDeadlineSeconds"), "is not supported by DaemonSets"))
+		}
+	}
+
+	if s.DaemonSet != nil {
+		allErrs = append(allErrs, s.DaemonSet.validate(fldPath.Child("daemonSet"))...)
+	}
+
+	return allErrs
+}
+
+// validateBatch validates a Microservice with the CronJob or Job workload
+// kind, whose pods run to completion and serve no traffic.
+func (r *Microservice) validateBatch(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	s := &r.Spec
+	notSupported := fmt.Sprintf("is not supported by %ss", r.GetWorkloadKind())
+
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"ingress", len(s.Ingress) > 0},
+		{"autoscaling", s.Autoscaling != nil},
+		{"scaling", s.Scaling != nil},
+		{"strategy", s.Strategy != nil},
+		{"autoRollback", s.AutoRollback},
+		{"canary", s.Canary != nil},
+		// a running sidecar keeps the pod from completing
+		{"sidecars", len(s.Sidecars) > 0},
+	} {
+		if f.set {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child(f.name), notSupported))
+		}
+	}
+
+	if r.GetWorkloadKind() != WorkloadKindCronJob {
+		return allErrs
+	}
+
+	// the CronJob controller appends an 11 character suffix to the name of
+	// the CronJob to name its Jobs
+	if len(r.Name) > maxCronJobNameLength {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "name"), r.Name, fmt.Sprintf("must be no more than %d characters with the CronJob workload kind", maxCronJobNameLength)))
+	}
+	if s.CronJob == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("cronJob"), "cronJob is required when workloadKind is CronJob"))
+		return allErrs
+	}
+	// cron.ParseStandard is the parser the CronJob controller uses as well
+	_, err := cron.ParseStandard(s.CronJob.Schedule)
+	if err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("cronJob", "schedule"), s.CronJob.Schedule, err.Error()))
+	}
+
+	return allErrs
+}
+
+// validate validates the StatefulSet parameters of spec.
+func (s *StatefulSetSpec) validate(spec *MicroserviceSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	volumes := map[string]bool{}
+	for _, v := range spec.Volumes {
+		volumes[v.Name] = true
+	}
+	if len(spec.ConfigFiles) > 0 {
+		volumes[ConfigFilesVolume] = true
+	}
+	for i, template := range s.VolumeClaimTemplates {
+		idxPath := fldPath.Child("volumeClaimTemplates").Index(i)
+
+		if template.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "claim template name must not be empty"))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(template.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), template.Name, msg))
+			}
+		}
+		if volumes[template.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), template.Name))
+		}
+		volumes[template.Name] = true
+
+		if template.Size.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("size"), template.Size.String(), "must be greater than 0"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateContainers validates the sidecars and init containers, which share
+// the pods with the container named after the Microservice, and the Ingresses
+// that expose a sidecar.
+func (r *Microservice) validateContainers(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	names := map[string]bool{r.Name: true}
+	volumes := r.Spec.volumeNames()
+	sidecars := map[string]Container{}
+	for i, c := range r.Spec.Sidecars {
+		allErrs = append(allErrs, c.validate(fldPath.Child("sidecars").Index(i), names, volumes)...)
+		sidecars[c.Name] = c
+	}
+	for i, c := range r.Spec.InitContainers {
+		idxPath := fldPath.Child("initContainers").Index(i)
+		allErrs = append(allErrs, c.validate(idxPath, names, volumes)...)
+
+		if c.LivenessProbe != nil {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("livenessProbe"), "init containers do not support probes"))
+		}
+		if c.ReadinessProbe != nil {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("readinessProbe"), "init containers do not support probes"))
+		}
+	}
+
+	for i, ing := range r.Spec.Ingress {
+		if ing.Container == "" {
+			continue
+		}
+		idxPath := fldPath.Child("ingress").Index(i)
+
+		sidecar, ok := sidecars[ing.Container]
+		if !ok {
+			allErrs = append(allErrs, field.NotFound(idxPath.Child("container"), ing.Container))
+			continue
+		}
+		if !sidecar.hasPort(ing.ContainerPort) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("containerPort"), ing.ContainerPort, fmt.Sprintf("must be a port of container %s", ing.Container)))
+		}
+	}
+
+	return allErrs
+}
+
+// validate validates a sidecar or init container, adding its name to the
+// names taken within the pod.
+func (c *Container) validate(fldPath *field.Path, names, volumes map[string]bool) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if c.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "container name must not be empty"))
+	} else {
+		for _, msg := range validation.IsDNS1123Label(c.Name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), c.Name, msg))
+		}
+	}
+	if names[c.Name] {
+		allErrs = append(allErrs, field.Duplicate(fldPath.Child("name"), c.Name))
+	}
+	names[c.Name] = true
+
+	if c.Image == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("image"), "image must not be empty"))
+	}
+
+	allErrs = append(allErrs, validateEnvVars(c.Env, map[string]bool{}, fldPath.Child("env"))...)
+	allErrs = append(allErrs, validateEnvFrom(c.EnvFrom, fldPath.Child("envFrom"))...)
+	allErrs = append(allErrs, validateVolumeMounts(c.VolumeMounts, volumes, fldPath.Child("volumeMounts"))...)
+
+	for i, port := range c.Ports {
+		for _, msg := range validation.IsValidPortNum(int(port.ContainerPort)) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("ports").Index(i).Child("containerPort"), port.ContainerPort, msg))
+		}
+	}
+
+	return allErrs
+}
+
+// validateVolumes validates the volumes of the pods and the
+// PersistentVolumeClaims of the Microservice.
+func (s *MicroserviceSpec) validateVolumes(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	volumes := map[string]bool{}
+	if len(s.ConfigFiles) > 0 {
+		volumes[ConfigFilesVolume] = true
+	}
+	for i, v := range s.Volumes {
+		idxPath := fldPath.Child("volumes").Index(i)
+
+		if v.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "volume name must not be empty"))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(v.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), v.Name, msg))
+			}
+		}
+		if volumes[v.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), v.Name))
+		}
+		volumes[v.Name] = true
+
+		sources := 0
+		for _, set := range []bool{
+			v.ConfigMap != nil,
+			v.Secret != nil,
+			v.EmptyDir != nil,
+			v.Projected != nil,
+			v.PersistentVolumeClaim != nil,
+		} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			allErrs = append(allErrs, field.Invalid(idxPath, v.Name, "must specify exactly one of configMap, secret, emptyDir, projected and persistentVolumeClaim"))
+		}
+	}
+
+	for _, key := range sortedKeys(s.ConfigFiles) {
+		for _, msg := range validation.IsConfigMapKey(key) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("configFiles").Key(key), key, msg))
+		}
+	}
+	if s.ConfigFilesMountPath != "" && !strings.HasPrefix(s.ConfigFilesMountPath, "/") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("configFilesMountPath"), s.ConfigFilesMountPath, "must be an absolute path"))
+	}
+
+	claims := map[string]bool{}
+	for i, pvc := range s.PersistentVolumeClaims {
+		idxPath := fldPath.Child("persistentVolumeClaims").Index(i)
+
+		if pvc.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "claim name must not be empty"))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(pvc.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), pvc.Name, msg))
+			}
+		}
+		if claims[pvc.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), pvc.Name))
+		}
+		claims[pvc.Name] = true
+
+		if pvc.Size.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("size"), pvc.Size.String(), "must be greater than 0"))
+		}
+	}
+
+	return allErrs
+}
+
+// validatePersistentVolumeClaimUpdate compares the PersistentVolumeClaims of
+// the Microservice with those of old, if set. Once a claim is bound,
+// Kubernetes only allows to increase its size.
+func (r *Microservice) validatePersistentVolumeClaimUpdate(old *Microservice, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if old == nil {
+		return allErrs
+	}
+
+	oldClaims := map[string]PersistentVolumeClaim{}
+	for _, pvc := range old.Spec.PersistentVolumeClaims {
+		oldClaims[pvc.Name] = pvc
+	}
+
+	for i, pvc := range r.Spec.PersistentVolumeClaims {
+		oldPVC, ok := oldClaims[pvc.Name]
+		if !ok {
+			continue
+		}
+		idxPath := fldPath.Child("persistentVolumeClaims").Index(i)
+
+		if pvc.Size.Cmp(oldPVC.Size) < 0 {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("size"), "may not be decreased"))
+		}
+		if !reflect.DeepEqual(pvc.GetAccessModes(), oldPVC.GetAccessModes()) {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("accessModes"), "may not be changed"))
+		}
+		if !reflect.DeepEqual(pvc.StorageClassName, oldPVC.StorageClassName) {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("storageClassName"), "may not be changed"))
+		}
+	}
+
+	return allErrs
+}
+
+func (s *MicroserviceSpec) volumeNames() map[string]bool {
+	names := map[string]bool{}
+	if len(s.ConfigFiles) > 0 {
+		names[ConfigFilesVolume] = true
+	}
+	for _, v := range s.Volumes {
+		names[v.Name] = true
+	}
+	// claim templates are mounted like volumes
+	if s.StatefulSet != nil && s.WorkloadKind == WorkloadKindStatefulSet {
+		for _, template := range s.StatefulSet.VolumeClaimTemplates {
+			names[template.Name] = true
+		}
+	}
+
+	return names
+}
+
+// sortedKeys returns the keys of m in order, so that errors are reported in
+// the same order every time.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// validateVolumeMounts validates the volume mounts of a container against
+// the volumes of the pod.
+func validateVolumeMounts(mounts []corev1.VolumeMount, volumes map[string]bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	paths := map[string]bool{}
+	for i, mount := range mounts {
+		idxPath := fldPath.Index(i)
+
+		if !volumes[mount.Name] {
+			allErrs = append(allErrs, field.NotFound(idxPath.Child("name"), mount.Name))
+		}
+		if mount.MountPath == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("mountPath"), "mount path must not be empty"))
+		} else if paths[mount.MountPath] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("mountPath"), mount.MountPath))
+		}
+		paths[mount.MountPath] = true
+	}
+
+	return allErrs
+}
+
+// validateEnvVars validates a list of environment variables, adding their
+// names to the names already set by the container.
+func validateEnvVars(vars []corev1.EnvVar, names map[string]bool, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, v := range vars {
+		idxPath := fldPath.Index(i)
+
+		if v.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), "environment variable name must not be empty"))
+		} else {
+			for _, msg := range validation.IsEnvVarName(v.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), v.Name, msg))
+			}
+		}
+		if names[v.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), v.Name))
+		}
+		names[v.Name] = true
+
+		if v.ValueFrom == nil {
+			continue
+		}
+		if v.Value != "" {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("valueFrom"), "may not be specified when value is not empty"))
+		}
+		sources := 0
+		for _, set := range []bool{
+			v.ValueFrom.SecretKeyRef != nil,
+			v.ValueFrom.ConfigMapKeyRef != nil,
+			v.ValueFrom.FieldRef != nil,
+			v.ValueFrom.ResourceFieldRef != nil,
+		} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("valueFrom"), "", "must specify exactly one of secretKeyRef, configMapKeyRef, fieldRef and resourceFieldRef"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateEnvFrom validates the Secrets and ConfigMaps set as environment
+// variables of a container.
+func validateEnvFrom(sources []corev1.EnvFromSource, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, source := range sources {
+		idxPath := fldPath.Index(i)
+
+		if (source.ConfigMapRef == nil) == (source.SecretRef == nil) {
+			allErrs = append(allErrs, field.Invalid(idxPath, "", "must specify exactly one of configMapRef and secretRef"))
+		}
+		if source.Prefix != "" {
+			for _, msg := range validation.IsEnvVarName(source.Prefix) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("prefix"), source.Prefix, msg))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func (c *Container) hasPort(port int32) bool {
+	for _, p := range c.Ports {
+		if p.ContainerPort == port {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *ScalingSpec) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if s.MaxReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxReplicas"), s.MaxReplicas, "must be greater than or equal to 1"))
+	}
+	if s.MinReplicas != nil && *s.MinReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicas"), *s.MinReplicas, "must be greater than or equal to 1"))
+	}
+	if s.MinReplicas != nil && *s.MinReplicas > s.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicas"), *s.MinReplicas, "must not be greater than maxReplicas"))
+	}
+
+	return allErrs
+}
+
+func (s *CanarySpec) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if len(s.Steps) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("steps"), "at least one step is required"))
+	}
+	for i, step := range s.Steps {
+		idxPath := fldPath.Child("steps").Index(i)
+
+		if step.Weight < 0 || step.Weight > 100 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("weight"), step.Weight, "must be between 0 and 100"))
+		}
+		if step.Pause != nil && step.Pause.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("pause"), step.Pause.Duration.String(), "must not be negative"))
+		}
+		if step.Pause == nil && i < len(s.Steps)-1 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("pause"), "only the last step may wait to be promoted"))
+		}
+	}
+
+	return allErrs
+}
+
+func (s *StrategySpec) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if s.RollingUpdate != nil {
+		if s.Type == RecreateStrategyType {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("rollingUpdate"), "may not be specified when type is Recreate"))
+		} else {
+			allErrs = append(allErrs, s.RollingUpdate.validate(fldPath.Child("rollingUpdate"))...)
+		}
+	}
+	if s.BlueGreen != nil {
+		if s.Type != BlueGreenStrategyType {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("blueGreen"), "may only be specified when type is BlueGreen"))
+		} else if s.BlueGreen.ScaleDownDelay != nil && s.BlueGreen.ScaleDownDelay.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("blueGreen", "scaleDownDelay"), s.BlueGreen.ScaleDownDelay.Duration.String(), "must not be negative"))
+		}
+	}
+	if s.ProgressDeadlineSeconds != nil && *s.ProgressDeadlineSeconds <= s.MinReadySeconds {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("progressDeadlineSeconds"), *s.ProgressDeadlineSeconds, "must be greater than minReadySeconds"))
+	}
+
+	return allErrs
+}
+
+func (s *DaemonSetSpec) validate(fldPath *field.Path) field.ErrorList {
+	maxUnavailable, allErrs := validateIntOrPercent(s.MaxUnavailable, fldPath.Child("maxUnavailable"))
+	if len(allErrs) > 0 || s.MaxUnavailable == nil {
+		return allErrs
+	}
+
+	if s.MaxUnavailable.Type == intstr.String && maxUnavailable > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxUnavailable"), s.MaxUnavailable.String(), "must not be greater than 100%"))
+	}
+	// DaemonSets are updated without surge, so no pod would ever be replaced
+	if maxUnavailable == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxUnavailable"), s.MaxUnavailable.String(), "must not be 0"))
+	}
+
+	return allErrs
+}
+
+func (s *RollingUpdateStrategy) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	maxSurge, errs := validateIntOrPercent(s.MaxSurge, fldPath.Child("maxSurge"))
+	allErrs = append(allErrs, errs...)
+	maxUnavailable, errs := validateIntOrPercent(s.MaxUnavailable, fldPath.Child("maxUnavailable"))
+	allErrs = append(allErrs, errs...)
+	if len(allErrs) > 0 {
+		return allErrs
+	}
+
+	if s.MaxUnavailable != nil && s.MaxUnavailable.Type == intstr.String && maxUnavailable > 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxUnavailable"), s.MaxUnavailable.String(), "must not be greater than 100%"))
+	}
+	// maxSurge defaults to 1 and maxUnavailable to 0, so a rollout can only
+	// stall if maxSurge is set to zero
+	if s.MaxSurge != nil && maxSurge == 0 && maxUnavailable == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxUnavailable"), maxUnavailable, "must not be 0 when maxSurge is 0"))
+	}
+
+	return allErrs
+}
+
+// validateIntOrPercent returns the value of a non-negative number or
+// percentage, with percentages returned as the percent value.
+func validateIntOrPercent(value *intstr.IntOrString, fldPath *field.Path) (int, field.ErrorList) {
+	if value == nil {
+		return 0, nil
+	}
+
+	if value.Type == intstr.String {
+		if msgs := validation.IsValidPercent(value.StrVal); len(msgs) > 0 {
+			return 0, field.ErrorList{field.Invalid(fldPath, value.StrVal, strings.Join(msgs, "; "))}
+		}
+	}
+
+	scaled, err := intstr.GetScaledValueFromIntOrPercent(value, 100, false)
+	if err != nil {
+		return 0, field.ErrorList{field.Invalid(fldPath, value.String(), err.Error())}
+	}
+	if scaled < 0 {
+		return 0, field.ErrorList{field.Invalid(fldPath, value.IntVal, "must be greater than or equal to 0")}
+	}
+
+	return scaled, nil
+}
@@ -0,0 +1,855 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestMicroserviceValidation(t *testing.T) {
+	newMicroservice := func() *Microservice {
+		replicas := int32(1)
+		return &Microservice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			Spec: MicroserviceSpec{
+				Image:    "image:latest",
+				Replicas: &replicas,
+				Labels: map[string]string{
+					"app": "test",
+				},
+				Ingress: []Ingress{
+					{Name: "http", ContainerPort: 8080},
+					{Name: "grpc", ContainerPort: 9090},
+				},
+			},
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		ms := newMicroservice()
+		assert.NoError(t, ms.ValidateCreate())
+		assert.NoError(t, ms.ValidateUpdate(newMicroservice()))
+		assert.NoError(t, ms.ValidateDelete())
+
+		ms.Annotations = map[string]string{DriftPolicyAnnotation: string(DriftPolicyReportOnly)}
+		assert.NoError(t, ms.ValidateCreate())
+
+		maxSurge := intstr.FromString("0%")
+		maxUnavailable := intstr.FromString("25%")
+		ms.Spec.Strategy = &StrategySpec{
+			RollingUpdate: &RollingUpdateStrategy{MaxSurge: &maxSurge, MaxUnavailable: &maxUnavailable},
+		}
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.Strategy = &StrategySpec{Type: RecreateStrategyType}
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.Canary = &CanarySpec{Steps: []CanaryStep{{Weight: 10, Pause: &metav1.Duration{Duration: time.Minute}}, {Weight: 50}}}
+		ms.Annotations[RolloutActionAnnotation] = string(RolloutActionPromote)
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.Canary = nil
+		ms.Spec.Strategy = &StrategySpec{
+			Type:      BlueGreenStrategyType,
+			BlueGreen: &BlueGreenStrategy{AutoPromote: true, ScaleDownDelay: &metav1.Duration{Duration: time.Minute}},
+		}
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.Sidecars = []Container{{Name: "proxy", Image: "proxy:latest", Ports: []corev1.ContainerPort{{ContainerPort: 9000}}}}
+		ms.Spec.InitContainers = []Container{{Name: "migrate", Image: "image:latest"}}
+		ms.Spec.Ingress[1].Container = "proxy"
+		ms.Spec.Ingress[1].ContainerPort = 9000
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.Env = map[string]string{"LOG_LEVEL": "debug"}
+		ms.Spec.EnvVars = []corev1.EnvVar{
+			{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+		}
+		ms.Spec.EnvFrom = []corev1.EnvFromSource{
+			{Prefix: "DB_", SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}}},
+		}
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.Volumes = []Volume{
+			{Name: "tmp", EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			{Name: "data", PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+		}
+		ms.Spec.VolumeMounts = []corev1.VolumeMount{{Name: "tmp", MountPath: "/tmp"}}
+		ms.Spec.Sidecars[0].VolumeMounts = []corev1.VolumeMount{{Name: "data", MountPath: "/data"}}
+		ms.Spec.PersistentVolumeClaims = []PersistentVolumeClaim{{Name: "data", Size: resource.MustParse("1Gi")}}
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.ConfigFiles = map[string]string{"app.yaml": "level: info"}
+		ms.Spec.ConfigFilesMountPath = "/app/config"
+		ms.Spec.Sidecars[0].VolumeMounts = append(ms.Spec.Sidecars[0].VolumeMounts, corev1.VolumeMount{Name: ConfigFilesVolume, MountPath: "/config"})
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.IgnoreChanges = []DependencyReference{{Kind: DependencyKindSecret, Name: "db"}}
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.Strategy = nil
+		ms.Spec.WorkloadKind = WorkloadKindStatefulSet
+		ms.Spec.StatefulSet = &StatefulSetSpec{
+			PodManagementPolicy:  appsv1.ParallelPodManagement,
+			VolumeClaimTemplates: []VolumeClaimTemplate{{Name: "state", Size: resource.MustParse("1Gi")}},
+		}
+		ms.Spec.VolumeMounts = append(ms.Spec.VolumeMounts, corev1.VolumeMount{Name: "state", MountPath: "/state"})
+		assert.NoError(t, ms.ValidateCreate())
+		assert.NoError(t, ms.ValidateUpdate(ms.DeepCopy()))
+		assert.NoError(t, ms.ValidateUpdate(newMicroservice()))
+	})
+
+	t.Run("valid pre-deploy hook", func(t *testing.T) {
+		ms := newMicroservice()
+		ms.Spec.PreDeploy = &PreDeployHook{Command: []string{"migrate", "up"}}
+		assert.NoError(t, ms.ValidateCreate())
+		assert.NoError(t, ms.ValidateUpdate(newMicroservice()))
+	})
+
+	t.Run("valid DaemonSet", func(t *testing.T) {
+		ms := newMicroservice()
+		maxUnavailable := intstr.FromString("10%")
+		ms.Spec.Ingress = nil
+		ms.Spec.WorkloadKind = WorkloadKindDaemonSet
+		ms.Spec.DaemonSet = &DaemonSetSpec{MaxUnavailable: &maxUnavailable}
+		ms.Spec.Strategy = &StrategySpec{MinReadySeconds: 10}
+		ms.Spec.Tolerations = []corev1.Toleration{{Operator: corev1.TolerationOpExists}}
+		assert.NoError(t, ms.ValidateCreate())
+		assert.NoError(t, ms.ValidateUpdate(newMicroservice()))
+	})
+
+	t.Run("valid batch workloads", func(t *testing.T) {
+		ms := newMicroservice()
+		ms.Spec.Ingress = nil
+		ms.Spec.WorkloadKind = WorkloadKindJob
+		ms.Spec.Job = &JobSpec{RestartPolicy: corev1.RestartPolicyNever}
+		assert.NoError(t, ms.ValidateCreate())
+
+		ms.Spec.WorkloadKind = WorkloadKindCronJob
+		ms.Spec.Job = nil
+		ms.Spec.CronJob = &CronJobSpec{Schedule: "@hourly"}
+		assert.NoError(t, ms.ValidateCreate())
+		assert.NoError(t, ms.ValidateUpdate(newMicroservice()))
+	})
+
+	t.Run("immutable statefulSet fields", func(t *testing.T) {
+		old := newMicroservice()
+		old.Spec.WorkloadKind = WorkloadKindStatefulSet
+		old.Spec.StatefulSet = &StatefulSetSpec{
+			VolumeClaimTemplates: []VolumeClaimTemplate{{Name: "state", Size: resource.MustParse("1Gi")}},
+		}
+
+		ms := old.DeepCopy()
+		ms.Spec.StatefulSet.PodManagementPolicy = appsv1.ParallelPodManagement
+		ms.Spec.StatefulSet.VolumeClaimTemplates[0].Size = resource.MustParse("2Gi")
+		assert.NoError(t, ms.ValidateCreate())
+
+		err := ms.ValidateUpdate(old)
+		assert.True(t, k8sErrors.IsInvalid(err))
+		statusErr, ok := err.(*k8sErrors.StatusError)
+		assert.True(t, ok)
+		assert.Len(t, statusErr.ErrStatus.Details.Causes, 2)
+		assert.Equal(t, "spec.statefulSet.podManagementPolicy", statusErr.ErrStatus.Details.Causes[0].Field)
+		assert.Equal(t, "spec.statefulSet.volumeClaimTemplates", statusErr.ErrStatus.Details.Causes[1].Field)
+	})
+
+	t.Run("immutable persistentVolumeClaims fields", func(t *testing.T) {
+		old := newMicroservice()
+		old.Spec.PersistentVolumeClaims = []PersistentVolumeClaim{{Name: "data", Size: resource.MustParse("2Gi")}}
+
+		// the defaults are no change, and claims can grow or be added
+		ms := old.DeepCopy()
+		ms.Spec.PersistentVolumeClaims[0].Size = resource.MustParse("4Gi")
+		ms.Spec.PersistentVolumeClaims[0].AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+		ms.Spec.PersistentVolumeClaims = append(ms.Spec.PersistentVolumeClaims, PersistentVolumeClaim{Name: "cache", Size: resource.MustParse("1Gi")})
+		assert.NoError(t, ms.ValidateUpdate(old))
+
+		storageClass := "fast"
+		ms = old.DeepCopy()
+		ms.Spec.PersistentVolumeClaims[0].Size = resource.MustParse("1Gi")
+		ms.Spec.PersistentVolumeClaims[0].AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+		ms.Spec.PersistentVolumeClaims[0].StorageClassName = &storageClass
+		assert.NoError(t, ms.ValidateCreate())
+
+		err := ms.ValidateUpdate(old)
+		assert.True(t, k8sErrors.IsInvalid(err))
+		statusErr, ok := err.(*k8sErrors.StatusError)
+		assert.True(t, ok)
+		assert.Len(t, statusErr.ErrStatus.Details.Causes, 3)
+		assert.Equal(t, "spec.persistentVolumeClaims[0].size", statusErr.ErrStatus.Details.Causes[0].Field)
+		assert.Equal(t, "spec.persistentVolumeClaims[0].accessModes", statusErr.ErrStatus.Details.Causes[1].Field)
+		assert.Equal(t, "spec.persistentVolumeClaims[0].storageClassName", statusErr.ErrStatus.Details.Causes[2].Field)
+	})
+
+	tests := []struct {
+		name   string
+		mutate func(ms *Microservice)
+		field  string
+	}{
+		{
+			name:   "empty image",
+			mutate: func(ms *Microservice) { ms.Spec.Image = "" },
+			field:  "spec.image",
+		},
+		{
+			name:   "duplicate ingress name",
+			mutate: func(ms *Microservice) { ms.Spec.Ingress[1].Name = "http" },
+			field:  "spec.ingress[1].name",
+		},
+		{
+			name:   "empty ingress name",
+			mutate: func(ms *Microservice) { ms.Spec.Ingress[0].Name = "" },
+			field:  "spec.ingress[0].name",
+		},
+		{
+			name:   "invalid port name",
+			mutate: func(ms *Microservice) { ms.Spec.Ingress[0].Name = "Not_Valid" },
+			field:  "spec.ingress[0].name",
+		},
+		{
+			name:   "port name longer than 15 characters",
+			mutate: func(ms *Microservice) { ms.Spec.Ingress[0].Name = "http-port-name-x" },
+			field:  "spec.ingress[0].name",
+		},
+		{
+			name:   "port name without letters",
+			mutate: func(ms *Microservice) { ms.Spec.Ingress[0].Name = "8080" },
+			field:  "spec.ingress[0].name",
+		},
+		{
+			name:   "zero container port",
+			mutate: func(ms *Microservice) { ms.Spec.Ingress[1].ContainerPort = 0 },
+			field:  "spec.ingress[1].containerPort",
+		},
+		{
+			name:   "container port out of range",
+			mutate: func(ms *Microservice) { ms.Spec.Ingress[1].ContainerPort = 70000 },
+			field:  "spec.ingress[1].containerPort",
+		},
+		{
+			name: "scaling and autoscaling",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Autoscaling = &autoscalingv2.HorizontalPodAutoscalerSpec{MaxReplicas: 3}
+				ms.Spec.Scaling = &ScalingSpec{MaxReplicas: 3}
+			},
+			field: "spec.scaling",
+		},
+		{
+			name: "scaling min replicas above max replicas",
+			mutate: func(ms *Microservice) {
+				minReplicas := int32(4)
+				ms.Spec.Scaling = &ScalingSpec{MinReplicas: &minReplicas, MaxReplicas: 3}
+			},
+			field: "spec.scaling.minReplicas",
+		},
+		{
+			name: "scaling without max replicas",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Scaling = &ScalingSpec{}
+			},
+			field: "spec.scaling.maxReplicas",
+		},
+		{
+			name: "rolling update with recreate",
+			mutate: func(ms *Microservice) {
+				maxSurge := intstr.FromInt(2)
+				ms.Spec.Strategy = &StrategySpec{
+					Type:          RecreateStrategyType,
+					RollingUpdate: &RollingUpdateStrategy{MaxSurge: &maxSurge},
+				}
+			},
+			field: "spec.strategy.rollingUpdate",
+		},
+		{
+			name: "invalid surge percentage",
+			mutate: func(ms *Microservice) {
+				maxSurge := intstr.FromString("25")
+				ms.Spec.Strategy = &StrategySpec{
+					RollingUpdate: &RollingUpdateStrategy{MaxSurge: &maxSurge},
+				}
+			},
+			field: "spec.strategy.rollingUpdate.maxSurge",
+		},
+		{
+			name: "negative max unavailable",
+			mutate: func(ms *Microservice) {
+				maxUnavailable := intstr.FromInt(-1)
+				ms.Spec.Strategy = &StrategySpec{
+					RollingUpdate: &RollingUpdateStrategy{MaxUnavailable: &maxUnavailable},
+				}
+			},
+			field: "spec.strategy.rollingUpdate.maxUnavailable",
+		},
+		{
+			name: "max unavailable above 100 percent",
+			mutate: func(ms *Microservice) {
+				maxUnavailable := intstr.FromString("150%")
+				ms.Spec.Strategy = &StrategySpec{
+					RollingUpdate: &RollingUpdateStrategy{MaxUnavailable: &maxUnavailable},
+				}
+			},
+			field: "spec.strategy.rollingUpdate.maxUnavailable",
+		},
+		{
+			name: "zero surge without unavailable pods",
+			mutate: func(ms *Microservice) {
+				maxSurge := intstr.FromString("0%")
+				ms.Spec.Strategy = &StrategySpec{
+					RollingUpdate: &RollingUpdateStrategy{MaxSurge: &maxSurge},
+				}
+			},
+			field: "spec.strategy.rollingUpdate.maxUnavailable",
+		},
+		{
+			name: "progress deadline below min ready seconds",
+			mutate: func(ms *Microservice) {
+				progressDeadline := int32(30)
+				ms.Spec.Strategy = &StrategySpec{
+					MinReadySeconds:         30,
+					ProgressDeadlineSeconds: &progressDeadline,
+				}
+			},
+			field: "spec.strategy.progressDeadlineSeconds",
+		},
+		{
+			name: "canary without steps",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Canary = &CanarySpec{}
+			},
+			field: "spec.canary.steps",
+		},
+		{
+			name: "canary weight above 100",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Canary = &CanarySpec{Steps: []CanaryStep{{Weight: 120}}}
+			},
+			field: "spec.canary.steps[0].weight",
+		},
+		{
+			name: "canary step without pause before the last step",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Canary = &CanarySpec{Steps: []CanaryStep{{Weight: 10}, {Weight: 50}}}
+			},
+			field: "spec.canary.steps[0].pause",
+		},
+		{
+			name: "canary with blue/green strategy",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Strategy = &StrategySpec{Type: BlueGreenStrategyType}
+				ms.Spec.Canary = &CanarySpec{Steps: []CanaryStep{{Weight: 10}}}
+			},
+			field: "spec.canary",
+		},
+		{
+			name: "blue/green settings without blue/green strategy",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Strategy = &StrategySpec{BlueGreen: &BlueGreenStrategy{AutoPromote: true}}
+			},
+			field: "spec.strategy.blueGreen",
+		},
+		{
+			name: "negative scale down delay",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Strategy = &StrategySpec{
+					Type:      BlueGreenStrategyType,
+					BlueGreen: &BlueGreenStrategy{ScaleDownDelay: &metav1.Duration{Duration: -time.Second}},
+				}
+			},
+			field: "spec.strategy.blueGreen.scaleDownDelay",
+		},
+		{
+			name: "sidecar without image",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Sidecars = []Container{{Name: "proxy"}}
+			},
+			field: "spec.sidecars[0].image",
+		},
+		{
+			name: "sidecar named after the microservice",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Sidecars = []Container{{Name: "foo", Image: "proxy:latest"}}
+			},
+			field: "spec.sidecars[0].name",
+		},
+		{
+			name: "init container with the name of a sidecar",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Sidecars = []Container{{Name: "proxy", Image: "proxy:latest"}}
+				ms.Spec.InitContainers = []Container{{Name: "proxy", Image: "proxy:latest"}}
+			},
+			field: "spec.initContainers[0].name",
+		},
+		{
+			name: "init container with probe",
+			mutate: func(ms *Microservice) {
+				ms.Spec.InitContainers = []Container{{Name: "migrate", Image: "image:latest", ReadinessProbe: &corev1.Probe{}}}
+			},
+			field: "spec.initContainers[0].readinessProbe",
+		},
+		{
+			name: "ingress for unknown container",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Ingress[1].Container = "proxy"
+			},
+			field: "spec.ingress[1].container",
+		},
+		{
+			name: "ingress port not exposed by the sidecar",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Sidecars = []Container{{Name: "proxy", Image: "proxy:latest", Ports: []corev1.ContainerPort{{ContainerPort: 9000}}}}
+				ms.Spec.Ingress[1].Container = "proxy"
+			},
+			field: "spec.ingress[1].containerPort",
+		},
+		{
+			name: "env var set in env and envVars",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Env = map[string]string{"LOG_LEVEL": "debug"}
+				ms.Spec.EnvVars = []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}}
+			},
+			field: "spec.envVars[0].name",
+		},
+		{
+			name: "env var with value and valueFrom",
+			mutate: func(ms *Microservice) {
+				ms.Spec.EnvVars = []corev1.EnvVar{{
+					Name:      "POD_NAME",
+					Value:     "foo",
+					ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}},
+				}}
+			},
+			field: "spec.envVars[0].valueFrom",
+		},
+		{
+			name: "env var without value source",
+			mutate: func(ms *Microservice) {
+				ms.Spec.EnvVars = []corev1.EnvVar{{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{}}}
+			},
+			field: "spec.envVars[0].valueFrom",
+		},
+		{
+			name: "envFrom with secret and config map",
+			mutate: func(ms *Microservice) {
+				ms.Spec.EnvFrom = []corev1.EnvFromSource{{
+					ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}},
+					SecretRef:    &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}},
+				}}
+			},
+			field: "spec.envFrom[0]",
+		},
+		{
+			name: "invalid sidecar env var name",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Sidecars = []Container{{Name: "proxy", Image: "proxy:latest", Env: []corev1.EnvVar{{Name: "1=2"}}}}
+			},
+			field: "spec.sidecars[0].env[0].name",
+		},
+		{
+			name: "volume without source",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Volumes = []Volume{{Name: "tmp"}}
+			},
+			field: "spec.volumes[0]",
+		},
+		{
+			name: "volume with two sources",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Volumes = []Volume{{
+					Name:     "tmp",
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+					Secret:   &corev1.SecretVolumeSource{SecretName: "tmp"},
+				}}
+			},
+			field: "spec.volumes[0]",
+		},
+		{
+			name: "mount of unknown volume",
+			mutate: func(ms *Microservice) {
+				ms.Spec.VolumeMounts = []corev1.VolumeMount{{Name: "tmp", MountPath: "/tmp"}}
+			},
+			field: "spec.volumeMounts[0].name",
+		},
+		{
+			name: "sidecar mount without path",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Volumes = []Volume{{Name: "tmp", EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+				ms.Spec.Sidecars = []Container{{Name: "proxy", Image: "proxy:latest", VolumeMounts: []corev1.VolumeMount{{Name: "tmp"}}}}
+			},
+			field: "spec.sidecars[0].volumeMounts[0].mountPath",
+		},
+		{
+			name: "claim without size",
+			mutate: func(ms *Microservice) {
+				ms.Spec.PersistentVolumeClaims = []PersistentVolumeClaim{{Name: "data"}}
+			},
+			field: "spec.persistentVolumeClaims[0].size",
+		},
+		{
+			name: "duplicate claim name",
+			mutate: func(ms *Microservice) {
+				ms.Spec.PersistentVolumeClaims = []PersistentVolumeClaim{
+					{Name: "data", Size: resource.MustParse("1Gi")},
+					{Name: "data", Size: resource.MustParse("2Gi")},
+				}
+			},
+			field: "spec.persistentVolumeClaims[1].name",
+		},
+		{
+			name: "invalid config file name",
+			mutate: func(ms *Microservice) {
+				ms.Spec.ConfigFiles = map[string]string{"conf/app.yaml": ""}
+			},
+			field: "spec.configFiles[conf/app.yaml]",
+		},
+		{
+			name: "relative config files mount path",
+			mutate: func(ms *Microservice) {
+				ms.Spec.ConfigFiles = map[string]string{"app.yaml": ""}
+				ms.Spec.ConfigFilesMountPath = "config"
+			},
+			field: "spec.configFilesMountPath",
+		},
+		{
+			name: "volume named like the config files volume",
+			mutate: func(ms *Microservice) {
+				ms.Spec.ConfigFiles = map[string]string{"app.yaml": ""}
+				ms.Spec.Volumes = []Volume{{Name: ConfigFilesVolume, EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+			},
+			field: "spec.volumes[0].name",
+		},
+		{
+			name: "ignored change of unknown kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.IgnoreChanges = []DependencyReference{{Kind: "Service", Name: "db"}}
+			},
+			field: "spec.ignoreChanges[0].kind",
+		},
+		{
+			name: "statefulSet without the StatefulSet workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.StatefulSet = &StatefulSetSpec{}
+			},
+			field: "spec.statefulSet",
+		},
+		{
+			name: "canary with the StatefulSet workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.WorkloadKind = WorkloadKindStatefulSet
+				ms.Spec.Canary = &CanarySpec{Steps: []CanaryStep{{Weight: 10}}}
+			},
+			field: "spec.canary",
+		},
+		{
+			name: "rolling update parameters with the StatefulSet workload kind",
+			mutate: func(ms *Microservice) {
+				maxSurge := intstr.FromInt(1)
+				ms.Spec.WorkloadKind = WorkloadKindStatefulSet
+				ms.Spec.Strategy = &StrategySpec{RollingUpdate: &RollingUpdateStrategy{MaxSurge: &maxSurge}}
+			},
+			field: "spec.strategy.rollingUpdate",
+		},
+		{
+			name: "claim template named like a volume",
+			mutate: func(ms *Microservice) {
+				ms.Spec.WorkloadKind = WorkloadKindStatefulSet
+				ms.Spec.Volumes = []Volume{{Name: "state", EmptyDir: &corev1.EmptyDirVolumeSource{}}}
+				ms.Spec.StatefulSet = &StatefulSetSpec{
+					VolumeClaimTemplates: []VolumeClaimTemplate{{Name: "state", Size: resource.MustParse("1Gi")}},
+				}
+			},
+			field: "spec.statefulSet.volumeClaimTemplates[0].name",
+		},
+		{
+			name: "pre-deploy hook with the StatefulSet workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.WorkloadKind = WorkloadKindStatefulSet
+				ms.Spec.PreDeploy = &PreDeployHook{}
+			},
+			field: "spec.preDeploy",
+		},
+		{
+			name: "pre-deploy hook with a canary rollout",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Canary = &CanarySpec{Steps: []CanaryStep{{Weight: 10}}}
+				ms.Spec.PreDeploy = &PreDeployHook{}
+			},
+			field: "spec.preDeploy",
+		},
+		{
+			name: "pre-deploy hook with the BlueGreen strategy",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Strategy = &StrategySpec{Type: BlueGreenStrategyType}
+				ms.Spec.PreDeploy = &PreDeployHook{}
+			},
+			field: "spec.preDeploy",
+		},
+		{
+			name: "pre-deploy hook with a long name",
+			mutate: func(ms *Microservice) {
+				ms.Name = strings.Repeat("a", 54)
+				ms.Spec.PreDeploy = &PreDeployHook{}
+			},
+			field: "metadata.name",
+		},
+		{
+			name: "daemonSet without the DaemonSet workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.DaemonSet = &DaemonSetSpec{}
+			},
+			field: "spec.daemonSet",
+		},
+		{
+			name: "ingress with the DaemonSet workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.WorkloadKind = WorkloadKindDaemonSet
+			},
+			field: "spec.ingress",
+		},
+		{
+			name: "ingress generation with the DaemonSet workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Ingress = nil
+				ms.Spec.WorkloadKind = WorkloadKindDaemonSet
+				ms.Spec.IngressEnabled = true
+			},
+			field: "spec.ingressEnabled",
+		},
+		{
+			name: "scaling with the DaemonSet workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Ingress = nil
+				ms.Spec.WorkloadKind = WorkloadKindDaemonSet
+				ms.Spec.Scaling = &ScalingSpec{MaxReplicas: 3}
+			},
+			field: "spec.scaling",
+		},
+		{
+			name: "DaemonSet without unavailable pods",
+			mutate: func(ms *Microservice) {
+				maxUnavailable := intstr.FromInt(0)
+				ms.Spec.Ingress = nil
+				ms.Spec.WorkloadKind = WorkloadKindDaemonSet
+				ms.Spec.DaemonSet = &DaemonSetSpec{MaxUnavailable: &maxUnavailable}
+			},
+			field: "spec.daemonSet.maxUnavailable",
+		},
+		{
+			name: "cronJob without the CronJob workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.CronJob = &CronJobSpec{Schedule: "@hourly"}
+			},
+			field: "spec.cronJob",
+		},
+		{
+			name: "ingress with the Job workload kind",
+			mutate: func(ms *Microservice) {
+				ms.Spec.WorkloadKind = WorkloadKindJob
+			},
+			field: "spec.ingress",
+		},
+		{
+			name: "CronJob without cronJob",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Ingress = nil
+				ms.Spec.WorkloadKind = WorkloadKindCronJob
+			},
+			field: "spec.cronJob",
+		},
+		{
+			name: "invalid schedule",
+			mutate: func(ms *Microservice) {
+				ms.Spec.Ingress = nil
+				ms.Spec.WorkloadKind = WorkloadKindCronJob
+				ms.Spec.CronJob = &CronJobSpec{Schedule: "every minute"}
+			},
+			field: "spec.cronJob.schedule",
+		},
+		{
+			name: "CronJob name too long",
+			mutate: func(ms *Microservice) {
+				ms.Name = strings.Repeat("a", 53)
+				ms.Spec.Ingress = nil
+				ms.Spec.WorkloadKind = WorkloadKindCronJob
+				ms.Spec.CronJob = &CronJobSpec{Schedule: "@hourly"}
+			},
+			field: "metadata.name",
+		},
+		{
+			name: "unknown rollout action",
+			mutate: func(ms *Microservice) {
+				ms.Annotations = map[string]string{RolloutActionAnnotation: "resume"}
+			},
+			field: "metadata.annotations[microservice.example.com/rollout]",
+		},
+		{
+			name: "unknown drift policy",
+			mutate: func(ms *Microservice) {
+				ms.Annotations = map[string]string{DriftPolicyAnnotation: "ignore"}
+			},
+			field: "metadata.annotations[microservice.example.com/drift-policy]",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := newMicroservice()
+			tc.mutate(ms)
+
+			for _, err := range []error{ms.ValidateCreate(), ms.ValidateUpdate(newMicroservice())} {
+				assert.Error(t, err)
+				assert.True(t, k8sErrors.IsInvalid(err))
+
+				statusErr, ok := err.(*k8sErrors.StatusError)
+				assert.True(t, ok)
+				assert.Equal(t, tc.field, statusErr.ErrStatus.Details.Causes[0].Field)
+			}
+		})
+	}
+}
+
+func TestMicroserviceDefaulting(t *testing.T) {
+	t.Run("empty spec", func(t *testing.T) {
+		ms := &Microservice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			Spec: MicroserviceSpec{
+				Image:       "image:latest",
+				Autoscaling: &autoscalingv2.HorizontalPodAutoscalerSpec{MaxReplicas: 3},
+			},
+		}
+		ms.Default()
+
+		assert.Equal(t, DefaultReplicas, *ms.Spec.Replicas)
+		assert.Equal(t, map[string]string{NameLabel: "foo"}, ms.Spec.Labels)
+		assert.Equal(t, autoscalingv2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       "foo",
+		}, ms.Spec.Autoscaling.ScaleTargetRef)
+	})
+
+	t.Run("probe ports", func(t *testing.T) {
+		ms := &Microservice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			Spec: MicroserviceSpec{
+				Image: "image:latest",
+				Ingress: []Ingress{
+					{Name: "http", ContainerPort: 8080},
+				},
+				LivenessProbe: &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz"},
+					},
+				},
+				ReadinessProbe: &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("admin")},
+					},
+				},
+			},
+		}
+		ms.Default()
+
+		assert.Equal(t, intstr.FromInt(8080), ms.Spec.LivenessProbe.HTTPGet.Port)
+		assert.Equal(t, intstr.FromString("admin"), ms.Spec.ReadinessProbe.TCPSocket.Port)
+	})
+
+	t.Run("sidecar probe ports", func(t *testing.T) {
+		ms := &Microservice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			Spec: MicroserviceSpec{
+				Image: "image:latest",
+				Ingress: []Ingress{
+					{Name: "proxy", ContainerPort: 9000, Container: "proxy"},
+					{Name: "http", ContainerPort: 8080},
+				},
+				LivenessProbe: &corev1.Probe{
+					ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{}},
+				},
+				Sidecars: []Container{
+					{
+						Name:  "proxy",
+						Image: "proxy:latest",
+						Ports: []corev1.ContainerPort{{ContainerPort: 9000}},
+						ReadinessProbe: &corev1.Probe{
+							ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/ready"}},
+						},
+					},
+				},
+			},
+		}
+		ms.Default()
+
+		assert.Equal(t, intstr.FromInt(8080), ms.Spec.LivenessProbe.TCPSocket.Port)
+		assert.Equal(t, intstr.FromInt(9000), ms.Spec.Sidecars[0].ReadinessProbe.HTTPGet.Port)
+	})
+
+	t.Run("user values are kept", func(t *testing.T) {
+		labels := map[string]string{"app": "test"}
+		replicas := int32(4)
+		ms := &Microservice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			Spec: MicroserviceSpec{
+				Image:       "image:latest",
+				Replicas:    &replicas,
+				Labels:      labels,
+				Autoscaling: &autoscalingv2.HorizontalPodAutoscalerSpec{MaxReplicas: 3},
+			},
+		}
+		ms.Default()
+
+		assert.Equal(t, int32(4), *ms.Spec.Replicas)
+		assert.Equal(t, labels, ms.Spec.Labels)
+		assert.Equal(t, int32(3), ms.Spec.Autoscaling.MaxReplicas)
+	})
+
+	t.Run("scale target is the deployment", func(t *testing.T) {
+		ms := &Microservice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "default",
+			},
+			Spec: MicroserviceSpec{
+				Image: "image:latest",
+				Autoscaling: &autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+						APIVersion: "apps/v1",
+						Kind:       "Deployment",
+						Name:       "php-apache",
+					},
+					MaxReplicas: 3,
+				},
+			},
+		}
+		ms.Default()
+
+		assert.Equal(t, autoscalingv2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       "foo",
+		}, ms.Spec.Autoscaling.ScaleTargetRef)
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScheduledAutoscalerSpec defines the desired state of ScheduledAutoscaler
+type ScheduledAutoscalerSpec struct {
+	MicroserviceName string     `json:"microserviceName"`
+	Schedules        []Schedule `json:"schedules"`
+}
+
+type Schedule struct {
+	Name        string `json:"name"`
+	Cron        string `json:"cron"`
+	MinReplicas int32  `json:"minReplicas"`
+	MaxReplicas int32  `json:"maxReplicas"`
+}
+
+// ScheduledAutoscalerStatus defines the observed state of ScheduledAutoscaler
+type ScheduledAutoscalerStatus struct {
+	// IDs of the cron entries registered for the schedules
+	// +optional
+	ScheduledCrons []int `json:"cronID,omitempty"`
+
+	// +optional
+	State RunningState `json:"state,omitempty"`
+
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+
+// ScheduledAutoscaler is the Schema for the scheduledautoscalers API
+type ScheduledAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScheduledAutoscalerSpec   `json:"spec,omitempty"`
+	Status ScheduledAutoscalerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ScheduledAutoscalerList contains a list of ScheduledAutoscaler
+type ScheduledAutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScheduledAutoscaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScheduledAutoscaler{}, &ScheduledAutoscalerList{})
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+
+	cron "github.com/robfig/cron/v3"
+	admissionv1 "k8s.io/api/admission/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var scheduledautoscalerlog = logf.Log.WithName("scheduledautoscaler-resource")
+
+const validateScheduledAutoscalerPath = "/validate-microservice-microservice-example-com-v1-scheduledautoscaler"
+
+// SetupWebhookWithManager registers the ScheduledAutoscaler conversion and
+// validating webhooks. A plain admission handler is used instead of
+// webhook.Validator because the webhook needs a client to look up the target
+// Microservice and has to return warnings alongside the admission result.
+func (r *ScheduledAutoscaler) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(validateScheduledAutoscalerPath, &webhook.Admission{
+		Handler: &ScheduledAutoscalerValidator{Client: mgr.GetClient()},
+	})
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-microservice-microservice-example-com-v1-scheduledautoscaler,mutating=false,failurePolicy=fail,sideEffects=None,groups=microservice.microservice.example.com,resources=scheduledautoscalers,verbs=create;update,versions=v1,name=vscheduledautoscaler.kb.io,admissionReviewVersions=v1
+
+// ScheduledAutoscalerValidator validates ScheduledAutoscaler objects.
+// +kubebuilder:object:generate=false
+type ScheduledAutoscalerValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &ScheduledAutoscalerValidator{}
+var _ admission.DecoderInjector = &ScheduledAutoscalerValidator{}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (v *ScheduledAutoscalerValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle rejects ScheduledAutoscalers with invalid schedules or targeting a
+// Microservice that cannot be scaled, and warns when the target Microservice
+// has no autoscaling configured.
+func (v *ScheduledAutoscalerValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	sa := &ScheduledAutoscaler{}
+	err := v.decoder.Decode(req, sa)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if sa.Namespace == "" {
+		sa.Namespace = req.Namespace
+	}
+
+	scheduledautoscalerlog.Info("validate", "name", sa.Name, "operation", req.Operation)
+
+	err = sa.validateScheduledAutoscaler()
+	if err == nil {
+		err = v.validateTarget(ctx, sa)
+	}
+	if err != nil {
+		var apiStatus k8sErrors.APIStatus
+		if goerrors.As(err, &apiStatus) {
+			status := apiStatus.Status()
+			return admission.Response{
+				AdmissionResponse: admissionv1.AdmissionResponse{
+					Allowed: false,
+					Result:  &status,
+				},
+			}
+		}
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("").WithWarnings(v.warnings(ctx, sa)...)
+}
+
+// validateTarget rejects ScheduledAutoscalers whose target Microservice
+// exists but runs a workload that cannot be scaled. Missing targets are only
+// warned about, since they may be created later.
+func (v *ScheduledAutoscalerValidator) validateTarget(ctx context.Context, sa *ScheduledAutoscaler) error {
+	mic := &Microservice{}
+	err := v.Client.Get(ctx, types.NamespacedName{Name: sa.Spec.MicroserviceName, Namespace: sa.Namespace}, mic)
+	if err != nil || mic.Scalable() {
+		return nil
+	}
+
+	fldPath := field.NewPath("spec", "microserviceName")
+	allErrs := field.ErrorList{field.Forbidden(fldPath, fmt.Sprintf("microservice %q runs a %s, which cannot be scaled", sa.Spec.MicroserviceName, mic.GetWorkloadKind()))}
+
+	return k8sErrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "ScheduledAutoscaler"}, sa.Name, allErrs)
+}
+
+// warnings returns the non-fatal problems with the Microservice targeted by
+// the ScheduledAutoscaler.
+func (v *ScheduledAutoscalerValidator) warnings(ctx context.Context, sa *ScheduledAutoscaler) []string {
+	mic := &Microservice{}
+	err := v.Client.Get(ctx, types.NamespacedName{Name: sa.Spec.MicroserviceName, Namespace: sa.Namespace}, mic)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		return []string{fmt.Sprintf("microservice %q does not exist in namespace %q", sa.Spec.MicroserviceName, sa.Namespace)}
+	} else if err != nil {
+		return []string{fmt.Sprintf("unable to check microservice %q: %s", sa.Spec.MicroserviceName, err)}
+	}
+
+	if !mic.AutoscalingEnabled() {
+		return []string{fmt.Sprintf("microservice %q has no autoscaling configured, schedules will have no effect", sa.Spec.MicroserviceName)}
+	}
+
+	return nil
+}
+
+func (r *ScheduledAutoscaler) validateScheduledAutoscaler() error {
+	allErrs := r.Spec.validate(field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return k8sErrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "ScheduledAutoscaler"}, r.Name, allErrs)
+}
+
+func (s *ScheduledAutoscalerSpec) validate(fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if s.MicroserviceName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("microserviceName"), "microserviceName must not be empty"))
+	}
+
+	scheduleNames := map[string]bool{}
+	for i, schedule := range s.Schedules {
+		idxPath := fldPath.Child("schedules").Index(i)
+
+		if scheduleNames[schedule.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), schedule.Name))
+		}
+		scheduleNames[schedule.Name] = true
+
+		// cron.ParseStandard is the parser used by cron.New(), which is what
+		// the ScheduledAutoscaler controller schedules the entries with.
+		_, err := cron.ParseStandard(schedule.Cron)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("cron"), schedule.Cron, err.Error()))
+		}
+
+		if schedule.MinReplicas > schedule.MaxReplicas {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("minReplicas"), schedule.MinReplicas, "minReplicas must not be greater than maxReplicas"))
+		}
+	}
+
+	return allErrs
+}
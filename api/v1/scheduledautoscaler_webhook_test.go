@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestScheduledAutoscalerValidation(t *testing.T) {
+	s := runtime.NewScheme()
+	err := AddToScheme(s)
+	assert.NoError(t, err)
+
+	decoder, err := admission.NewDecoder(s)
+	assert.NoError(t, err)
+
+	withAutoscaling := &Microservice{
+		ObjectMeta: metav1.ObjectMeta{Name: "scaled", Namespace: "default"},
+		Spec: MicroserviceSpec{
+			Image:       "image:latest",
+			Autoscaling: &autoscalingv2.HorizontalPodAutoscalerSpec{MaxReplicas: 3},
+		},
+	}
+	withoutAutoscaling := &Microservice{
+		ObjectMeta: metav1.ObjectMeta{Name: "static", Namespace: "default"},
+		Spec: MicroserviceSpec{
+			Image: "image:latest",
+		},
+	}
+
+	daemonSet := &Microservice{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+		Spec: MicroserviceSpec{
+			Image:        "image:latest",
+			WorkloadKind: WorkloadKindDaemonSet,
+		},
+	}
+
+	v := &ScheduledAutoscalerValidator{
+		Client: fake.NewClientBuilder().WithScheme(s).WithObjects(withAutoscaling, withoutAutoscaling, daemonSet).Build(),
+	}
+	err = v.InjectDecoder(decoder)
+	assert.NoError(t, err)
+
+	handle := func(sa *ScheduledAutoscaler) admission.Response {
+		raw, err := json.Marshal(sa)
+		assert.NoError(t, err)
+
+		return v.Handle(context.TODO(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Namespace: "default",
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		})
+	}
+
+	newScheduledAutoscaler := func(microserviceName string) *ScheduledAutoscaler {
+		return &ScheduledAutoscaler{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: GroupVersion.String(),
+				Kind:       "ScheduledAutoscaler",
+			},
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: ScheduledAutoscalerSpec{
+				MicroserviceName: microserviceName,
+				Schedules: []Schedule{
+					{Name: "day", Cron: "0 8 * * *", MinReplicas: 3, MaxReplicas: 10},
+					{Name: "night", Cron: "@every 1h", MinReplicas: 1, MaxReplicas: 2},
+				},
+			},
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		resp := handle(newScheduledAutoscaler("scaled"))
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Warnings)
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		resp := handle(newScheduledAutoscaler("missing"))
+		assert.True(t, resp.Allowed)
+		assert.Len(t, resp.Warnings, 1)
+		assert.Contains(t, resp.Warnings[0], "does not exist")
+
+		resp = handle(newScheduledAutoscaler("static"))
+		assert.True(t, resp.Allowed)
+		assert.Len(t, resp.Warnings, 1)
+		assert.Contains(t, resp.Warnings[0], "no autoscaling configured")
+	})
+
+	t.Run("target that cannot be scaled", func(t *testing.T) {
+		resp := handle(newScheduledAutoscaler("agent"))
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, metav1.StatusReasonInvalid, resp.Result.Reason)
+		assert.Equal(t, "spec.microserviceName", resp.Result.Details.Causes[0].Field)
+		assert.Contains(t, resp.Result.Details.Causes[0].Message, "runs a DaemonSet")
+	})
+
+	tests := []struct {
+		name   string
+		mutate func(sa *ScheduledAutoscaler)
+		field  string
+	}{
+		{
+			name:   "invalid cron",
+			mutate: func(sa *ScheduledAutoscaler) { sa.Spec.Schedules[1].Cron = "every hour" },
+			field:  "spec.schedules[1].cron",
+		},
+		{
+			name:   "min greater than max",
+			mutate: func(sa *ScheduledAutoscaler) { sa.Spec.Schedules[0].MinReplicas = 11 },
+			field:  "spec.schedules[0].minReplicas",
+		},
+		{
+			name:   "duplicate name",
+			mutate: func(sa *ScheduledAutoscaler) { sa.Spec.Schedules[1].Name = "day" },
+			field:  "spec.schedules[1].name",
+		},
+		{
+			name:   "empty microservice name",
+			mutate: func(sa *ScheduledAutoscaler) { sa.Spec.MicroserviceName = "" },
+			field:  "spec.microserviceName",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sa := newScheduledAutoscaler("scaled")
+			tc.mutate(sa)
+
+			resp := handle(sa)
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, metav1.StatusReasonInvalid, resp.Result.Reason)
+			assert.Equal(t, tc.field, resp.Result.Details.Causes[0].Field)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// conversionDataAnnotation holds the v1 spec and status of an object whose
+// v1 representation can not be expressed in v1beta1, so that converting it
+// back to v1 does not lose the fields that only exist in v1.
+const conversionDataAnnotation = "microservice.example.com/conversion-data"
+
+type conversionData struct {
+	Spec   json.RawMessage `json:"spec,omitempty"`
+	Status json.RawMessage `json:"status,omitempty"`
+}
+
+// marshalConversionData stores the given v1 spec and status on obj.
+func marshalConversionData(obj metav1.Object, spec, status interface{}) error {
+	rawSpec, err := json.Marshal(spec)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal spec")
+	}
+
+	rawStatus, err := json.Marshal(status)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal status")
+	}
+
+	data, err := json.Marshal(conversionData{Spec: rawSpec, Status: rawStatus})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal conversion data")
+	}
+
+	annotations := map[string]string{}
+	for k, v := range obj.GetAnnotations() {
+		annotations[k] = v
+	}
+	annotations[conversionDataAnnotation] = string(data)
+	obj.SetAnnotations(annotations)
+
+	return nil
+}
+
+// unmarshalConversionData restores the v1 spec and status stored on obj, if
+// any, and removes the annotation from obj.
+func unmarshalConversionData(obj metav1.Object, spec, status interface{}) error {
+	raw, ok := obj.GetAnnotations()[conversionDataAnnotation]
+	if !ok {
+		return nil
+	}
+
+	annotations := map[string]string{}
+	for k, v := range obj.GetAnnotations() {
+		if k != conversionDataAnnotation {
+			annotations[k] = v
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+
+	data := conversionData{}
+	err := json.Unmarshal([]byte(raw), &data)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal conversion data")
+	}
+
+	if len(data.Spec) > 0 {
+		err = json.Unmarshal(data.Spec, spec)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal spec")
+		}
+	}
+
+	if len(data.Status) > 0 {
+		err = json.Unmarshal(data.Status, status)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal status")
+		}
+	}
+
+	return nil
+}
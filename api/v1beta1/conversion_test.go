@@ -0,0 +1,196 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+)
+
+func TestMicroserviceConversion(t *testing.T) {
+	replicas := int32(2)
+	v1beta1ms := &Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   "default",
+			Annotations: map[string]string{"test": "annotation"},
+		},
+		Spec: MicroserviceSpec{
+			Image:    "image:latest",
+			Replicas: 3,
+			Labels:   map[string]string{"app": "test"},
+			Env:      map[string]string{"test": "env"},
+			Ingress: []Ingress{
+				{
+					Name:          "http",
+					ContainerPort: 8080,
+					Hosts:         []string{"foo.example.com"},
+					Paths:         []string{"/"},
+				},
+			},
+			IngressEnabled: true,
+			Tolerations: []corev1.Toleration{
+				{Key: "test", Value: "toleration", Effect: corev1.TaintEffectNoExecute},
+			},
+			Autoscaling: &autoscalingv2.HorizontalPodAutoscalerSpec{
+				MinReplicas: &replicas,
+				MaxReplicas: 5,
+			},
+		},
+		Status: MicroserviceStatus{
+			State: Stable,
+		},
+	}
+
+	t.Run("v1beta1 round trip", func(t *testing.T) {
+		hub := &microservicev1.Microservice{}
+		err := v1beta1ms.ConvertTo(hub)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo.example.com"}, hub.Spec.Ingress[0].Hosts)
+		assert.Equal(t, microservicev1.Stable, hub.Status.State)
+
+		restored := &Microservice{}
+		err = restored.ConvertFrom(hub)
+		assert.NoError(t, err)
+		assert.Equal(t, v1beta1ms, restored)
+	})
+
+	t.Run("hosts are serialized as hosts in v1", func(t *testing.T) {
+		hub := &microservicev1.Microservice{}
+		err := v1beta1ms.ConvertTo(hub)
+		assert.NoError(t, err)
+
+		raw, err := json.Marshal(hub.Spec.Ingress[0])
+		assert.NoError(t, err)
+		assert.Contains(t, string(raw), `"hosts":["foo.example.com"]`)
+
+		raw, err = json.Marshal(v1beta1ms.Spec.Ingress[0])
+		assert.NoError(t, err)
+		assert.Contains(t, string(raw), `"host":["foo.example.com"]`)
+	})
+
+	t.Run("conversion data", func(t *testing.T) {
+		hub := &microservicev1.Microservice{}
+		err := v1beta1ms.ConvertTo(hub)
+		assert.NoError(t, err)
+
+		spoke := &Microservice{}
+		err = spoke.ConvertFrom(hub)
+		assert.NoError(t, err)
+		assert.NotContains(t, spoke.Annotations, conversionDataAnnotation)
+
+		// fields set on the v1beta1 object take precedence over the stored
+		// v1 spec
+		stored := hub.DeepCopy()
+		stored.Spec.Ingress[0].Paths = []string{"/v1-only"}
+		err = marshalConversionData(spoke, stored.Spec, stored.Status)
+		assert.NoError(t, err)
+
+		spoke.Spec.Ingress[0].Paths = nil
+		restored := &microservicev1.Microservice{}
+		err = spoke.ConvertTo(restored)
+		assert.NoError(t, err)
+		assert.NotContains(t, restored.Annotations, conversionDataAnnotation)
+		assert.Equal(t, "annotation", restored.Annotations["test"])
+		assert.Nil(t, restored.Spec.Ingress[0].Paths)
+		assert.Equal(t, hub.Spec.Image, restored.Spec.Image)
+	})
+}
+
+func TestMicroserviceConversionV1Only(t *testing.T) {
+	replicas := int32(0)
+	hub := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:    "image:latest",
+			Replicas: &replicas,
+			Strategy: &microservicev1.StrategySpec{Type: microservicev1.RecreateStrategyType},
+		},
+		Status: microservicev1.MicroserviceStatus{
+			State:              microservicev1.Stable,
+			ObservedGeneration: 2,
+			ReadyReplicas:      1,
+			Image:              "image:latest",
+			Conditions: []metav1.Condition{
+				{
+					Type:               microservicev1.ConditionAvailable,
+					Status:             metav1.ConditionTrue,
+					Reason:             "MinimumReplicasAvailable",
+					LastTransitionTime: metav1.Unix(1, 0),
+				},
+			},
+		},
+	}
+
+	spoke := &Microservice{}
+	err := spoke.ConvertFrom(hub)
+	assert.NoError(t, err)
+	assert.Contains(t, spoke.Annotations, conversionDataAnnotation)
+	assert.Equal(t, Stable, spoke.Status.State)
+
+	restored := &microservicev1.Microservice{}
+	err = spoke.ConvertTo(restored)
+	assert.NoError(t, err)
+	assert.Equal(t, hub, restored)
+}
+
+func TestMicroserviceConversionReplicas(t *testing.T) {
+	spoke := &Microservice{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       MicroserviceSpec{Image: "image:latest"},
+	}
+
+	// an unset replica count stays unset so it is defaulted
+	hub := &microservicev1.Microservice{}
+	assert.NoError(t, spoke.ConvertTo(hub))
+	assert.Nil(t, hub.Spec.Replicas)
+
+	spoke.Spec.Replicas = 2
+	assert.NoError(t, spoke.ConvertTo(hub))
+	assert.Equal(t, int32(2), *hub.Spec.Replicas)
+}
+
+func TestScheduledAutoscalerConversion(t *testing.T) {
+	sa := &ScheduledAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: ScheduledAutoscalerSpec{
+			MicroserviceName: "foo",
+			Schedules: []Schedule{
+				{Name: "day", Cron: "0 8 * * *", MinReplicas: 3, MaxReplicas: 10},
+			},
+		},
+		Status: ScheduledAutoscalerStatus{
+			ScheduledCrons: []int{1},
+			State:          Stable,
+		},
+	}
+
+	hub := &microservicev1.ScheduledAutoscaler{}
+	err := sa.ConvertTo(hub)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, hub.Status.ScheduledCrons)
+	assert.Equal(t, "0 8 * * *", hub.Spec.Schedules[0].Cron)
+
+	// the status is serialized the same way in both versions
+	spokeStatus, err := json.Marshal(sa.Status)
+	assert.NoError(t, err)
+	hubStatus, err := json.Marshal(hub.Status)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(spokeStatus), string(hubStatus))
+
+	restored := &ScheduledAutoscaler{}
+	err = restored.ConvertFrom(hub)
+	assert.NoError(t, err)
+	assert.Equal(t, sa, restored)
+}
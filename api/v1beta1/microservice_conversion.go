@@ -0,0 +1,141 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+)
+
+var _ conversion.Convertible = &Microservice{}
+
+// ConvertTo converts this Microservice to the Hub version (v1).
+func (src *Microservice) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*microservicev1.Microservice)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	err := unmarshalConversionData(dst, &dst.Spec, &dst.Status)
+	if err != nil {
+		return err
+	}
+
+	convertMicroserviceSpecToV1(&src.Spec, &dst.Spec)
+	dst.Status.State = microservicev1.RunningState(src.Status.State)
+	dst.Status.Error = src.Status.Error
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1) to this version.
+func (dst *Microservice) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*microservicev1.Microservice)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	convertMicroserviceSpecFromV1(&src.Spec, &dst.Spec)
+	dst.Status.State = RunningState(src.Status.State)
+	dst.Status.Error = src.Status.Error
+
+	// keep the v1 representation around only if it can not be restored
+	// from the v1beta1 fields alone
+	restored := &microservicev1.Microservice{}
+	err := dst.ConvertTo(restored)
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(src.Spec, restored.Spec) && equality.Semantic.DeepEqual(src.Status, restored.Status) {
+		return nil
+	}
+
+	return marshalConversionData(dst, src.Spec, src.Status)
+}
+
+func convertMicroserviceSpecToV1(src *MicroserviceSpec, dst *microservicev1.MicroserviceSpec) {
+	ingresses := make([]microservicev1.Ingress, len(src.Ingress))
+	for i, ing := range src.Ingress {
+		// start from the restored entry so fields that only exist in v1
+		// are kept
+		if i < len(dst.Ingress) {
+			ingresses[i] = dst.Ingress[i]
+		}
+		ingresses[i].Hosts = ing.Hosts
+		ingresses[i].Annotations = ing.Annotations
+		ingresses[i].Paths = ing.Paths
+		ingresses[i].Name = ing.Name
+		ingresses[i].ContainerPort = ing.ContainerPort
+	}
+	if src.Ingress == nil {
+		ingresses = nil
+	}
+
+	dst.Ingress = ingresses
+	dst.PodAnnotations = src.PodAnnotations
+	dst.Env = src.Env
+	dst.Image = src.Image
+	dst.NodeSelector = src.NodeSelector
+	dst.Tolerations = src.Tolerations
+	dst.LivenessProbe = src.LivenessProbe
+	dst.ReadinessProbe = src.ReadinessProbe
+	// v1beta1 can not tell zero replicas from an unset replica count, so
+	// keep the restored value for zero
+	if src.Replicas != 0 {
+		replicas := src.Replicas
+		dst.Replicas = &replicas
+	} else if dst.Replicas != nil && *dst.Replicas != 0 {
+		dst.Replicas = nil
+	}
+	dst.Resources = src.Resources
+	dst.Labels = src.Labels
+	dst.IngressEnabled = src.IngressEnabled
+	dst.Autoscaling = src.Autoscaling
+	dst.DisableServiceAccountCreation = src.DisableServiceAccountCreation
+}
+
+func convertMicroserviceSpecFromV1(src *microservicev1.MicroserviceSpec, dst *MicroserviceSpec) {
+	var ingresses []Ingress
+	if src.Ingress != nil {
+		ingresses = make([]Ingress, len(src.Ingress))
+	}
+	for i, ing := range src.Ingress {
+		ingresses[i] = Ingress{
+			Hosts:         ing.Hosts,
+			Annotations:   ing.Annotations,
+			Paths:         ing.Paths,
+			Name:          ing.Name,
+			ContainerPort: ing.ContainerPort,
+		}
+	}
+
+	dst.Ingress = ingresses
+	dst.PodAnnotations = src.PodAnnotations
+	dst.Env = src.Env
+	dst.Image = src.Image
+	dst.NodeSelector = src.NodeSelector
+	dst.Tolerations = src.Tolerations
+	dst.LivenessProbe = src.LivenessProbe
+	dst.ReadinessProbe = src.ReadinessProbe
+	dst.Replicas = 0
+	if src.Replicas != nil {
+		dst.Replicas = *src.Replicas
+	}
+	dst.Resources = src.Resources
+	dst.Labels = src.Labels
+	dst.IngressEnabled = src.IngressEnabled
+	dst.Autoscaling = src.Autoscaling
+	dst.DisableServiceAccountCreation = src.DisableServiceAccountCreation
+}
@@ -129,25 +129,6 @@ type MicroserviceList struct {
 	Items           []Microservice `json:"items"`
 }
 
-//func (d *Microservice) SetDefaults() error {
-//	if d.Spec.Replicas == nil {
-//		return fmt.Errorf("spec.replicas is required")
-//	}
-//
-//	if d.Spec.Image == nil {
-//		return fmt.Errorf("spec.image is required")
-//	}
-//
-//	for _, ingress := range d.Spec.Ingress {
-//		if ingress.ContainerPort == nil {
-//
-//		}
-//
-//	}
-//
-//	return nil
-//}
-
 func init() {
 	SchemeBuilder.Register(&Microservice{}, &MicroserviceList{})
 }
@@ -0,0 +1,97 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+)
+
+var _ conversion.Convertible = &ScheduledAutoscaler{}
+
+// ConvertTo converts this ScheduledAutoscaler to the Hub version (v1).
+func (src *ScheduledAutoscaler) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*microservicev1.ScheduledAutoscaler)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	err := unmarshalConversionData(dst, &dst.Spec, &dst.Status)
+	if err != nil {
+		return err
+	}
+
+	var schedules []microservicev1.Schedule
+	if src.Spec.Schedules != nil {
+		schedules = make([]microservicev1.Schedule, len(src.Spec.Schedules))
+	}
+	for i, schedule := range src.Spec.Schedules {
+		if i < len(dst.Spec.Schedules) {
+			schedules[i] = dst.Spec.Schedules[i]
+		}
+		schedules[i].Name = schedule.Name
+		schedules[i].Cron = schedule.Cron
+		schedules[i].MinReplicas = schedule.MinReplicas
+		schedules[i].MaxReplicas = schedule.MaxReplicas
+	}
+
+	dst.Spec.MicroserviceName = src.Spec.MicroserviceName
+	dst.Spec.Schedules = schedules
+	dst.Status.ScheduledCrons = src.Status.ScheduledCrons
+	dst.Status.State = microservicev1.RunningState(src.Status.State)
+	dst.Status.Error = src.Status.Error
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1) to this version.
+func (dst *ScheduledAutoscaler) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*microservicev1.ScheduledAutoscaler)
+
+	var schedules []Schedule
+	if src.Spec.Schedules != nil {
+		schedules = make([]Schedule, len(src.Spec.Schedules))
+	}
+	for i, schedule := range src.Spec.Schedules {
+		schedules[i] = Schedule{
+			Name:        schedule.Name,
+			Cron:        schedule.Cron,
+			MinReplicas: schedule.MinReplicas,
+			MaxReplicas: schedule.MaxReplicas,
+		}
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec.MicroserviceName = src.Spec.MicroserviceName
+	dst.Spec.Schedules = schedules
+	dst.Status.ScheduledCrons = src.Status.ScheduledCrons
+	dst.Status.State = RunningState(src.Status.State)
+	dst.Status.Error = src.Status.Error
+
+	// keep the v1 representation around only if it can not be restored
+	// from the v1beta1 fields alone
+	restored := &microservicev1.ScheduledAutoscaler{}
+	err := dst.ConvertTo(restored)
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(src.Spec, restored.Spec) && equality.Semantic.DeepEqual(src.Status, restored.Status) {
+		return nil
+	}
+
+	return marshalConversionData(dst, src.Spec, src.Status)
+}
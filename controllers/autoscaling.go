@@ -4,37 +4,93 @@ import (
 	"context"
 	"fmt"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
 
 	"github.com/go-logr/logr"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 )
 
-func (r *MicroserviceReconciler) checkAutoscaling(mic *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger) error {
-	if mic.Spec.Autoscaling == nil {
+func (r *MicroserviceReconciler) checkAutoscaling(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	if !mic.AutoscalingEnabled() {
+		status.Autoscaling = nil
 		return r.Resources.DeleteHPA(types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()}, reqLogger)
 	}
 
+	current := &autoscalingv2.HorizontalPodAutoscaler{}
+
 	if mic.Annotations["scheduledautoscaler.override"] == "true" {
 		l := fmt.Sprintf("scheduledautoscaler override found, skipping checkAutoscaling for %s", mic.GetName())
 		reqLogger.Info(l)
+
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()}, current)
+		if err != nil && k8sErrors.IsNotFound(err) {
+			status.Autoscaling = nil
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		setAutoscalingStatus(status, current)
 		return nil
 	}
 
 	desired := microservice.GenerateAutoscalingv2(mic)
+	// the active Deployment of a blue/green rollout is scaled
+	desired.Spec.ScaleTargetRef.Name = deploymentName(mic, status)
 
 	err := r.Resources.CreateHPAIfNotExists(mic, desired, reqLogger)
 	if err != nil {
 		return err
 	}
 
-	current := &autoscalingv2.HorizontalPodAutoscaler{}
 	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
 	if err != nil {
 		return err
 	}
 
-	return r.Resources.Update(current, desired, reqLogger)
+	setAutoscalingStatus(status, current)
+
+	return r.updateResource(mic, status, current, desired, reqLogger)
+}
+
+// autoscaledReplicas returns the number of replicas to set on the workload
+// of an autoscaled Microservice: the current number of replicas, which is
+// owned by the HorizontalPodAutoscaler, limited to the bounds of its spec.
+// It returns nil if the workload is managed with server-side apply and the
+// replicas within the bounds are only owned by the HorizontalPodAutoscaler,
+// so that they are not claimed on every reconcile.
+func (r *MicroserviceReconciler) autoscaledReplicas(mic *microservicev1.Microservice, current resources.Object, replicas int32) (*int32, error) {
+	// a scheduled autoscaler changes the bounds of the
+	// HorizontalPodAutoscaler directly, so prefer them over the spec
+	bounds := *microservice.AutoscalingSpec(mic)
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()}, hpa)
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return nil, err
+	} else if err == nil {
+		bounds = hpa.Spec
+	}
+
+	bounded := microservice.ReplicasWithinAutoscalingBounds(replicas, bounds)
+	if bounded == replicas {
+		ownedByOthers, err := r.Resources.OwnedByOthers(current, "spec.replicas")
+		if err != nil || ownedByOthers {
+			return nil, err
+		}
+	}
+
+	return &bounded, nil
+}
+
+func setAutoscalingStatus(status *microservicev1.MicroserviceStatus, hpa *autoscalingv2.HorizontalPodAutoscaler) {
+	status.Autoscaling = &microservicev1.AutoscalingStatus{
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		LastScaleTime:   hpa.Status.LastScaleTime,
+	}
 }
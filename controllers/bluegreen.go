@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// checkBlueGreen advances the blue/green rollout of the Microservice and
+// brings the idle Deployment and the preview Service in line with it. It
+// runs before checkDeployment, checkAutoscaling and checkService, which
+// follow the active Deployment recorded here.
+func (r *MicroserviceReconciler) checkBlueGreen(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	if !mic.BlueGreenEnabled() && !handingBack(mic, status) {
+		status.BlueGreen = nil
+
+		// the green Deployment of another kind of workload is retired once
+		// the new workload is stable
+		if mic.GetWorkloadKind() == microservicev1.WorkloadKindDeployment {
+			err := r.deleteOwned(mic, &appsv1.Deployment{}, microservice.ColorName(mic, microservicev1.BlueGreenGreen), reqLogger)
+			if err != nil {
+				return err
+			}
+		}
+
+		return r.deleteOwned(mic, &corev1.Service{}, microservice.PreviewName(mic), reqLogger)
+	}
+
+	r.startPreview(mic, status)
+
+	err := r.advanceBlueGreen(mic, status)
+	if err != nil {
+		return err
+	}
+
+	err = r.applyIdleDeployment(mic, status, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	if status.BlueGreen.Phase != microservicev1.BlueGreenPreview {
+		return r.deleteOwned(mic, &corev1.Service{}, microservice.PreviewName(mic), reqLogger)
+	}
+
+	return r.applyTrackService(mic, status, microservice.GeneratePreviewServiceV1(mic, idleColor(status)), reqLogger)
+}
+
+// handingBack returns true while a Microservice that no longer uses the
+// BlueGreen strategy moves its traffic from the green Deployment back to the
+// blue one, which is the Deployment of the Microservice for every other
+// strategy.
+func handingBack(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) bool {
+	if status.BlueGreen == nil || mic.GetWorkloadKind() != microservicev1.WorkloadKindDeployment {
+		return false
+	}
+
+	return activeColor(status) == microservicev1.BlueGreenGreen || status.BlueGreen.Phase == microservicev1.BlueGreenScalingDown
+}
+
+// activeColor returns the color of the Deployment that receives the traffic
+// of the Microservice.
+func activeColor(status *microservicev1.MicroserviceStatus) microservicev1.BlueGreenColor {
+	if status.BlueGreen == nil || status.BlueGreen.ActiveColor == "" {
+		return microservicev1.BlueGreenBlue
+	}
+
+	return status.BlueGreen.ActiveColor
+}
+
+// idleColor returns the color of the Deployment that runs the preview of a
+// blue/green rollout.
+func idleColor(status *microservicev1.MicroserviceStatus) microservicev1.BlueGreenColor {
+	if activeColor(status) == microservicev1.BlueGreenGreen {
+		return microservicev1.BlueGreenBlue
+	}
+
+	return microservicev1.BlueGreenGreen
+}
+
+// deploymentName returns the name of the Deployment that receives the
+// traffic of the Microservice.
+func deploymentName(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) string {
+	return microservice.ColorName(mic, activeColor(status))
+}
+
+// previewRunning returns true while the idle Deployment runs next to the
+// active one.
+func previewRunning(status *microservicev1.MicroserviceStatus) bool {
+	if status.BlueGreen == nil {
+		return false
+	}
+
+	switch status.BlueGreen.Phase {
+	case microservicev1.BlueGreenPreview, microservicev1.BlueGreenScalingDown:
+		return true
+	}
+
+	return false
+}
+
+// startPreview starts a new preview when the image of the Microservice
+// changed. The first time, the image of the last completed rollout is taken
+// as the active image of the blue Deployment.
+func (r *MicroserviceReconciler) startPreview(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) {
+	if status.BlueGreen == nil {
+		active := status.Image
+		if active == "" {
+			active = mic.Spec.Image
+		}
+		status.BlueGreen = &microservicev1.BlueGreenStatus{
+			Phase:        microservicev1.BlueGreenActive,
+			ActiveColor:  microservicev1.BlueGreenBlue,
+			ActiveImage:  active,
+			PreviewImage: active,
+		}
+	}
+
+	// once the BlueGreen strategy is no longer used, the blue Deployment
+	// takes over the traffic of the green one by previewing the image of the
+	// spec, even if it did not change
+	blueGreen := status.BlueGreen
+	handBack := !mic.BlueGreenEnabled() && activeColor(status) == microservicev1.BlueGreenGreen &&
+		blueGreen.Phase != microservicev1.BlueGreenPreview
+	if !handBack && (!mic.BlueGreenEnabled() || mic.Spec.Image == blueGreen.PreviewImage) {
+		return
+	}
+
+	*blueGreen = microservicev1.BlueGreenStatus{
+		Phase:        microservicev1.BlueGreenActive,
+		ActiveColor:  activeColor(status),
+		ActiveImage:  blueGreen.ActiveImage,
+		PreviewImage: mic.Spec.Image,
+		SwitchedAt:   blueGreen.SwitchedAt,
+	}
+	if mic.Spec.Image == blueGreen.ActiveImage && !handBack {
+		return
+	}
+
+	blueGreen.Phase = microservicev1.BlueGreenPreview
+	r.Recorder.Eventf(mic, corev1.EventTypeNormal, "PreviewStarted", "Started preview of image %s on deployment %s",
+		blueGreen.PreviewImage, microservice.ColorName(mic, idleColor(status)))
+}
+
+// advanceBlueGreen moves the blue/green rollout to its next phase: once the
+// preview is available and promoted, the idle Deployment becomes the active
+// one and receives the traffic of the Microservice, while the previously
+// active one keeps running until the scale down delay elapsed.
+func (r *MicroserviceReconciler) advanceBlueGreen(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) error {
+	blueGreen := status.BlueGreen
+	action := mic.GetRolloutAction()
+
+	switch blueGreen.Phase {
+	case microservicev1.BlueGreenPreview:
+		if action == microservicev1.RolloutActionAbort {
+			blueGreen.Phase = microservicev1.BlueGreenAborted
+			r.Recorder.Eventf(mic, corev1.EventTypeWarning, "PreviewAborted", "Aborted preview of image %s", blueGreen.PreviewImage)
+			return r.clearRolloutAction(mic)
+		}
+
+		// a promotion is kept until the preview is available, the hand back
+		// of a disabled blue/green rollout needs none
+		autoPromote := !mic.BlueGreenEnabled() || (mic.Spec.Strategy.BlueGreen != nil && mic.Spec.Strategy.BlueGreen.AutoPromote)
+		if action != microservicev1.RolloutActionPromote && !autoPromote {
+			return nil
+		}
+		preview := microservice.ColorName(mic, idleColor(status))
+		available, err := r.trackAvailable(mic, preview, blueGreen.PreviewImage)
+		if err != nil || !available {
+			return err
+		}
+
+		now := metav1.Now()
+		blueGreen.Phase = microservicev1.BlueGreenScalingDown
+		blueGreen.ActiveColor = idleColor(status)
+		blueGreen.ActiveImage = blueGreen.PreviewImage
+		blueGreen.SwitchedAt = &now
+		r.Recorder.Eventf(mic, corev1.EventTypeNormal, "PreviewPromoted", "Switched the Service to deployment %s running image %s", preview, blueGreen.ActiveImage)
+	case microservicev1.BlueGreenScalingDown:
+		if blueGreen.SwitchedAt == nil || !time.Now().Before(blueGreen.SwitchedAt.Add(microservice.ScaleDownDelay(mic))) {
+			blueGreen.Phase = microservicev1.BlueGreenActive
+		}
+	}
+
+	// there is nothing to promote or abort outside of a preview
+	if action != "" && blueGreen.Phase != microservicev1.BlueGreenPreview {
+		return r.clearRolloutAction(mic)
+	}
+
+	return nil
+}
+
+// applyIdleDeployment brings the Deployment that does not receive the
+// traffic of the Microservice in line with the blue/green rollout. During a
+// preview it runs the preview image with as many replicas as the active
+// Deployment, so that it can take over all of its traffic. After a promotion
+// it keeps running until the scale down delay elapsed, and it is scaled down
+// to zero replicas otherwise.
+func (r *MicroserviceReconciler) applyIdleDeployment(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	blueGreen := status.BlueGreen
+	if blueGreen.Phase == microservicev1.BlueGreenScalingDown {
+		return nil
+	}
+
+	desired := microservice.GenerateColorDeployment(mic, idleColor(status))
+	current := &appsv1.Deployment{}
+
+	if blueGreen.Phase == microservicev1.BlueGreenPreview {
+		replicas, err := r.activeReplicas(mic, status)
+		if err != nil {
+			return err
+		}
+		err = r.setDependencyHash(mic, &desired.Spec.Template)
+		if err != nil {
+			return err
+		}
+		microservice.SetContainerImage(&desired.Spec.Template, mic.GetName(), blueGreen.PreviewImage)
+		desired.Spec.Replicas = &replicas
+
+		err = r.Resources.CreateDeploymentIfNotExists(mic, desired, reqLogger)
+		if err != nil {
+			return err
+		}
+		err = r.getOwned(mic, current, desired.Name)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := r.getOwned(mic, current, desired.Name)
+		if err != nil && k8sErrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// the pods are not rolled out again only to be scaled down
+		replicas := int32(0)
+		desired.Spec.Template = current.Spec.Template
+		desired.Spec.Replicas = &replicas
+	}
+
+	keepDeploymentSelector(current, desired)
+
+	return r.updateResource(mic, status, current, desired, reqLogger)
+}
+
+// activeReplicas returns the number of replicas of the active Deployment.
+func (r *MicroserviceReconciler) activeReplicas(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) (int32, error) {
+	current := &appsv1.Deployment{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: deploymentName(mic, status), Namespace: mic.GetNamespace()}, current)
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return 0, errors.Wrap(err, "failed to get the active deployment")
+	} else if err == nil && current.Spec.Replicas != nil {
+		return *current.Spec.Replicas, nil
+	}
+
+	return *microservice.GenerateDeployment(mic).Spec.Replicas, nil
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestBlueGreen(t *testing.T) {
+	blueKey := types.NamespacedName{Name: "foo", Namespace: "default"}
+	greenKey := types.NamespacedName{Name: "foo-green", Namespace: "default"}
+	previewKey := types.NamespacedName{Name: "foo-preview", Namespace: "default"}
+
+	// newBlueGreen returns a Microservice that previews image:v2 next to a
+	// Deployment running image:v1
+	newBlueGreen := func(t *testing.T, blueGreen *microservicev1.BlueGreenStrategy) (*MicroserviceReconciler, *microservicev1.Microservice, *microservicev1.MicroserviceStatus) {
+		replicas := int32(3)
+		r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+			Image:    "image:v2",
+			Replicas: &replicas,
+			Ingress: []microservicev1.Ingress{
+				{Name: "http", ContainerPort: 8080},
+			},
+			Strategy: &microservicev1.StrategySpec{
+				Type:      microservicev1.BlueGreenStrategyType,
+				BlueGreen: blueGreen,
+			},
+		})
+		status.Image = "image:v1"
+		return r, mic, status
+	}
+
+	// available marks a Deployment as rolled out
+	available := func(t *testing.T, r *MicroserviceReconciler, key types.NamespacedName) {
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		current.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: current.Generation,
+			Replicas:           *current.Spec.Replicas,
+			UpdatedReplicas:    *current.Spec.Replicas,
+			AvailableReplicas:  *current.Spec.Replicas,
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	}
+
+	// reconcile runs the blue/green, Deployment, autoscaling and Service
+	// checks in the order of Reconcile
+	reconcile := func(t *testing.T, r *MicroserviceReconciler, mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) {
+		require.NoError(t, r.checkBlueGreen(mic, status, log.Log))
+		require.NoError(t, r.checkDeployment(mic, status, log.Log))
+		require.NoError(t, r.checkAutoscaling(mic, status, log.Log))
+		require.NoError(t, r.checkService(mic, status, log.Log))
+	}
+
+	// promote switches the Service to the available preview
+	promote := func(t *testing.T, r *MicroserviceReconciler, mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, preview types.NamespacedName) {
+		available(t, r, preview)
+		mic.Annotations = map[string]string{microservicev1.RolloutActionAnnotation: string(microservicev1.RolloutActionPromote)}
+		reconcile(t, r, mic, status)
+		require.Equal(t, microservicev1.BlueGreenScalingDown, status.BlueGreen.Phase)
+	}
+
+	deployment := func(t *testing.T, r *MicroserviceReconciler, key types.NamespacedName) *appsv1.Deployment {
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		return current
+	}
+
+	image := func(t *testing.T, r *MicroserviceReconciler, key types.NamespacedName) string {
+		return deployment(t, r, key).Spec.Template.Spec.Containers[0].Image
+	}
+
+	replicas := func(t *testing.T, r *MicroserviceReconciler, key types.NamespacedName) int32 {
+		return *deployment(t, r, key).Spec.Replicas
+	}
+
+	serviceTrack := func(t *testing.T, r *MicroserviceReconciler, key types.NamespacedName) string {
+		current := &corev1.Service{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		return current.Spec.Selector[microservicev1.TrackLabel]
+	}
+
+	// elapse moves the last switch of the Service past the scale down delay
+	elapse := func(status *microservicev1.MicroserviceStatus) {
+		status.BlueGreen.SwitchedAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+	}
+
+	assertNotFound := func(t *testing.T, r *MicroserviceReconciler, key types.NamespacedName, obj client.Object) {
+		err := r.Client.Get(context.TODO(), key, obj)
+		assert.True(t, k8sErrors.IsNotFound(err), "%s: %v", key.Name, err)
+	}
+
+	t.Run("manual promotion", func(t *testing.T) {
+		r, mic, status := newBlueGreen(t, nil)
+
+		reconcile(t, r, mic, status)
+		require.NotNil(t, status.BlueGreen)
+		assert.Equal(t, microservicev1.BlueGreenPreview, status.BlueGreen.Phase)
+		assert.Equal(t, microservicev1.BlueGreenBlue, status.BlueGreen.ActiveColor)
+		assert.Equal(t, "image:v1", status.BlueGreen.ActiveImage)
+		assert.Equal(t, "image:v2", status.BlueGreen.PreviewImage)
+		assert.Equal(t, "image:v1", image(t, r, blueKey))
+		assert.Equal(t, microservicev1.TrackStable, serviceTrack(t, r, blueKey))
+
+		assert.Equal(t, "image:v2", image(t, r, greenKey))
+		assert.Equal(t, int32(3), replicas(t, r, greenKey))
+		assert.Equal(t, microservicev1.TrackGreen, serviceTrack(t, r, previewKey))
+
+		// the Service is not switched without a promotion
+		available(t, r, greenKey)
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenPreview, status.BlueGreen.Phase)
+		assert.Equal(t, microservicev1.TrackStable, serviceTrack(t, r, blueKey))
+
+		// the promoted Deployment keeps its pods and takes over the traffic
+		preview := deployment(t, r, greenKey).Spec
+		promote(t, r, mic, status, greenKey)
+		assert.NotContains(t, mic.Annotations, microservicev1.RolloutActionAnnotation)
+		assert.Equal(t, microservicev1.BlueGreenGreen, status.BlueGreen.ActiveColor)
+		assert.Equal(t, "image:v2", status.BlueGreen.ActiveImage)
+		assert.Equal(t, preview, deployment(t, r, greenKey).Spec)
+		assert.Equal(t, microservicev1.TrackGreen, serviceTrack(t, r, blueKey))
+		assertNotFound(t, r, previewKey, &corev1.Service{})
+
+		require.NoError(t, r.checkDeploymentStatus(mic, status, log.Log))
+		assert.Equal(t, "app.kubernetes.io/name=foo,microservice.example.com/track=green", status.Selector)
+		assert.Equal(t, "image:v2", status.Image)
+
+		// while the previous one keeps running until the scale down delay
+		// elapsed
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenScalingDown, status.BlueGreen.Phase)
+		assert.Equal(t, "image:v1", image(t, r, blueKey))
+		assert.Equal(t, int32(3), replicas(t, r, blueKey))
+
+		elapse(status)
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenActive, status.BlueGreen.Phase)
+		assert.Equal(t, "image:v1", image(t, r, blueKey))
+		assert.Equal(t, int32(0), replicas(t, r, blueKey))
+		assert.Equal(t, int32(3), replicas(t, r, greenKey))
+
+		// the next image is previewed on the blue Deployment
+		mic.Spec.Image = "image:v3"
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenPreview, status.BlueGreen.Phase)
+		assert.Equal(t, "image:v3", image(t, r, blueKey))
+		assert.Equal(t, int32(3), replicas(t, r, blueKey))
+		assert.Equal(t, "image:v2", image(t, r, greenKey))
+		assert.Equal(t, microservicev1.TrackStable, serviceTrack(t, r, previewKey))
+
+		promote(t, r, mic, status, blueKey)
+		assert.Equal(t, microservicev1.BlueGreenBlue, status.BlueGreen.ActiveColor)
+		assert.Equal(t, microservicev1.TrackStable, serviceTrack(t, r, blueKey))
+	})
+
+	t.Run("auto promotion", func(t *testing.T) {
+		r, mic, status := newBlueGreen(t, &microservicev1.BlueGreenStrategy{AutoPromote: true})
+
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenPreview, status.BlueGreen.Phase)
+
+		available(t, r, greenKey)
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenScalingDown, status.BlueGreen.Phase)
+		assert.Equal(t, microservicev1.BlueGreenGreen, status.BlueGreen.ActiveColor)
+		assert.Equal(t, microservicev1.TrackGreen, serviceTrack(t, r, blueKey))
+	})
+
+	t.Run("autoscaling", func(t *testing.T) {
+		r, mic, status := newBlueGreen(t, nil)
+		mic.Spec.Replicas = nil
+		mic.Spec.Scaling = &microservicev1.ScalingSpec{MaxReplicas: 10}
+		require.NoError(t, r.Client.Update(context.TODO(), mic))
+
+		reconcile(t, r, mic, status)
+
+		// the preview runs as many replicas as the autoscaled Deployment
+		scaled := int32(5)
+		blue := deployment(t, r, blueKey)
+		blue.Spec.Replicas = &scaled
+		require.NoError(t, r.Client.Update(context.TODO(), blue))
+		reconcile(t, r, mic, status)
+		assert.Equal(t, int32(5), replicas(t, r, greenKey))
+
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		require.NoError(t, r.Client.Get(context.TODO(), blueKey, hpa))
+		assert.Equal(t, "foo", hpa.Spec.ScaleTargetRef.Name)
+
+		// and the HorizontalPodAutoscaler follows the promoted Deployment
+		promote(t, r, mic, status, greenKey)
+		require.NoError(t, r.Client.Get(context.TODO(), blueKey, hpa))
+		assert.Equal(t, "foo-green", hpa.Spec.ScaleTargetRef.Name)
+		assert.Equal(t, int32(5), replicas(t, r, greenKey))
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		r, mic, status := newBlueGreen(t, nil)
+
+		reconcile(t, r, mic, status)
+		mic.Annotations = map[string]string{microservicev1.RolloutActionAnnotation: string(microservicev1.RolloutActionAbort)}
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenAborted, status.BlueGreen.Phase)
+		assert.Equal(t, "image:v1", image(t, r, blueKey))
+		assert.Equal(t, int32(0), replicas(t, r, greenKey))
+		assert.Equal(t, microservicev1.TrackStable, serviceTrack(t, r, blueKey))
+		assertNotFound(t, r, previewKey, &corev1.Service{})
+
+		stored := &microservicev1.Microservice{}
+		require.NoError(t, r.Client.Get(context.TODO(), blueKey, stored))
+		assert.NotContains(t, stored.Annotations, microservicev1.RolloutActionAnnotation)
+
+		// the aborted image is not previewed again, a new one is
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenAborted, status.BlueGreen.Phase)
+
+		mic.Spec.Image = "image:v3"
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenPreview, status.BlueGreen.Phase)
+		assert.Equal(t, "image:v3", status.BlueGreen.PreviewImage)
+		assert.Equal(t, "image:v1", status.BlueGreen.ActiveImage)
+		assert.Equal(t, int32(3), replicas(t, r, greenKey))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		r, mic, status := newBlueGreen(t, nil)
+
+		reconcile(t, r, mic, status)
+		mic.Spec.Strategy = nil
+		reconcile(t, r, mic, status)
+		assert.Nil(t, status.BlueGreen)
+		assert.Equal(t, "image:v2", image(t, r, blueKey))
+		assertNotFound(t, r, greenKey, &appsv1.Deployment{})
+		assertNotFound(t, r, previewKey, &corev1.Service{})
+	})
+
+	t.Run("disabled while green is active", func(t *testing.T) {
+		r, mic, status := newBlueGreen(t, nil)
+
+		reconcile(t, r, mic, status)
+		promote(t, r, mic, status, greenKey)
+		elapse(status)
+		reconcile(t, r, mic, status)
+		require.Equal(t, int32(0), replicas(t, r, blueKey))
+
+		// the blue Deployment takes the traffic back before the green one is
+		// removed
+		mic.Spec.Strategy = nil
+		reconcile(t, r, mic, status)
+		require.NotNil(t, status.BlueGreen)
+		assert.Equal(t, microservicev1.BlueGreenPreview, status.BlueGreen.Phase)
+		assert.Equal(t, "image:v2", image(t, r, blueKey))
+		assert.Equal(t, int32(3), replicas(t, r, blueKey))
+		assert.Equal(t, microservicev1.TrackGreen, serviceTrack(t, r, blueKey))
+
+		available(t, r, blueKey)
+		reconcile(t, r, mic, status)
+		assert.Equal(t, microservicev1.BlueGreenScalingDown, status.BlueGreen.Phase)
+		assert.Equal(t, microservicev1.BlueGreenBlue, status.BlueGreen.ActiveColor)
+		assert.Equal(t, microservicev1.TrackStable, serviceTrack(t, r, blueKey))
+		assert.Equal(t, int32(3), replicas(t, r, greenKey))
+
+		elapse(status)
+		reconcile(t, r, mic, status)
+		reconcile(t, r, mic, status)
+		assert.Nil(t, status.BlueGreen)
+		assertNotFound(t, r, greenKey, &appsv1.Deployment{})
+		assert.Equal(t, microservicev1.TrackStable, serviceTrack(t, r, blueKey))
+	})
+}
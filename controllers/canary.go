@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// checkCanary advances the canary rollout of the Microservice and creates or
+// removes the canary Deployment, Service and Ingresses accordingly. It runs
+// before checkDeployment, which rolls out the stable image recorded here.
+func (r *MicroserviceReconciler) checkCanary(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	if mic.Spec.Canary == nil {
+		status.Canary = nil
+		return r.deleteCanary(mic, reqLogger)
+	}
+
+	r.startCanary(mic, status)
+
+	err := r.takeRolloutAction(mic, status)
+	if err != nil {
+		return err
+	}
+
+	if canaryInProgress(status) {
+		err = r.advanceCanary(mic, status)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !canaryInProgress(status) {
+		return r.deleteCanary(mic, reqLogger)
+	}
+
+	return r.applyCanary(mic, status, reqLogger)
+}
+
+// canaryInProgress returns true while a canary runs next to the stable pods.
+func canaryInProgress(status *microservicev1.MicroserviceStatus) bool {
+	return status.Canary != nil &&
+		(status.Canary.Phase == microservicev1.CanaryProgressing || status.Canary.Phase == microservicev1.CanaryPaused)
+}
+
+// startCanary starts a new canary rollout when the image of the Microservice
+// changed. The first time, the image of the last completed rollout is taken
+// as the stable image.
+func (r *MicroserviceReconciler) startCanary(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) {
+	if status.Canary == nil {
+		stable := status.Image
+		if stable == "" {
+			stable = mic.Spec.Image
+		}
+		status.Canary = &microservicev1.CanaryStatus{
+			Phase:       microservicev1.CanaryPromoted,
+			StableImage: stable,
+			Image:       stable,
+		}
+	}
+
+	canary := status.Canary
+	if mic.Spec.Image == canary.Image {
+		return
+	}
+
+	*canary = microservicev1.CanaryStatus{
+		Phase:       microservicev1.CanaryPromoted,
+		StableImage: canary.StableImage,
+		Image:       mic.Spec.Image,
+	}
+	if mic.Spec.Image == canary.StableImage {
+		return
+	}
+
+	canary.Phase = microservicev1.CanaryProgressing
+	canary.Weight = mic.Spec.Canary.Steps[0].Weight
+	r.Recorder.Eventf(mic, corev1.EventTypeNormal, "CanaryStarted", "Started canary rollout of image %s", canary.Image)
+}
+
+// takeRolloutAction promotes or aborts the canary rollout as requested by the
+// rollout annotation and removes the annotation.
+func (r *MicroserviceReconciler) takeRolloutAction(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) error {
+	action := mic.GetRolloutAction()
+	if action == "" {
+		return nil
+	}
+
+	if canaryInProgress(status) {
+		switch action {
+		case microservicev1.RolloutActionPromote:
+			r.promoteCanary(mic, status)
+		case microservicev1.RolloutActionAbort:
+			canary := status.Canary
+			canary.Phase = microservicev1.CanaryAborted
+			canary.Weight = 0
+			canary.StepStartedAt = nil
+			r.Recorder.Eventf(mic, corev1.EventTypeWarning, "CanaryAborted", "Aborted canary rollout of image %s", canary.Image)
+		}
+	}
+
+	return r.clearRolloutAction(mic)
+}
+
+// clearRolloutAction removes the rollout annotation once its action was
+// taken.
+func (r *MicroserviceReconciler) clearRolloutAction(mic *microservicev1.Microservice) error {
+	patch := client.MergeFrom(mic.DeepCopy())
+	delete(mic.Annotations, microservicev1.RolloutActionAnnotation)
+	err := r.Client.Patch(context.TODO(), mic, patch)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove the rollout annotation")
+	}
+
+	return nil
+}
+
+// advanceCanary moves the canary rollout to its next step once the pause of
+// the current step elapsed, counting from when the canary Deployment became
+// available, and promotes the canary after the last step.
+func (r *MicroserviceReconciler) advanceCanary(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) error {
+	canary := status.Canary
+	steps := mic.Spec.Canary.Steps
+
+	// steps may have been removed from the spec during the rollout
+	if int(canary.Step) >= len(steps) {
+		r.promoteCanary(mic, status)
+		return nil
+	}
+	canary.Weight = steps[canary.Step].Weight
+
+	available, err := r.trackAvailable(mic, microservice.CanaryName(mic), canary.Image)
+	if err != nil || !available {
+		return err
+	}
+
+	now := metav1.Now()
+	if canary.StepStartedAt == nil {
+		canary.StepStartedAt = &now
+	}
+
+	step := steps[canary.Step]
+	if step.Pause == nil {
+		canary.Phase = microservicev1.CanaryPaused
+		return nil
+	}
+	canary.Phase = microservicev1.CanaryProgressing
+	if now.Before(&metav1.Time{Time: canary.StepStartedAt.Add(step.Pause.Duration)}) {
+		return nil
+	}
+
+	canary.Step++
+	if int(canary.Step) == len(steps) {
+		r.promoteCanary(mic, status)
+		return nil
+	}
+	canary.Weight = steps[canary.Step].Weight
+	canary.StepStartedAt = &now
+
+	return nil
+}
+
+// promoteCanary makes the canary image the stable image, which rolls it out
+// to the Deployment of the Microservice.
+func (r *MicroserviceReconciler) promoteCanary(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) {
+	canary := status.Canary
+	canary.Phase = microservicev1.CanaryPromoted
+	canary.StableImage = canary.Image
+	canary.Weight = 0
+	canary.StepStartedAt = nil
+	r.Recorder.Eventf(mic, corev1.EventTypeNormal, "CanaryPromoted", "Promoted canary image %s", canary.Image)
+}
+
+// applyCanary brings the canary Deployment, Service and Ingresses in line with
+// the canary rollout.
+func (r *MicroserviceReconciler) applyCanary(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	err := r.applyTrack(mic, status,
+		microservice.GenerateCanaryDeployment(mic, status.Canary.Image),
+		microservice.GenerateCanaryServiceV1(mic),
+		reqLogger,
+	)
+	if err != nil {
+		return err
+	}
+
+	// canary Ingresses are removed together with the Ingresses of the
+	// Microservice by checkIngress
+	if len(mic.Spec.Ingress) == 0 || !mic.Spec.IngressEnabled {
+		return nil
+	}
+
+	for _, desired := range microservice.GenerateCanaryIngressesV1(mic, status.Canary.Weight) {
+		err = r.Resources.CreateIngressIfNotExists(mic, desired, reqLogger)
+		if err != nil {
+			return err
+		}
+		current := &networking.Ingress{}
+		err = r.getOwned(mic, current, desired.Name)
+		if err != nil {
+			return err
+		}
+		err = r.updateResource(mic, status, current, desired, reqLogger)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteCanary removes the canary Deployment, Service and Ingresses of the
+// Microservice.
+func (r *MicroserviceReconciler) deleteCanary(mic *microservicev1.Microservice, reqLogger logr.Logger) error {
+	err := r.deleteTrack(mic, microservice.CanaryName(mic), reqLogger)
+	if err != nil {
+		return err
+	}
+
+	for _, ing := range mic.Spec.Ingress {
+		err = r.deleteOwned(mic, &networking.Ingress{}, microservice.CanaryIngressName(mic, ing.Name), reqLogger)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestCanary(t *testing.T) {
+	canaryKey := types.NamespacedName{Name: "foo-canary", Namespace: "default"}
+	ingressKey := types.NamespacedName{Name: "foo-http-canary", Namespace: "default"}
+
+	// newCanary returns a Microservice that rolls out image:v2 over image:v1
+	newCanary := func(t *testing.T, steps ...microservicev1.CanaryStep) (*MicroserviceReconciler, *microservicev1.Microservice, *microservicev1.MicroserviceStatus) {
+		r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+			Image: "image:v2",
+			Ingress: []microservicev1.Ingress{
+				{Name: "http", ContainerPort: 8080},
+			},
+			IngressEnabled: true,
+			Canary:         &microservicev1.CanarySpec{Steps: steps},
+		})
+		status.Image = "image:v1"
+		return r, mic, status
+	}
+
+	// canaryAvailable marks the canary Deployment as rolled out
+	canaryAvailable := func(t *testing.T, r *MicroserviceReconciler) {
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), canaryKey, current))
+		current.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: current.Generation,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	}
+
+	stableImage := func(t *testing.T, r *MicroserviceReconciler, mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) string {
+		require.NoError(t, r.checkDeployment(mic, status, log.Log))
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, current))
+		return current.Spec.Template.Spec.Containers[0].Image
+	}
+
+	canaryWeight := func(t *testing.T, r *MicroserviceReconciler) string {
+		current := &networking.Ingress{}
+		require.NoError(t, r.Client.Get(context.TODO(), ingressKey, current))
+		return current.Annotations["nginx.ingress.kubernetes.io/canary-weight"]
+	}
+
+	assertRemoved := func(t *testing.T, r *MicroserviceReconciler) {
+		for _, obj := range []client.Object{&appsv1.Deployment{}, &corev1.Service{}} {
+			err := r.Client.Get(context.TODO(), canaryKey, obj)
+			assert.True(t, k8sErrors.IsNotFound(err))
+		}
+		err := r.Client.Get(context.TODO(), ingressKey, &networking.Ingress{})
+		assert.True(t, k8sErrors.IsNotFound(err))
+	}
+
+	t.Run("steps", func(t *testing.T) {
+		pause := metav1.Duration{Duration: time.Minute}
+		r, mic, status := newCanary(t,
+			microservicev1.CanaryStep{Weight: 10, Pause: &pause},
+			microservicev1.CanaryStep{Weight: 50, Pause: &pause},
+		)
+
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		require.NotNil(t, status.Canary)
+		assert.Equal(t, microservicev1.CanaryProgressing, status.Canary.Phase)
+		assert.Equal(t, "image:v1", status.Canary.StableImage)
+		assert.Equal(t, "image:v2", status.Canary.Image)
+		assert.Equal(t, int32(10), status.Canary.Weight)
+		assert.Nil(t, status.Canary.StepStartedAt)
+		assert.Equal(t, "10", canaryWeight(t, r))
+		assert.Equal(t, "image:v1", stableImage(t, r, mic, status))
+
+		canaryDeployment := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), canaryKey, canaryDeployment))
+		assert.Equal(t, "image:v2", canaryDeployment.Spec.Template.Spec.Containers[0].Image)
+		canaryService := &corev1.Service{}
+		require.NoError(t, r.Client.Get(context.TODO(), canaryKey, canaryService))
+		assert.Equal(t, microservicev1.TrackCanary, canaryService.Spec.Selector[microservicev1.TrackLabel])
+
+		// the pause starts once the canary is available
+		canaryAvailable(t, r)
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		require.NotNil(t, status.Canary.StepStartedAt)
+		assert.Equal(t, int32(0), status.Canary.Step)
+
+		status.Canary.StepStartedAt = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		assert.Equal(t, int32(1), status.Canary.Step)
+		assert.Equal(t, int32(50), status.Canary.Weight)
+		assert.Equal(t, "50", canaryWeight(t, r))
+		assert.Equal(t, "image:v1", stableImage(t, r, mic, status))
+
+		status.Canary.StepStartedAt = &metav1.Time{Time: time.Now().Add(-2 * time.Minute)}
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		assert.Equal(t, microservicev1.CanaryPromoted, status.Canary.Phase)
+		assert.Equal(t, "image:v2", status.Canary.StableImage)
+		assert.Equal(t, "image:v2", stableImage(t, r, mic, status))
+		assertRemoved(t, r)
+	})
+
+	t.Run("selector", func(t *testing.T) {
+		r, mic, status := newCanary(t, microservicev1.CanaryStep{Weight: 20})
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		require.NoError(t, r.checkDeployment(mic, status, log.Log))
+		require.NoError(t, r.checkService(mic, status, log.Log))
+
+		// a pod of each Deployment, labeled by its template
+		for _, key := range []types.NamespacedName{{Name: "foo", Namespace: "default"}, canaryKey} {
+			current := &appsv1.Deployment{}
+			require.NoError(t, r.Client.Get(context.TODO(), key, current))
+			require.NoError(t, r.Client.Create(context.TODO(), &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name + "-abcde", Namespace: "default", Labels: current.Spec.Template.Labels},
+			}))
+		}
+
+		// the scale subresource and the Service only count the stable pods
+		require.NoError(t, r.checkDeploymentStatus(mic, status, log.Log))
+		selector, err := labels.Parse(status.Selector)
+		require.NoError(t, err)
+		pods := &corev1.PodList{}
+		require.NoError(t, r.Client.List(context.TODO(), pods, client.MatchingLabelsSelector{Selector: selector}))
+		require.Len(t, pods.Items, 1)
+		assert.Equal(t, "foo-abcde", pods.Items[0].Name)
+
+		service := &corev1.Service{}
+		require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, service))
+		require.NoError(t, r.Client.List(context.TODO(), pods, client.MatchingLabels(service.Spec.Selector)))
+		require.Len(t, pods.Items, 1)
+		assert.Equal(t, "foo-abcde", pods.Items[0].Name)
+	})
+
+	t.Run("manual promotion", func(t *testing.T) {
+		r, mic, status := newCanary(t, microservicev1.CanaryStep{Weight: 20})
+
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		canaryAvailable(t, r)
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		assert.Equal(t, microservicev1.CanaryPaused, status.Canary.Phase)
+		assert.Equal(t, "20", canaryWeight(t, r))
+
+		mic.Annotations = map[string]string{microservicev1.RolloutActionAnnotation: string(microservicev1.RolloutActionPromote)}
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		assert.Equal(t, microservicev1.CanaryPromoted, status.Canary.Phase)
+		assert.NotContains(t, mic.Annotations, microservicev1.RolloutActionAnnotation)
+		assert.Equal(t, "image:v2", stableImage(t, r, mic, status))
+		assertRemoved(t, r)
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		r, mic, status := newCanary(t, microservicev1.CanaryStep{Weight: 20})
+
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		mic.Annotations = map[string]string{microservicev1.RolloutActionAnnotation: string(microservicev1.RolloutActionAbort)}
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		assert.Equal(t, microservicev1.CanaryAborted, status.Canary.Phase)
+		assert.Equal(t, "image:v1", stableImage(t, r, mic, status))
+		assertRemoved(t, r)
+
+		stored := &microservicev1.Microservice{}
+		require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, stored))
+		assert.NotContains(t, stored.Annotations, microservicev1.RolloutActionAnnotation)
+
+		// the aborted image is not retried, a new one starts a new canary
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		assert.Equal(t, microservicev1.CanaryAborted, status.Canary.Phase)
+
+		mic.Spec.Image = "image:v3"
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		assert.Equal(t, microservicev1.CanaryProgressing, status.Canary.Phase)
+		assert.Equal(t, "image:v3", status.Canary.Image)
+		assert.Equal(t, "image:v1", status.Canary.StableImage)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		r, mic, status := newCanary(t, microservicev1.CanaryStep{Weight: 20})
+
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		mic.Spec.Canary = nil
+		require.NoError(t, r.checkCanary(mic, status, log.Log))
+		assert.Nil(t, status.Canary)
+		assert.Equal(t, "image:v2", stableImage(t, r, mic, status))
+		assertRemoved(t, r)
+	})
+}
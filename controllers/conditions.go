@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"fmt"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setReconciledCondition records the result of reconciling one kind of child
+// resource. A failure also marks the Microservice as degraded.
+func setReconciledCondition(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, conditionType string, err error) {
+	if err != nil {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             metav1.ConditionFalse,
+			Reason:             microservicev1.ReasonReconcileFailed,
+			Message:            err.Error(),
+			ObservedGeneration: mic.GetGeneration(),
+		})
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               microservicev1.ConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			Reason:             microservicev1.ReasonReconcileFailed,
+			Message:            fmt.Sprintf("%s: %s", conditionType, err.Error()),
+			ObservedGeneration: mic.GetGeneration(),
+		})
+		return
+	}
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             microservicev1.ReasonReconcileSucceeded,
+		ObservedGeneration: mic.GetGeneration(),
+	})
+}
+
+// setDeploymentConditions derives the Available, Progressing and Degraded
+// conditions from the status of the owned Deployment.
+func setDeploymentConditions(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, deployment *appsv1.Deployment) {
+	available := metav1.Condition{
+		Type:               microservicev1.ConditionAvailable,
+		Status:             metav1.ConditionFalse,
+		Reason:             microservicev1.ReasonRolloutInProgress,
+		Message:            "Deployment does not report availability yet",
+		ObservedGeneration: mic.GetGeneration(),
+	}
+	if cond := getDeploymentCondition(deployment, appsv1.DeploymentAvailable); cond != nil {
+		available.Status = metav1.ConditionStatus(cond.Status)
+		available.Reason = cond.Reason
+		available.Message = cond.Message
+	}
+	meta.SetStatusCondition(&status.Conditions, available)
+
+	progressing := metav1.Condition{
+		Type:               microservicev1.ConditionProgressing,
+		ObservedGeneration: mic.GetGeneration(),
+	}
+	degraded := metav1.Condition{
+		Type:               microservicev1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             microservicev1.ReasonAsExpected,
+		ObservedGeneration: mic.GetGeneration(),
+	}
+
+	if failed, message := deploymentRolloutFailed(deployment); failed {
+		progressing.Status = metav1.ConditionFalse
+		progressing.Reason = microservicev1.ReasonRolloutFailed
+		progressing.Message = message
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = microservicev1.ReasonRolloutFailed
+		degraded.Message = message
+	} else if deploymentRolledOut(deployment) {
+		progressing.Status = metav1.ConditionFalse
+		progressing.Reason = microservicev1.ReasonRolloutComplete
+		progressing.Message = "Deployment rollout is complete"
+	} else {
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = microservicev1.ReasonRolloutInProgress
+		progressing.Message = fmt.Sprintf("%d of %d updated replicas are available", deployment.Status.AvailableReplicas, deploymentReplicas(deployment))
+	}
+
+	meta.SetStatusCondition(&status.Conditions, progressing)
+	meta.SetStatusCondition(&status.Conditions, degraded)
+}
+
+// setRolledBackCondition marks the Microservice as degraded while it runs the
+// pod template restored by an automatic rollback.
+func setRolledBackCondition(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:   microservicev1.ConditionDegraded,
+		Status: metav1.ConditionTrue,
+		Reason: microservicev1.ReasonRolledBack,
+		Message: fmt.Sprintf("Rollout of image %s failed, rolled back to revision %s: %s",
+			status.Rollback.FailedImage, status.Rollback.RestoredRevision, status.Rollback.Message),
+		ObservedGeneration: mic.GetGeneration(),
+	})
+}
+
+// deploymentRunningState maps the rollout progress of the Deployment to the
+// running state of the Microservice: Stable once the rollout completed, Ready
+// once the minimum number of replicas is available and Reconciling otherwise.
+func deploymentRunningState(deployment *appsv1.Deployment) microservicev1.RunningState {
+	if deploymentRolledOut(deployment) {
+		return microservicev1.Stable
+	}
+
+	if cond := getDeploymentCondition(deployment, appsv1.DeploymentAvailable); cond != nil && cond.Status == corev1.ConditionTrue {
+		return microservicev1.Ready
+	}
+
+	return microservicev1.Reconciling
+}
+
+// deploymentRolledOut returns true once every replica of the Deployment runs
+// the current pod template and is available, the same way
+// `kubectl rollout status` does.
+func deploymentRolledOut(deployment *appsv1.Deployment) bool {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+
+	replicas := deploymentReplicas(deployment)
+
+	return deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
+
+// deploymentRolloutFailed returns true and the reason when the Deployment
+// controller gave up on the current rollout. The conditions of a Deployment
+// whose latest generation was not observed yet describe an earlier rollout.
+func deploymentRolloutFailed(deployment *appsv1.Deployment) (bool, string) {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, ""
+	}
+
+	if cond := getDeploymentCondition(deployment, appsv1.DeploymentProgressing); cond != nil && cond.Reason == "ProgressDeadlineExceeded" {
+		return true, cond.Message
+	}
+
+	if cond := getDeploymentCondition(deployment, appsv1.DeploymentReplicaFailure); cond != nil && cond.Status == corev1.ConditionTrue {
+		return true, cond.Message
+	}
+
+	return false, ""
+}
+
+func deploymentReplicas(deployment *appsv1.Deployment) int32 {
+	if deployment.Spec.Replicas == nil {
+		return 1
+	}
+
+	return *deployment.Spec.Replicas
+}
+
+func getDeploymentCondition(deployment *appsv1.Deployment, conditionType appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range deployment.Status.Conditions {
+		if deployment.Status.Conditions[i].Type == conditionType {
+			return &deployment.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
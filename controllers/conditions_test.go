@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConditions(t *testing.T) {
+	replicas := int32(2)
+	_, mic, _ := newFakeMicroservice(t, microservicev1.MicroserviceSpec{Image: "image:latest"})
+
+	t.Run("reconciled", func(t *testing.T) {
+		status := microservicev1.MicroserviceStatus{}
+
+		setReconciledCondition(mic, &status, microservicev1.ConditionServiceReconciled, nil)
+		assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionServiceReconciled))
+		assert.Nil(t, meta.FindStatusCondition(status.Conditions, microservicev1.ConditionDegraded))
+
+		setReconciledCondition(mic, &status, microservicev1.ConditionServiceReconciled, errors.New("boom"))
+		cond := meta.FindStatusCondition(status.Conditions, microservicev1.ConditionServiceReconciled)
+		assert.Equal(t, metav1.ConditionFalse, cond.Status)
+		assert.Equal(t, "boom", cond.Message)
+		assert.Equal(t, mic.Generation, cond.ObservedGeneration)
+		assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionDegraded))
+	})
+
+	t.Run("rollout in progress", func(t *testing.T) {
+		status := microservicev1.MicroserviceStatus{}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           2,
+				UpdatedReplicas:    1,
+				AvailableReplicas:  1,
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue, Reason: "MinimumReplicasAvailable"},
+				},
+			},
+		}
+
+		setDeploymentConditions(mic, &status, deployment)
+		assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionAvailable))
+		assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionProgressing))
+		assert.False(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionDegraded))
+	})
+
+	t.Run("rollout complete", func(t *testing.T) {
+		status := microservicev1.MicroserviceStatus{}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           2,
+				UpdatedReplicas:    2,
+				AvailableReplicas:  2,
+			},
+		}
+
+		setDeploymentConditions(mic, &status, deployment)
+		assert.False(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionAvailable))
+		cond := meta.FindStatusCondition(status.Conditions, microservicev1.ConditionProgressing)
+		assert.Equal(t, metav1.ConditionFalse, cond.Status)
+		assert.Equal(t, microservicev1.ReasonRolloutComplete, cond.Reason)
+	})
+
+	t.Run("rollout failed", func(t *testing.T) {
+		status := microservicev1.MicroserviceStatus{}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           3,
+				UpdatedReplicas:    1,
+				AvailableReplicas:  2,
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded", Message: "timed out"},
+				},
+			},
+		}
+
+		setDeploymentConditions(mic, &status, deployment)
+		cond := meta.FindStatusCondition(status.Conditions, microservicev1.ConditionDegraded)
+		assert.Equal(t, metav1.ConditionTrue, cond.Status)
+		assert.Equal(t, "timed out", cond.Message)
+		assert.False(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionProgressing))
+	})
+}
+
+func TestDeploymentRunningState(t *testing.T) {
+	replicas := int32(2)
+	deployment := func(updated, available int32, conditions ...appsv1.DeploymentCondition) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: 1},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 1,
+				Replicas:           2,
+				UpdatedReplicas:    updated,
+				AvailableReplicas:  available,
+				Conditions:         conditions,
+			},
+		}
+	}
+	availableCondition := appsv1.DeploymentCondition{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}
+
+	assert.Equal(t, microservicev1.Reconciling, deploymentRunningState(deployment(0, 0)))
+	assert.Equal(t, microservicev1.Ready, deploymentRunningState(deployment(1, 1, availableCondition)))
+	assert.Equal(t, microservicev1.Stable, deploymentRunningState(deployment(2, 2, availableCondition)))
+
+	stale := deployment(2, 2, availableCondition)
+	stale.Generation = 2
+	assert.Equal(t, microservicev1.Ready, deploymentRunningState(stale))
+}
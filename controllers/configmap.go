@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// checkConfigMap creates or updates the ConfigMap of the config files of the
+// Microservice, and removes it once the spec has no config files left.
+func (r *MicroserviceReconciler) checkConfigMap(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	desired := microservice.GenerateConfigMap(mic)
+	if desired == nil {
+		return r.deleteOwned(mic, &corev1.ConfigMap{}, microservice.ConfigMapName(mic), reqLogger)
+	}
+
+	err := r.Resources.CreateConfigMapIfNotExists(mic, desired, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	current := &corev1.ConfigMap{}
+	err = r.getOwned(mic, current, desired.Name)
+	if err != nil {
+		return err
+	}
+
+	return r.updateResource(mic, status, current, desired, reqLogger)
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestConfigMap(t *testing.T) {
+	key := types.NamespacedName{Name: "foo-config", Namespace: "default"}
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image:       "image:latest",
+		ConfigFiles: map[string]string{"app.yaml": "level: info\n"},
+	})
+
+	require.NoError(t, r.checkConfigMap(mic, status, log.Log))
+	current := &corev1.ConfigMap{}
+	require.NoError(t, r.Client.Get(context.TODO(), key, current))
+	assert.True(t, metav1.IsControlledBy(current, mic))
+	assert.Equal(t, "level: info\n", current.Data["app.yaml"])
+
+	mic.Spec.ConfigFiles["app.yaml"] = "level: debug\n"
+	mic.Generation = 2
+	require.NoError(t, r.checkConfigMap(mic, status, log.Log))
+	require.NoError(t, r.Client.Get(context.TODO(), key, current))
+	assert.Equal(t, "level: debug\n", current.Data["app.yaml"])
+
+	mic.Spec.ConfigFiles = nil
+	require.NoError(t, r.checkConfigMap(mic, status, log.Log))
+	err := r.Client.Get(context.TODO(), key, current)
+	assert.True(t, k8sErrors.IsNotFound(err))
+}
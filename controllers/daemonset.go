@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// checkDaemonSet creates or updates the DaemonSet of the Microservice.
+func (r *MicroserviceReconciler) checkDaemonSet(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	// the dependency hash is set from the start, so that the new DaemonSet is
+	// not rolled out a second time by the update below
+	desired := microservice.GenerateDaemonSet(mic)
+	err := r.setDependencyHash(mic, &desired.Spec.Template)
+	if err != nil {
+		return err
+	}
+
+	err = r.Resources.CreateDaemonSetIfNotExists(mic, desired.DeepCopy(), reqLogger)
+	if err != nil {
+		return err
+	}
+
+	current := &appsv1.DaemonSet{}
+	err = r.getOwned(mic, current, mic.GetName())
+	if err != nil {
+		return err
+	}
+
+	return r.updateResource(mic, status, current, desired, reqLogger)
+}
+
+// checkDaemonSetStatus copies the rollout progress of the owned DaemonSet
+// into the Microservice status and sets the running state from it. The
+// replicas of the status count the nodes the DaemonSet runs a pod on.
+func (r *MicroserviceReconciler) checkDaemonSetStatus(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	current := &appsv1.DaemonSet{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()}, current)
+	if err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(current.Spec.Selector)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the daemonset selector")
+	}
+
+	status.Replicas = current.Status.CurrentNumberScheduled
+	status.Selector = selector.String()
+	status.ReadyReplicas = current.Status.NumberReady
+	status.UpdatedReplicas = current.Status.UpdatedNumberScheduled
+
+	if daemonSetRolledOut(current) {
+		status.Image = containerImage(current.Spec.Template, mic.GetName())
+	}
+
+	setDaemonSetConditions(mic, status, current)
+
+	status.State = daemonSetRunningState(current)
+	if status.State != microservicev1.Stable {
+		reqLogger.Info("Waiting for daemonset rollout to complete",
+			"updated", current.Status.UpdatedNumberScheduled,
+			"available", current.Status.NumberAvailable,
+			"desired", current.Status.DesiredNumberScheduled,
+		)
+	}
+
+	return nil
+}
+
+// setDaemonSetConditions derives the Available, Progressing and Degraded
+// conditions from the status of the owned DaemonSet. DaemonSets do not
+// report failed rollouts, so the Microservice is never degraded by one.
+func setDaemonSetConditions(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, daemonSet *appsv1.DaemonSet) {
+	message := fmt.Sprintf("%d of %d nodes run an available pod", daemonSet.Status.NumberAvailable, daemonSet.Status.DesiredNumberScheduled)
+
+	available := metav1.Condition{
+		Type:               microservicev1.ConditionAvailable,
+		Status:             metav1.ConditionFalse,
+		Reason:             microservicev1.ReasonRolloutInProgress,
+		Message:            message,
+		ObservedGeneration: mic.GetGeneration(),
+	}
+	if daemonSetAvailable(daemonSet) {
+		available.Status = metav1.ConditionTrue
+		available.Reason = microservicev1.ReasonAsExpected
+	}
+	meta.SetStatusCondition(&status.Conditions, available)
+
+	progressing := metav1.Condition{
+		Type:               microservicev1.ConditionProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             microservicev1.ReasonRolloutInProgress,
+		Message:            message,
+		ObservedGeneration: mic.GetGeneration(),
+	}
+	if daemonSetRolledOut(daemonSet) {
+		progressing.Status = metav1.ConditionFalse
+		progressing.Reason = microservicev1.ReasonRolloutComplete
+		progressing.Message = "DaemonSet rollout is complete"
+	}
+	meta.SetStatusCondition(&status.Conditions, progressing)
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               microservicev1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             microservicev1.ReasonAsExpected,
+		ObservedGeneration: mic.GetGeneration(),
+	})
+}
+
+// daemonSetRunningState maps the rollout progress of the DaemonSet to the
+// running state of the Microservice, like deploymentRunningState.
+func daemonSetRunningState(daemonSet *appsv1.DaemonSet) microservicev1.RunningState {
+	if daemonSetRolledOut(daemonSet) {
+		return microservicev1.Stable
+	}
+
+	if daemonSetAvailable(daemonSet) {
+		return microservicev1.Ready
+	}
+
+	return microservicev1.Reconciling
+}
+
+// daemonSetRolledOut returns true once every node selected by the DaemonSet
+// runs an available pod of its current template, the same way
+// `kubectl rollout status` does.
+func daemonSetRolledOut(daemonSet *appsv1.DaemonSet) bool {
+	if daemonSet.Status.ObservedGeneration < daemonSet.Generation {
+		return false
+	}
+
+	desired := daemonSet.Status.DesiredNumberScheduled
+
+	return daemonSet.Status.UpdatedNumberScheduled == desired &&
+		daemonSet.Status.NumberAvailable == desired
+}
+
+// daemonSetAvailable returns true when at most as many pods of the DaemonSet
+// are unavailable as its rolling update takes down at a time.
+func daemonSetAvailable(daemonSet *appsv1.DaemonSet) bool {
+	desired := int(daemonSet.Status.DesiredNumberScheduled)
+	if desired == 0 {
+		return true
+	}
+
+	maxUnavailable := intstr.FromInt(1)
+	if daemonSet.Spec.UpdateStrategy.RollingUpdate != nil && daemonSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable != nil {
+		maxUnavailable = *daemonSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable
+	}
+	// the DaemonSet controller rounds percentages up as well
+	unavailable, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, desired, true)
+	if err != nil {
+		unavailable = 1
+	}
+
+	available := int(daemonSet.Status.NumberAvailable)
+
+	return available > 0 && available >= desired-unavailable
+}
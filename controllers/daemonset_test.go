@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestDaemonSet(t *testing.T) {
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image:        "image:v1",
+		WorkloadKind: microservicev1.WorkloadKindDaemonSet,
+		Tolerations:  []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+	})
+
+	reconcile := func(t *testing.T) {
+		err := r.checkWorkload(mic, status, log.Log)
+		setReconciledCondition(mic, status, workloadConditions[mic.GetWorkloadKind()], err)
+		require.NoError(t, err)
+		require.NoError(t, r.checkWorkloadStatus(mic, status, log.Log))
+		require.NoError(t, r.retireWorkloads(mic, status, log.Log))
+	}
+
+	setDaemonSetStatus := func(t *testing.T, available int32) {
+		daemonSet := &appsv1.DaemonSet{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, daemonSet))
+		daemonSet.Status = appsv1.DaemonSetStatus{
+			ObservedGeneration:     daemonSet.Generation,
+			DesiredNumberScheduled: 3,
+			CurrentNumberScheduled: 3,
+			UpdatedNumberScheduled: 3,
+			NumberReady:            available,
+			NumberAvailable:        available,
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), daemonSet))
+	}
+
+	reconcile(t)
+
+	daemonSet := &appsv1.DaemonSet{}
+	require.NoError(t, r.Client.Get(context.TODO(), key, daemonSet))
+	assert.True(t, metav1.IsControlledBy(daemonSet, mic))
+	assert.Equal(t, intstr.FromInt(1), *daemonSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable)
+	assert.Len(t, daemonSet.Spec.Template.Spec.Tolerations, 1)
+
+	setDaemonSetStatus(t, 1)
+	reconcile(t)
+	assert.Equal(t, microservicev1.Reconciling, status.State)
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, microservicev1.ConditionAvailable))
+
+	// a Deployment left over from before the switch to a DaemonSet is
+	// deleted once the DaemonSet rolled out
+	replicas := int32(1)
+	require.NoError(t, r.Client.Create(context.TODO(), &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "foo",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(mic, microservicev1.GroupVersion.WithKind("Microservice"))},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+	}))
+
+	setDaemonSetStatus(t, 3)
+	reconcile(t)
+	assert.Equal(t, microservicev1.Stable, status.State)
+	assert.Equal(t, "image:v1", status.Image)
+	assert.Equal(t, int32(3), status.Replicas)
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionAvailable))
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionDaemonSetReconciled))
+	assert.Nil(t, meta.FindStatusCondition(status.Conditions, microservicev1.ConditionDeploymentReconciled))
+
+	err := r.Client.Get(context.TODO(), key, &appsv1.Deployment{})
+	assert.True(t, k8sErrors.IsNotFound(err))
+}
+
+func TestDaemonSetRunningState(t *testing.T) {
+	newDaemonSet := func(available, updated int32, maxUnavailable intstr.IntOrString) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			Spec: appsv1.DaemonSetSpec{
+				UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+					RollingUpdate: &appsv1.RollingUpdateDaemonSet{MaxUnavailable: &maxUnavailable},
+				},
+			},
+			Status: appsv1.DaemonSetStatus{
+				DesiredNumberScheduled: 10,
+				UpdatedNumberScheduled: updated,
+				NumberAvailable:        available,
+			},
+		}
+	}
+
+	assert.Equal(t, microservicev1.Stable, daemonSetRunningState(newDaemonSet(10, 10, intstr.FromInt(1))))
+	assert.Equal(t, microservicev1.Ready, daemonSetRunningState(newDaemonSet(10, 5, intstr.FromInt(1))))
+	assert.Equal(t, microservicev1.Ready, daemonSetRunningState(newDaemonSet(9, 5, intstr.FromInt(1))))
+	assert.Equal(t, microservicev1.Reconciling, daemonSetRunningState(newDaemonSet(8, 5, intstr.FromInt(1))))
+	// 15% of 10 nodes is rounded up to 2
+	assert.Equal(t, microservicev1.Ready, daemonSetRunningState(newDaemonSet(8, 5, intstr.FromString("15%"))))
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// dependencyIndexes are the field indexes of the Microservices by the names of
+// the Secrets and ConfigMaps they depend on.
+var dependencyIndexes = map[microservicev1.DependencyKind]string{
+	microservicev1.DependencyKindSecret:    "spec.dependencies.secrets",
+	microservicev1.DependencyKindConfigMap: "spec.dependencies.configMaps",
+}
+
+// indexDependencies registers the dependency field indexes, so that the
+// Microservices depending on a Secret or ConfigMap can be found without
+// listing every Microservice of its namespace.
+func indexDependencies(ctx context.Context, indexer client.FieldIndexer) error {
+	for kind, index := range dependencyIndexes {
+		kind := kind
+		err := indexer.IndexField(ctx, &microservicev1.Microservice{}, index, func(obj client.Object) []string {
+			return microservice.DependencyNames(obj.(*microservicev1.Microservice), kind)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to index %s dependencies", kind)
+		}
+	}
+
+	return nil
+}
+
+// dependentMicroservices returns a handler.MapFunc that enqueues the
+// Microservices depending on a changed Secret or ConfigMap.
+func (r *MicroserviceReconciler) dependentMicroservices(kind microservicev1.DependencyKind) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		list := &microservicev1.MicroserviceList{}
+		err := r.Client.List(context.TODO(), list,
+			client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{dependencyIndexes[kind]: obj.GetName()},
+		)
+		if err != nil {
+			log.Log.Error(err, "Failed to list dependent microservices", "kind", kind, "name", obj.GetName())
+			return nil
+		}
+
+		requests := []reconcile.Request{}
+		for _, mic := range list.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()},
+			})
+		}
+		return requests
+	}
+}
+
+// setDependencyHash sets the hash of the content of the Secrets and
+// ConfigMaps the Microservice depends on as an annotation of the pod template
+// of a workload, so that changing them rolls out new pods.
+func (r *MicroserviceReconciler) setDependencyHash(mic *microservicev1.Microservice, template *corev1.PodTemplateSpec) error {
+	dependencies := microservice.Dependencies(mic)
+	if len(dependencies) == 0 {
+		return nil
+	}
+
+	hash := sha256.New()
+	for _, dependency := range dependencies {
+		data, err := r.dependencyData(mic, dependency)
+		if err != nil {
+			return err
+		}
+
+		// missing optional dependencies hash differently from empty ones, so
+		// that creating them restarts the pods as well
+		hash.Write([]byte(string(dependency.Kind) + "/" + dependency.Name))
+		if data == nil {
+			hash.Write([]byte{0})
+			continue
+		}
+		hash.Write([]byte{1})
+
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			hash.Write([]byte(key))
+			hash.Write([]byte{0})
+			hash.Write(data[key])
+			hash.Write([]byte{0})
+		}
+	}
+
+	// the annotations may be shared with the spec of the Microservice
+	annotations := map[string]string{}
+	for key, value := range template.Annotations {
+		annotations[key] = value
+	}
+	annotations[microservicev1.DependencyHashAnnotation] = hex.EncodeToString(hash.Sum(nil))
+	template.Annotations = annotations
+
+	return nil
+}
+
+// dependencyData returns the data of a Secret or ConfigMap the Microservice
+// depends on, or nil if it does not exist.
+func (r *MicroserviceReconciler) dependencyData(mic *microservicev1.Microservice, dependency microservicev1.DependencyReference) (map[string][]byte, error) {
+	key := types.NamespacedName{Name: dependency.Name, Namespace: mic.GetNamespace()}
+
+	var obj client.Object = &corev1.Secret{}
+	if dependency.Kind == microservicev1.DependencyKindConfigMap {
+		obj = &corev1.ConfigMap{}
+	}
+	err := r.Client.Get(context.TODO(), key, obj)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %s %s", dependency.Kind, dependency.Name)
+	}
+
+	data := map[string][]byte{}
+	switch obj := obj.(type) {
+	case *corev1.Secret:
+		for key, value := range obj.Data {
+			data[key] = value
+		}
+	case *corev1.ConfigMap:
+		for key, value := range obj.Data {
+			data[key] = []byte(value)
+		}
+		for key, value := range obj.BinaryData {
+			data[key] = value
+		}
+	}
+
+	return data, nil
+}
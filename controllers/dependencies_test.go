@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestDependencyHash(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image:          "image:latest",
+		PodAnnotations: map[string]string{"team": "a"},
+		EnvFrom: []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}}},
+			{ConfigMapRef: &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+				Optional:             boolPtr(true),
+			}},
+		},
+	}, secret)
+
+	podAnnotations := func(t *testing.T) map[string]string {
+		require.NoError(t, r.checkDeployment(mic, status, log.Log))
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "default"}, current))
+		return current.Spec.Template.Annotations
+	}
+
+	annotations := podAnnotations(t)
+	hash := annotations[microservicev1.DependencyHashAnnotation]
+	assert.NotEmpty(t, hash)
+	assert.Equal(t, "a", annotations["team"])
+	assert.Equal(t, map[string]string{"team": "a"}, mic.Spec.PodAnnotations)
+
+	// unrelated changes keep the hash
+	assert.Equal(t, hash, podAnnotations(t)[microservicev1.DependencyHashAnnotation])
+
+	secret.Data["password"] = []byte("rotated")
+	require.NoError(t, r.Client.Update(context.TODO(), secret))
+	rotated := podAnnotations(t)[microservicev1.DependencyHashAnnotation]
+	assert.NotEqual(t, hash, rotated)
+
+	// creating the missing optional ConfigMap changes the hash as well
+	require.NoError(t, r.Client.Create(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"},
+	}))
+	assert.NotEqual(t, rotated, podAnnotations(t)[microservicev1.DependencyHashAnnotation])
+
+	mic.Spec.IgnoreChanges = []microservicev1.DependencyReference{
+		{Kind: microservicev1.DependencyKindSecret, Name: "db"},
+		{Kind: microservicev1.DependencyKindConfigMap, Name: "settings"},
+	}
+	mic.Generation = 2
+	assert.NotContains(t, podAnnotations(t), microservicev1.DependencyHashAnnotation)
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
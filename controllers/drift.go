@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// updateResource brings current in line with desired according to the drift
+// policy of the Microservice. Changes made to current outside of the
+// Microservice spec are recorded in the status; with the report-only policy
+// they are left in place until the spec changes.
+func (r *MicroserviceReconciler) updateResource(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, current, desired resources.Object, reqLogger logr.Logger) error {
+	gvk, err := apiutil.GVKForObject(current, r.Scheme)
+	if err != nil {
+		return errors.Wrap(err, "failed to determine the resource kind")
+	}
+
+	paths, err := r.driftPaths(current, desired)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		clearDrift(status, gvk.Kind, current.GetName())
+		return r.Resources.Update(current, desired, reqLogger)
+	}
+
+	specChanged := status.ObservedGeneration != mic.GetGeneration()
+	revert := specChanged || mic.GetDriftPolicy() == microservicev1.DriftPolicyEnforce
+	r.recordDrift(mic, status, gvk.Kind, current.GetName(), paths, revert)
+
+	if !revert {
+		reqLogger.Info("Drift detected, not reverting because of the report-only drift policy",
+			"name", current.GetName(),
+			"kind", gvk.Kind,
+			"paths", paths,
+		)
+		return nil
+	}
+
+	return r.Resources.Update(current, desired, reqLogger)
+}
+
+// driftPaths returns the paths of the fields of current that were changed
+// since it was last applied and that differ from desired. Fields that desired
+// follows, like the replicas of an autoscaled Deployment, are not drift.
+func (r *MicroserviceReconciler) driftPaths(current, desired resources.Object) ([]string, error) {
+	driftPaths, err := r.Resources.DriftPaths(current)
+	if err != nil || len(driftPaths) == 0 {
+		return nil, err
+	}
+	diff, err := r.Resources.Diff(current, desired)
+	if err != nil || diff == nil {
+		return nil, err
+	}
+
+	diffPaths, err := resources.PatchPaths(diff)
+	if err != nil {
+		return nil, err
+	}
+
+	differs := map[string]bool{}
+	for _, path := range diffPaths {
+		differs[path] = true
+	}
+
+	paths := []string{}
+	for _, path := range driftPaths {
+		if differs[path] {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// recordDrift adds or updates the drift entry of a resource in the status and
+// emits an event when the drift was not reported before.
+func (r *MicroserviceReconciler) recordDrift(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, kind, name string, paths []string, reverted bool) {
+	entry := microservicev1.ResourceDrift{
+		Kind:       kind,
+		Name:       name,
+		Paths:      paths,
+		DetectedAt: metav1.Now(),
+		Reverted:   reverted,
+	}
+
+	i := findDrift(status, kind, name)
+	if i < 0 {
+		status.Drift = append(status.Drift, entry)
+	} else {
+		// drift that is still in place was already reported
+		previous := status.Drift[i]
+		if !previous.Reverted && !reverted && reflect.DeepEqual(previous.Paths, paths) {
+			return
+		}
+		status.Drift[i] = entry
+	}
+
+	action := "reported"
+	if reverted {
+		action = "reverted"
+	}
+	r.Recorder.Eventf(mic, corev1.EventTypeWarning, "DriftDetected", "%s %s was changed outside of the Microservice spec at %s, %s",
+		kind, name, strings.Join(paths, ", "), action)
+}
+
+// clearDrift removes the drift entry of a resource that is no longer changed
+// from the status. Reverted entries are kept as a record of the last drift.
+func clearDrift(status *microservicev1.MicroserviceStatus, kind, name string) {
+	i := findDrift(status, kind, name)
+	if i < 0 || status.Drift[i].Reverted {
+		return
+	}
+
+	status.Drift = append(status.Drift[:i], status.Drift[i+1:]...)
+	if len(status.Drift) == 0 {
+		status.Drift = nil
+	}
+}
+
+// driftKinds are the kinds of the generated resources that drift is recorded
+// for.
+var driftKinds = map[string]func() client.Object{
+	"Deployment":              func() client.Object { return &appsv1.Deployment{} },
+	"StatefulSet":             func() client.Object { return &appsv1.StatefulSet{} },
+	"DaemonSet":               func() client.Object { return &appsv1.DaemonSet{} },
+	"Job":                     func() client.Object { return &batchv1.Job{} },
+	"CronJob":                 func() client.Object { return &batchv1.CronJob{} },
+	"HorizontalPodAutoscaler": func() client.Object { return &autoscalingv2.HorizontalPodAutoscaler{} },
+	"Service":                 func() client.Object { return &corev1.Service{} },
+	"ServiceAccount":          func() client.Object { return &corev1.ServiceAccount{} },
+	"Secret":                  func() client.Object { return &corev1.Secret{} },
+	"ConfigMap":               func() client.Object { return &corev1.ConfigMap{} },
+	"PersistentVolumeClaim":   func() client.Object { return &corev1.PersistentVolumeClaim{} },
+	"Ingress":                 func() client.Object { return &networkingv1.Ingress{} },
+}
+
+// pruneDrift removes the drift entries of resources the Microservice no
+// longer owns, e.g. a retired workload or the Deployment of a finished
+// canary, so that the status only reports the last drift of the resources
+// that are still generated.
+func (r *MicroserviceReconciler) pruneDrift(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) error {
+	var drift []microservicev1.ResourceDrift
+	for _, entry := range status.Drift {
+		newObj, ok := driftKinds[entry.Kind]
+		if !ok {
+			drift = append(drift, entry)
+			continue
+		}
+
+		obj := newObj()
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Name: entry.Name, Namespace: mic.GetNamespace()}, obj)
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to check if %s %s exists", entry.Kind, entry.Name)
+		}
+		if err == nil && metav1.IsControlledBy(obj, mic) {
+			drift = append(drift, entry)
+		}
+	}
+
+	status.Drift = drift
+
+	return nil
+}
+
+func findDrift(status *microservicev1.MicroserviceStatus, kind, name string) int {
+	for i, drift := range status.Drift {
+		if drift.Kind == kind && drift.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ignoreStatusChangesPredicate filters out update events of owned resources
+// that only changed their status or bookkeeping metadata, so the reconciler
+// only runs when something it manages was modified.
+type ignoreStatusChangesPredicate struct {
+	predicate.Funcs
+}
+
+func (ignoreStatusChangesPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return true
+	}
+
+	oldObj, err := managedFields(e.ObjectOld)
+	if err != nil {
+		return true
+	}
+	newObj, err := managedFields(e.ObjectNew)
+	if err != nil {
+		return true
+	}
+
+	return !reflect.DeepEqual(oldObj, newObj)
+}
+
+// workloadStatusChangedPredicate passes update events of owned workloads
+// whose status changed, e.g. their ready replicas or the outcome of a Job, so
+// that the Microservice status follows them once it is stable.
+type workloadStatusChangedPredicate struct {
+	predicate.Funcs
+}
+
+func (workloadStatusChangedPredicate) Update(e event.UpdateEvent) bool {
+	if e.ObjectOld == nil || e.ObjectNew == nil {
+		return true
+	}
+
+	oldContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(e.ObjectOld)
+	if err != nil {
+		return true
+	}
+	newContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(e.ObjectNew)
+	if err != nil {
+		return true
+	}
+
+	return !reflect.DeepEqual(oldContent["status"], newContent["status"])
+}
+
+// managedFields returns the content of obj without its status and the
+// metadata that the API server updates on every write.
+func managedFields(obj client.Object) (map[string]interface{}, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(content, "status")
+	if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "resourceVersion")
+		delete(metadata, "managedFields")
+	}
+
+	return content, nil
+}
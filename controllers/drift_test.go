@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+func TestUpdateResource(t *testing.T) {
+	newMicroservice := func(t *testing.T, policy microservicev1.DriftPolicy) (*MicroserviceReconciler, *microservicev1.Microservice) {
+		replicas := int32(2)
+		r, mic, _ := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+			Image:    "image:latest",
+			Replicas: &replicas,
+		})
+		mic.Annotations = map[string]string{microservicev1.DriftPolicyAnnotation: string(policy)}
+		return r, mic
+	}
+
+	// reconcileDrift creates the Deployment, changes its replicas out of
+	// band and reconciles it again
+	reconcileDrift := func(t *testing.T, r *MicroserviceReconciler, mic *microservicev1.Microservice) *appsv1.Deployment {
+		c := r.Client
+		logger := log.Log
+		key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+		require.NoError(t, r.Resources.Create(mic, microservice.GenerateDeployment(mic), logger))
+
+		current := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.TODO(), key, current))
+		require.NoError(t, r.updateResource(mic, &mic.Status, current, microservice.GenerateDeployment(mic), logger))
+		assert.Empty(t, mic.Status.Drift)
+
+		replicas := int32(5)
+		current.Spec.Replicas = &replicas
+		require.NoError(t, c.Update(context.TODO(), current))
+
+		require.NoError(t, c.Get(context.TODO(), key, current))
+		require.NoError(t, r.updateResource(mic, &mic.Status, current, microservice.GenerateDeployment(mic), logger))
+
+		result := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.TODO(), key, result))
+		return result
+	}
+
+	t.Run("enforce", func(t *testing.T) {
+		r, mic := newMicroservice(t, microservicev1.DriftPolicyEnforce)
+		recorder := r.Recorder.(*record.FakeRecorder)
+		result := reconcileDrift(t, r, mic)
+		assert.Equal(t, int32(2), *result.Spec.Replicas)
+
+		require.Len(t, mic.Status.Drift, 1)
+		assert.Equal(t, "Deployment", mic.Status.Drift[0].Kind)
+		assert.Equal(t, "foo", mic.Status.Drift[0].Name)
+		assert.Equal(t, []string{"spec.replicas"}, mic.Status.Drift[0].Paths)
+		assert.True(t, mic.Status.Drift[0].Reverted)
+		require.Len(t, recorder.Events, 1)
+		assert.Contains(t, <-recorder.Events, "Deployment foo was changed outside of the Microservice spec at spec.replicas, reverted")
+	})
+
+	t.Run("report only", func(t *testing.T) {
+		r, mic := newMicroservice(t, microservicev1.DriftPolicyReportOnly)
+		recorder := r.Recorder.(*record.FakeRecorder)
+		result := reconcileDrift(t, r, mic)
+		assert.Equal(t, int32(5), *result.Spec.Replicas)
+
+		require.Len(t, mic.Status.Drift, 1)
+		assert.False(t, mic.Status.Drift[0].Reverted)
+		require.Len(t, recorder.Events, 1)
+		<-recorder.Events
+
+		// drift that is still in place is reported once
+		require.NoError(t, r.updateResource(mic, &mic.Status, result, microservice.GenerateDeployment(mic), log.Log))
+		assert.Len(t, mic.Status.Drift, 1)
+		assert.Empty(t, recorder.Events)
+	})
+
+	t.Run("report only with spec change", func(t *testing.T) {
+		r, mic := newMicroservice(t, microservicev1.DriftPolicyReportOnly)
+		mic.Generation = 2
+		result := reconcileDrift(t, r, mic)
+		assert.Equal(t, int32(2), *result.Spec.Replicas)
+		require.Len(t, mic.Status.Drift, 1)
+		assert.True(t, mic.Status.Drift[0].Reverted)
+	})
+}
+
+func TestPruneDrift(t *testing.T) {
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{Image: "image:latest"})
+	owned := metav1.ObjectMeta{
+		Name:            "foo",
+		Namespace:       "default",
+		OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(mic, microservicev1.GroupVersion.WithKind("Microservice"))},
+	}
+	require.NoError(t, r.Client.Create(context.TODO(), &appsv1.Deployment{ObjectMeta: owned}))
+	require.NoError(t, r.Client.Create(context.TODO(), &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}))
+
+	status.Drift = []microservicev1.ResourceDrift{
+		{Kind: "Deployment", Name: "foo", Reverted: true},
+		{Kind: "Deployment", Name: "foo-canary", Reverted: true},
+		{Kind: "StatefulSet", Name: "foo"},
+		{Kind: "Service", Name: "foo"},
+	}
+	require.NoError(t, r.pruneDrift(mic, status))
+	assert.Equal(t, []microservicev1.ResourceDrift{{Kind: "Deployment", Name: "foo", Reverted: true}}, status.Drift)
+
+	require.NoError(t, r.Client.DeleteAllOf(context.TODO(), &appsv1.Deployment{}))
+	require.NoError(t, r.pruneDrift(mic, status))
+	assert.Nil(t, status.Drift)
+}
+
+func TestIgnoreStatusChangesPredicate(t *testing.T) {
+	pred := ignoreStatusChangesPredicate{}
+	old := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "foo",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "foo", Image: "image:1"}},
+				},
+			},
+		},
+	}
+
+	statusOnly := old.DeepCopy()
+	statusOnly.ResourceVersion = "2"
+	statusOnly.Status.ReadyReplicas = 1
+	assert.False(t, pred.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: statusOnly}))
+
+	specChange := old.DeepCopy()
+	specChange.ResourceVersion = "2"
+	specChange.Spec.Template.Spec.Containers[0].Image = "image:2"
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: specChange}))
+
+	labelChange := old.DeepCopy()
+	labelChange.Labels = map[string]string{"foo": "bar"}
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: labelChange}))
+
+	assert.True(t, pred.Create(event.CreateEvent{Object: old}))
+	assert.True(t, pred.Delete(event.DeleteEvent{Object: old}))
+}
+
+func TestWorkloadStatusChangedPredicate(t *testing.T) {
+	pred := predicate.Or(ignoreStatusChangesPredicate{}, workloadStatusChangedPredicate{})
+	old := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "foo",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:      2,
+			ReadyReplicas: 2,
+		},
+	}
+
+	// a crash-looping pod of a stable Deployment
+	statusOnly := old.DeepCopy()
+	statusOnly.ResourceVersion = "2"
+	statusOnly.Status.ReadyReplicas = 1
+	statusOnly.Status.UnavailableReplicas = 1
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: statusOnly}))
+
+	specChange := old.DeepCopy()
+	specChange.ResourceVersion = "2"
+	specChange.Spec.Paused = true
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: specChange}))
+
+	unchanged := old.DeepCopy()
+	unchanged.ResourceVersion = "2"
+	unchanged.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kube-controller-manager"}}
+	assert.False(t, pred.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: unchanged}))
+}
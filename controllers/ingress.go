@@ -4,7 +4,7 @@ import (
 	"context"
 	"strings"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
 	"github.com/go-logr/logr"
@@ -15,7 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 )
 
-func (r *MicroserviceReconciler) checkIngress(deployment *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger) error {
+func (r *MicroserviceReconciler) checkIngress(deployment *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
 	ingresses := networking.IngressList{}
 	err := r.Client.List(context.TODO(), &ingresses, &client.ListOptions{
 		Namespace: deployment.GetNamespace(),
@@ -51,7 +51,7 @@ func (r *MicroserviceReconciler) checkIngress(deployment *microservicev1beta1.Mi
 			return err
 		}
 
-		err = r.Resources.Update(current, desired, reqLogger)
+		err = r.updateResource(deployment, status, current, desired, reqLogger)
 		if err != nil {
 			return err
 		}
@@ -60,13 +60,18 @@ func (r *MicroserviceReconciler) checkIngress(deployment *microservicev1beta1.Mi
 	return nil
 }
 
-func (r *MicroserviceReconciler) checkService(deployment *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger) error {
+func (r *MicroserviceReconciler) checkService(deployment *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
 	if len(deployment.Spec.Ingress) < 1 {
 		return r.Resources.DeleteService(types.NamespacedName{Name: deployment.GetName(), Namespace: deployment.GetNamespace()}, reqLogger)
 	}
 
 	desired := microservice.GenerateServiceV1(deployment)
 
+	// the Service follows the active Deployment of a blue/green rollout
+	if status.BlueGreen != nil {
+		desired.Spec.Selector = microservice.ColorLabels(deployment, activeColor(status))
+	}
+
 	err := r.Resources.CreateServiceIfNotExists(deployment, desired, reqLogger)
 	if err != nil {
 		return err
@@ -80,5 +85,5 @@ func (r *MicroserviceReconciler) checkService(deployment *microservicev1beta1.Mi
 
 	resources.CopyServiceEmptyAutoAssignedFields(desired, current)
 
-	return r.Resources.Update(current, desired, reqLogger)
+	return r.updateResource(deployment, status, current, desired, reqLogger)
 }
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// jobSpecHashAnnotation records the hash of the spec a Job was created
+// with, so that changes to it can be told apart from the defaults the API
+// server fills in.
+const jobSpecHashAnnotation = "microservice.example.com/job-spec-hash"
+
+// checkCronJob creates or updates the CronJob of the Microservice.
+func (r *MicroserviceReconciler) checkCronJob(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	desired := microservice.GenerateCronJob(mic)
+	err := r.setDependencyHash(mic, &desired.Spec.JobTemplate.Spec.Template)
+	if err != nil {
+		return err
+	}
+
+	err = r.Resources.CreateCronJobIfNotExists(mic, desired.DeepCopy(), reqLogger)
+	if err != nil {
+		return err
+	}
+
+	current := &batchv1.CronJob{}
+	err = r.getOwned(mic, current, mic.GetName())
+	if err != nil {
+		return err
+	}
+
+	return r.updateResource(mic, status, current, desired, reqLogger)
+}
+
+// checkJob creates the Job of the Microservice. The pod template of a Job
+// cannot be changed, so a Job whose spec changed is replaced, which runs it
+// again.
+func (r *MicroserviceReconciler) checkJob(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	desired, err := r.desiredJob(mic)
+	if err != nil {
+		return err
+	}
+
+	err = r.Resources.CreateJobIfNotExists(mic, desired.DeepCopy(), reqLogger)
+	if err != nil {
+		return err
+	}
+
+	current := &batchv1.Job{}
+	err = r.getOwned(mic, current, mic.GetName())
+	if err != nil {
+		return err
+	}
+
+	if current.Annotations[jobSpecHashAnnotation] == desired.Annotations[jobSpecHashAnnotation] {
+		return r.updateResource(mic, status, current, desired, reqLogger)
+	}
+
+	reqLogger.Info("Replacing job", "name", current.GetName())
+	err = r.Client.Delete(context.TODO(), current, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete the previous job")
+	}
+
+	err = r.Resources.Create(mic, desired, reqLogger)
+	if k8sErrors.IsAlreadyExists(err) {
+		return errors.New("the previous job is still being deleted")
+	} else if err != nil {
+		return errors.Wrap(err, "failed to create the job")
+	}
+
+	return nil
+}
+
+// desiredJob generates the Job of the Microservice and records the hash of
+// its spec.
+func (r *MicroserviceReconciler) desiredJob(mic *microservicev1.Microservice) (*batchv1.Job, error) {
+	desired := microservice.GenerateJob(mic)
+	err := r.setDependencyHash(mic, &desired.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(desired.Spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash the job spec")
+	}
+
+	annotations := make(map[string]string, len(desired.Annotations)+1)
+	for key, value := range desired.Annotations {
+		annotations[key] = value
+	}
+	annotations[jobSpecHashAnnotation] = fmt.Sprintf("%x", sha256.Sum256(data))
+	desired.Annotations = annotations
+
+	return desired, nil
+}
+
+// checkCronJobStatus copies the last runs of the owned CronJob into the
+// Microservice status. A CronJob is stable as soon as it is scheduled, the
+// outcome of its Jobs does not change the state of the Microservice.
+func (r *MicroserviceReconciler) checkCronJobStatus(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	current := &batchv1.CronJob{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()}, current)
+	if err != nil {
+		return err
+	}
+
+	clearReplicaStatus(status)
+	status.Job = &microservicev1.JobStatus{
+		Active:             int32(len(current.Status.Active)),
+		LastScheduleTime:   current.Status.LastScheduleTime,
+		LastSuccessfulTime: current.Status.LastSuccessfulTime,
+	}
+	status.Image = containerImage(current.Spec.JobTemplate.Spec.Template, mic.GetName())
+
+	message := "CronJob is scheduled"
+	if current.Spec.Suspend != nil && *current.Spec.Suspend {
+		message = "CronJob is suspended"
+	}
+	setBatchConditions(mic, status, metav1.ConditionFalse, microservicev1.ReasonRolloutComplete, message)
+
+	status.State = microservicev1.Stable
+
+	return nil
+}
+
+// checkJobStatus copies the progress of the owned Job into the Microservice
+// status. The Microservice is stable once the Job completed and fails with
+// the Job.
+func (r *MicroserviceReconciler) checkJobStatus(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	current := &batchv1.Job{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()}, current)
+	if err != nil {
+		return err
+	}
+
+	clearReplicaStatus(status)
+	status.Replicas = current.Status.Active
+	status.Job = &microservicev1.JobStatus{
+		Active:         current.Status.Active,
+		Succeeded:      current.Status.Succeeded,
+		Failed:         current.Status.Failed,
+		CompletionTime: current.Status.CompletionTime,
+	}
+
+	if failed := jobCondition(current, batchv1.JobFailed); failed != nil {
+		message := fmt.Sprintf("%s: %s", failed.Reason, failed.Message)
+		setBatchConditions(mic, status, metav1.ConditionFalse, microservicev1.ReasonRolloutFailed, message)
+		return errors.Errorf("job failed: %s", message)
+	}
+
+	if jobCondition(current, batchv1.JobComplete) != nil {
+		status.Image = containerImage(current.Spec.Template, mic.GetName())
+		setBatchConditions(mic, status, metav1.ConditionFalse, microservicev1.ReasonRolloutComplete, "Job completed")
+		status.State = microservicev1.Stable
+		return nil
+	}
+
+	message := fmt.Sprintf("%d pods are running", current.Status.Active)
+	setBatchConditions(mic, status, metav1.ConditionTrue, microservicev1.ReasonRolloutInProgress, message)
+	status.State = microservicev1.Reconciling
+	reqLogger.Info("Waiting for job to complete",
+		"active", current.Status.Active,
+		"failed", current.Status.Failed,
+	)
+
+	return nil
+}
+
+// setBatchConditions sets the Progressing and Degraded conditions of a
+// Microservice that runs a Job or CronJob. Its pods serve no traffic, so it
+// has no Available condition.
+func setBatchConditions(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, progressing metav1.ConditionStatus, reason, message string) {
+	meta.RemoveStatusCondition(&status.Conditions, microservicev1.ConditionAvailable)
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               microservicev1.ConditionProgressing,
+		Status:             progressing,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: mic.GetGeneration(),
+	})
+
+	degraded := metav1.Condition{
+		Type:               microservicev1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             microservicev1.ReasonAsExpected,
+		ObservedGeneration: mic.GetGeneration(),
+	}
+	if reason == microservicev1.ReasonRolloutFailed {
+		degraded.Status = metav1.ConditionTrue
+		degraded.Reason = reason
+		degraded.Message = message
+	}
+	meta.SetStatusCondition(&status.Conditions, degraded)
+}
+
+// jobCondition returns the condition of the given type of the Job if it is
+// true.
+func jobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		condition := &job.Status.Conditions[i]
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return condition
+		}
+	}
+
+	return nil
+}
+
+// clearReplicaStatus resets the replica counts of a previous Deployment or
+// StatefulSet, which Jobs do not have.
+func clearReplicaStatus(status *microservicev1.MicroserviceStatus) {
+	status.Replicas = 0
+	status.Selector = ""
+	status.ReadyReplicas = 0
+	status.UpdatedReplicas = 0
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestJob(t *testing.T) {
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image:        "image:v1",
+		WorkloadKind: microservicev1.WorkloadKindJob,
+	})
+
+	reconcile := func(t *testing.T) error {
+		err := r.checkWorkload(mic, status, log.Log)
+		setReconciledCondition(mic, status, workloadConditions[mic.GetWorkloadKind()], err)
+		require.NoError(t, err)
+		err = r.checkWorkloadStatus(mic, status, log.Log)
+		if err != nil {
+			return err
+		}
+		return r.retireWorkloads(mic, status, log.Log)
+	}
+
+	setJobStatus := func(t *testing.T, conditionType batchv1.JobConditionType) {
+		current := &batchv1.Job{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		current.Status = batchv1.JobStatus{
+			Succeeded:  1,
+			Conditions: []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}},
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	}
+
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.Reconciling, status.State)
+
+	job := &batchv1.Job{}
+	require.NoError(t, r.Client.Get(context.TODO(), key, job))
+	assert.True(t, metav1.IsControlledBy(job, mic))
+	assert.Equal(t, corev1.RestartPolicyOnFailure, job.Spec.Template.Spec.RestartPolicy)
+	hash := job.Annotations[jobSpecHashAnnotation]
+	assert.NotEmpty(t, hash)
+
+	setJobStatus(t, batchv1.JobComplete)
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.Stable, status.State)
+	assert.Equal(t, "image:v1", status.Image)
+	assert.Equal(t, int32(1), status.Job.Succeeded)
+	assert.Nil(t, meta.FindStatusCondition(status.Conditions, microservicev1.ConditionAvailable))
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, microservicev1.ConditionProgressing))
+
+	// reconciling the same spec again does not run the Job again
+	require.NoError(t, r.Client.Get(context.TODO(), key, job))
+	resourceVersion := job.ResourceVersion
+	require.NoError(t, reconcile(t))
+	require.NoError(t, r.Client.Get(context.TODO(), key, job))
+	assert.Equal(t, resourceVersion, job.ResourceVersion)
+
+	// a new image replaces the Job, which runs it again
+	mic.Spec.Image = "image:v2"
+	mic.Generation = 2
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.Reconciling, status.State)
+	require.NoError(t, r.Client.Get(context.TODO(), key, job))
+	assert.NotEqual(t, hash, job.Annotations[jobSpecHashAnnotation])
+	assert.Empty(t, job.Status.Conditions)
+	assert.Equal(t, "image:v2", containerImage(job.Spec.Template, "foo"))
+
+	setJobStatus(t, batchv1.JobFailed)
+	err := reconcile(t)
+	assert.EqualError(t, err, "job failed: BackoffLimitExceeded: ")
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionDegraded))
+
+	// switching to a CronJob deletes the Job once the CronJob is scheduled
+	mic.Spec.WorkloadKind = microservicev1.WorkloadKindCronJob
+	mic.Spec.CronJob = &microservicev1.CronJobSpec{Schedule: "@hourly"}
+	mic.Generation = 3
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.Stable, status.State)
+
+	cronJob := &batchv1.CronJob{}
+	require.NoError(t, r.Client.Get(context.TODO(), key, cronJob))
+	assert.Equal(t, "@hourly", cronJob.Spec.Schedule)
+	assert.Equal(t, "image:v2", status.Image)
+	assert.Equal(t, int32(0), status.Job.Active)
+
+	err = r.Client.Get(context.TODO(), key, &batchv1.Job{})
+	assert.True(t, k8sErrors.IsNotFound(err))
+	assert.Nil(t, meta.FindStatusCondition(status.Conditions, microservicev1.ConditionJobReconciled))
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionCronJobReconciled))
+
+	// and back to a Deployment
+	mic.Spec.WorkloadKind = ""
+	mic.Spec.CronJob = nil
+	mic.Generation = 4
+	require.NoError(t, reconcile(t))
+	assert.Nil(t, status.Job)
+	require.NoError(t, r.Client.Get(context.TODO(), key, &appsv1.Deployment{}))
+	require.NoError(t, r.Client.Get(context.TODO(), key, &batchv1.CronJob{}))
+}
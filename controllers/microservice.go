@@ -2,28 +2,185 @@ package controllers
 
 import (
 	"context"
+	"reflect"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
 	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
 
 	appsv1 "k8s.io/api/apps/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 )
 
-func (r *MicroserviceReconciler) checkDeployment(deployment *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger) error {
-	desired := microservice.GenerateDeployment(deployment)
+func (r *MicroserviceReconciler) checkDeployment(deployment *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	// there is no previous image to run until the pre-deploy hook succeeded
+	if preDeployPending(deployment, status) {
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Name: deploymentName(deployment, status), Namespace: deployment.Namespace}, &appsv1.Deployment{})
+		if err != nil && k8sErrors.IsNotFound(err) {
+			reqLogger.Info("Waiting for the pre-deploy hook to complete before creating the deployment")
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// the dependency hash is set from the start, so that the new Deployment
+	// is not rolled out a second time by the update below
+	created := microservice.GenerateColorDeployment(deployment, activeColor(status))
+	err := r.setDependencyHash(deployment, &created.Spec.Template)
+	if err != nil {
+		return err
+	}
+	err = r.Resources.CreateDeploymentIfNotExists(deployment, created, reqLogger)
+	if err != nil {
+		return err
+	}
 
-	err := r.Resources.CreateDeploymentIfNotExists(deployment, desired, reqLogger)
+	current := &appsv1.Deployment{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: created.Name, Namespace: deployment.Namespace}, current)
 	if err != nil {
 		return err
 	}
 
+	desired, err := r.desiredDeployment(deployment, status, current)
+	if err != nil {
+		return err
+	}
+
+	return r.updateResource(deployment, status, current, desired, reqLogger)
+}
+
+// desiredDeployment generates the Deployment of the Microservice, keeping the
+// parts of current that are not owned by the spec.
+func (r *MicroserviceReconciler) desiredDeployment(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, current *appsv1.Deployment) (*appsv1.Deployment, error) {
+	desired := microservice.GenerateColorDeployment(mic, activeColor(status))
+	err := r.setDependencyHash(mic, &desired.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	keepDeploymentSelector(current, desired)
+
+	// the HorizontalPodAutoscaler owns the number of replicas once the
+	// Deployment exists
+	if mic.AutoscalingEnabled() && current.Spec.Replicas != nil {
+		replicas, err := r.autoscaledReplicas(mic, current, *current.Spec.Replicas)
+		if err != nil {
+			return nil, err
+		}
+		desired.Spec.Replicas = replicas
+	}
+
+	// the Deployment keeps running the previous image until the pre-deploy
+	// hook succeeded with the new one
+	if preDeployPending(mic, status) {
+		microservice.SetContainerImage(&desired.Spec.Template, mic.GetName(), containerImage(current.Spec.Template, mic.GetName()))
+	}
+
+	// during a canary rollout the Deployment keeps running the stable image
+	if status.Canary != nil {
+		microservice.SetContainerImage(&desired.Spec.Template, mic.GetName(), status.Canary.StableImage)
+	}
+
+	// during a blue/green rollout the active Deployment keeps running the
+	// active image, the image of the spec is previewed on the idle one
+	if status.BlueGreen != nil {
+		microservice.SetContainerImage(&desired.Spec.Template, mic.GetName(), status.BlueGreen.ActiveImage)
+	}
+
+	// a rolled back Deployment keeps the restored pod template until the
+	// spec changes
+	if rollbackActive(mic, status) {
+		desired.Spec.Template = current.Spec.Template
+	}
+
+	return desired, nil
+}
+
+// keepDeploymentSelector keeps the selector of current on desired. The
+// selector of a Deployment cannot be changed, so a Deployment created before
+// its pods carried the track label keeps selecting them on the labels of the
+// Microservice.
+func keepDeploymentSelector(current, desired *appsv1.Deployment) {
+	if reflect.DeepEqual(current.Spec.Selector, desired.Spec.Selector) {
+		return
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(current.Spec.Selector)
+	if err == nil && !selector.Empty() && selector.Matches(labels.Set(desired.Spec.Template.Labels)) {
+		desired.Spec.Selector = current.Spec.Selector
+	}
+}
+
+// checkDeploymentStatus copies the rollout progress of the owned Deployment
+// into the Microservice status and sets the running state from it. A failed
+// rollout is rolled back if automatic rollbacks are enabled, and returned as
+// an error otherwise.
+func (r *MicroserviceReconciler) checkDeploymentStatus(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
 	current := &appsv1.Deployment{}
-	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current)
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: deploymentName(mic, status), Namespace: mic.GetNamespace()}, current)
+	if err != nil && k8sErrors.IsNotFound(err) && preDeployPending(mic, status) {
+		return waitForPreDeploy(mic, status, reqLogger)
+	} else if err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(current.Spec.Selector)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the deployment selector")
+	}
+
+	status.Replicas = current.Status.Replicas
+	status.Selector = selector.String()
+	status.ReadyReplicas = current.Status.ReadyReplicas
+	status.UpdatedReplicas = current.Status.UpdatedReplicas
+
+	if deploymentRolledOut(current) {
+		status.Image = containerImage(current.Spec.Template, mic.GetName())
+		status.LastGoodRevision = current.Annotations[revisionAnnotation]
+	}
+
+	setDeploymentConditions(mic, status, current)
+
+	failed, message, err := r.rolloutFailed(mic, current)
 	if err != nil {
 		return err
 	}
+	if failed {
+		// a failed rollback is not rolled back again
+		if !mic.Spec.AutoRollback || rollbackActive(mic, status) {
+			return errors.Errorf("deployment rollout failed: %s", message)
+		}
+
+		err = r.rollbackDeployment(mic, status, current, message, reqLogger)
+		if err != nil {
+			return err
+		}
+		setRolledBackCondition(mic, status)
+		status.State = microservicev1.Reconciling
+		return nil
+	}
+
+	if rollbackActive(mic, status) {
+		setRolledBackCondition(mic, status)
+	}
+
+	status.State = deploymentRunningState(current)
+	if status.State != microservicev1.Stable {
+		reqLogger.Info("Waiting for deployment rollout to complete",
+			"updated", current.Status.UpdatedReplicas,
+			"available", current.Status.AvailableReplicas,
+			"desired", deploymentReplicas(current),
+		)
+	}
+
+	if preDeployPending(mic, status) {
+		return waitForPreDeploy(mic, status, reqLogger)
+	}
 
-	return r.Resources.Update(current, desired, reqLogger)
+	return nil
 }
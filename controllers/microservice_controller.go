@@ -18,25 +18,40 @@ package controllers
 
 import (
 	"context"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
 
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// rolloutRequeueDelay is how long to wait before checking the progress of a
+// Deployment rollout again.
+const rolloutRequeueDelay = 10 * time.Second
+
 // MicroserviceReconciler reconciles a Microservice object
 type MicroserviceReconciler struct {
 	client.Client
 	Scheme    *runtime.Scheme
 	Resources *resources.ResourceHelper
+	Recorder  record.EventRecorder
 }
 
 func NewMicroserviceReconciler(mgr ctrl.Manager) *MicroserviceReconciler {
@@ -44,12 +59,23 @@ func NewMicroserviceReconciler(mgr ctrl.Manager) *MicroserviceReconciler {
 		Client:    mgr.GetClient(),
 		Scheme:    mgr.GetScheme(),
 		Resources: resources.NewResourceHelper(mgr.GetClient(), mgr.GetScheme()),
+		Recorder:  mgr.GetEventRecorderFor("microservice-controller"),
 	}
 }
 
-//+kubebuilder:rbac:groups=microservice.microservice.example.com,resources=deployments,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=microservice.microservice.example.com,resources=deployments/status,verbs=get;update;patch
-//+kubebuilder:rbac:groups=microservice.microservice.example.com,resources=deployments/finalizers,verbs=update
+//+kubebuilder:rbac:groups=microservice.microservice.example.com,resources=microservices,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=microservice.microservice.example.com,resources=microservices/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=microservice.microservice.example.com,resources=microservices/finalizers,verbs=update
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=services;serviceaccounts;secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -63,7 +89,7 @@ func NewMicroserviceReconciler(mgr ctrl.Manager) *MicroserviceReconciler {
 func (r *MicroserviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := log.FromContext(ctx)
 
-	deployment := &microservicev1beta1.Microservice{}
+	deployment := &microservicev1.Microservice{}
 	err := r.Client.Get(ctx, req.NamespacedName, deployment)
 	if err != nil && k8sErrors.IsNotFound(err) {
 		// Request object not found, could have been deleted after reconcile
@@ -73,67 +99,164 @@ func (r *MicroserviceReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return reconcile.Result{}, err
 	}
 
+	// Owned objects are garbage collected once the finalizers ran
+	if !deployment.GetDeletionTimestamp().IsZero() {
+		err = r.finalizePersistentVolumeClaims(deployment, reqLogger)
+		return reconcile.Result{}, err
+	}
+
 	// We copy status to not to refetch the resource
 	status := deployment.Status
 
-	if status.State != microservicev1beta1.Reconciling {
+	// Only a spec change moves the Microservice back to reconciling upfront.
+	// Changes of the workload status merely refresh the status, and the
+	// state follows the workloads the checks below updated.
+	specChanged := status.ObservedGeneration != deployment.GetGeneration()
+	if status.State != microservicev1.Reconciling && specChanged {
 		err = r.updateStatusReconciling(deployment, status, reqLogger)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
 	}
 
-	err = r.checkServiceAccount(deployment, status, reqLogger)
+	err = r.checkServiceAccount(deployment, &status, reqLogger)
+	if err == nil {
+		err = r.checkServiceAccountSecret(deployment, &status, reqLogger)
+	}
+	setReconciledCondition(deployment, &status, microservicev1.ConditionServiceAccountReconciled, err)
+	if err != nil {
+		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
+		return reconcile.Result{}, err
+	}
+
+	err = r.checkPersistentVolumeClaims(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, microservicev1.ConditionPersistentVolumeClaimsReconciled, err)
+	if err != nil {
+		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
+		return reconcile.Result{}, err
+	}
+
+	err = r.checkConfigMap(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, microservicev1.ConditionConfigMapReconciled, err)
+	if err != nil {
+		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
+		return reconcile.Result{}, err
+	}
+
+	err = r.checkCanary(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, microservicev1.ConditionCanaryReconciled, err)
+	if err != nil {
+		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
+		return reconcile.Result{}, err
+	}
+
+	err = r.checkBlueGreen(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, microservicev1.ConditionBlueGreenReconciled, err)
 	if err != nil {
 		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
 		return reconcile.Result{}, err
 	}
 
-	err = r.checkServiceAccountSecret(deployment, status, reqLogger)
+	err = r.checkPreDeploy(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, microservicev1.ConditionPreDeployReconciled, err)
 	if err != nil {
 		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
 		return reconcile.Result{}, err
 	}
 
-	err = r.checkDeployment(deployment, status, reqLogger)
+	err = r.checkWorkload(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, workloadConditions[deployment.GetWorkloadKind()], err)
 	if err != nil {
 		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
 		return reconcile.Result{}, err
 	}
 
-	err = r.checkAutoscaling(deployment, status, reqLogger)
+	err = r.checkAutoscaling(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, microservicev1.ConditionAutoscalingReconciled, err)
 	if err != nil {
 		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
 		return reconcile.Result{}, err
 	}
 
-	err = r.checkService(deployment, status, reqLogger)
+	err = r.checkService(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, microservicev1.ConditionServiceReconciled, err)
 	if err != nil {
 		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
 		return reconcile.Result{}, err
 	}
 
-	err = r.checkIngress(deployment, status, reqLogger)
+	err = r.checkIngress(deployment, &status, reqLogger)
+	setReconciledCondition(deployment, &status, microservicev1.ConditionIngressReconciled, err)
 	if err != nil {
 		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
 		return reconcile.Result{}, err
 	}
 
-	status.State = microservicev1beta1.Stable
+	err = r.checkWorkloadStatus(deployment, &status, reqLogger)
+	if err == nil {
+		err = r.retireWorkloads(deployment, &status, reqLogger)
+	}
+	if err == nil {
+		err = r.pruneDrift(deployment, &status)
+	}
+	if err != nil {
+		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
+		return reconcile.Result{}, err
+	}
+
+	status.Error = ""
+	status.ObservedGeneration = deployment.GetGeneration()
 	err = r.updateStatus(deployment, status, reqLogger)
 	if err != nil {
 		r.updateStatusReconcilingAndLogError(deployment, status, reqLogger, err)
 		return reconcile.Result{}, err
 	}
 
+	// Poll until the rollout has completed and canary steps and blue/green
+	// delays have elapsed, which do not change the workload status.
+	if status.State != microservicev1.Stable || canaryInProgress(&status) || previewRunning(&status) {
+		return ctrl.Result{RequeueAfter: rolloutRequeueDelay}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MicroserviceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	pred := predicate.GenerationChangedPredicate{}
+	// annotations select the drift policy and the scheduled autoscaler
+	// override, so they have to trigger a reconcile as well
+	pred := predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})
+	ownedPred := ignoreStatusChangesPredicate{}
+	// the status of the workload is copied into the Microservice status,
+	// which has to follow it after the rollout as well
+	workloadPred := predicate.Or(ownedPred, workloadStatusChangedPredicate{})
+
+	err := indexDependencies(context.Background(), mgr.GetFieldIndexer())
+	if err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&microservicev1beta1.Microservice{}).
-		WithEventFilter(pred).
+		For(&microservicev1.Microservice{}, builder.WithPredicates(pred)).
+		Owns(&corev1.ServiceAccount{}, builder.WithPredicates(ownedPred)).
+		Owns(&corev1.Secret{}, builder.WithPredicates(ownedPred)).
+		Owns(&appsv1.Deployment{}, builder.WithPredicates(workloadPred)).
+		Owns(&appsv1.StatefulSet{}, builder.WithPredicates(workloadPred)).
+		Owns(&appsv1.DaemonSet{}, builder.WithPredicates(workloadPred)).
+		Owns(&batchv1.Job{}, builder.WithPredicates(workloadPred)).
+		Owns(&batchv1.CronJob{}, builder.WithPredicates(workloadPred)).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}, builder.WithPredicates(ownedPred)).
+		Owns(&corev1.Service{}, builder.WithPredicates(ownedPred)).
+		Owns(&networkingv1.Ingress{}, builder.WithPredicates(ownedPred)).
+		Owns(&corev1.PersistentVolumeClaim{}, builder.WithPredicates(ownedPred)).
+		Owns(&corev1.ConfigMap{}, builder.WithPredicates(ownedPred)).
+		// referenced Secrets and ConfigMaps are not owned, but changing them
+		// rolls out new pods
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.dependentMicroservices(microservicev1.DependencyKindSecret)),
+			builder.WithPredicates(ownedPred)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.dependentMicroservices(microservicev1.DependencyKindConfigMap)),
+			builder.WithPredicates(ownedPred)).
 		Complete(r)
 }
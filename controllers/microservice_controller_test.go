@@ -39,6 +39,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -48,7 +49,8 @@ import (
 
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
 	//+kubebuilder:scaffold:imports
 )
@@ -73,7 +75,7 @@ func setupSuite(tb testing.TB) func(tb testing.TB) {
 	cfg, err = testEnv.Start()
 	assert.NoError(tb, err)
 
-	err = microservicev1beta1.AddToScheme(scheme.Scheme)
+	err = microservicev1.AddToScheme(scheme.Scheme)
 	assert.NoError(tb, err)
 
 	//+kubebuilder:scaffold:scheme
@@ -88,7 +90,7 @@ func setupSuite(tb testing.TB) func(tb testing.TB) {
 }
 
 func prepareSchema(t *testing.T, scheme *runtime.Scheme) *runtime.Scheme {
-	err := microservicev1beta1.AddToScheme(scheme)
+	err := microservicev1.AddToScheme(scheme)
 	assert.NoError(t, err)
 
 	return scheme
@@ -100,6 +102,7 @@ func setupTestDeps(t *testing.T) (logr.Logger, *MicroserviceReconciler) {
 		Client:    k8sClient,
 		Scheme:    s,
 		Resources: resources.NewResourceHelper(k8sClient, s),
+		Recorder:  record.NewFakeRecorder(100),
 	}
 
 	logger := log.FromContext(context.TODO())
@@ -118,7 +121,7 @@ func TestAllChecks(t *testing.T) {
 	msName := "foo"
 	msNamespace := "default"
 
-	ms := &microservicev1beta1.Microservice{
+	ms := &microservicev1.Microservice{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      msName,
 			Namespace: msNamespace,
@@ -126,11 +129,11 @@ func TestAllChecks(t *testing.T) {
 		},
 	}
 
-	currentStatus := microservicev1beta1.MicroserviceStatus{}
+	currentStatus := microservicev1.MicroserviceStatus{}
 
 	t.Run("service", func(t *testing.T) {
 		// ---
-		err := r.checkService(ms, currentStatus, logger)
+		err := r.checkService(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current := &corev1.Service{}
@@ -139,15 +142,15 @@ func TestAllChecks(t *testing.T) {
 		assert.True(t, k8sErrors.IsNotFound(err))
 
 		// ---
-		ms.Spec = microservicev1beta1.MicroserviceSpec{
-			Ingress: []microservicev1beta1.Ingress{
+		ms.Spec = microservicev1.MicroserviceSpec{
+			Ingress: []microservicev1.Ingress{
 				{
 					ContainerPort: 8080,
 					Name:          "test-1",
 				},
 			},
 		}
-		err = r.checkService(ms, currentStatus, logger)
+		err = r.checkService(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &corev1.Service{}
@@ -159,15 +162,15 @@ func TestAllChecks(t *testing.T) {
 		assert.Equal(t, "test-1", current.Spec.Ports[0].Name)
 
 		// ---
-		ms.Spec = microservicev1beta1.MicroserviceSpec{
-			Ingress: []microservicev1beta1.Ingress{
+		ms.Spec = microservicev1.MicroserviceSpec{
+			Ingress: []microservicev1.Ingress{
 				{
 					ContainerPort: 8090,
 					Name:          "test-2",
 				},
 			},
 		}
-		err = r.checkService(ms, currentStatus, logger)
+		err = r.checkService(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &corev1.Service{}
@@ -179,8 +182,8 @@ func TestAllChecks(t *testing.T) {
 		assert.Equal(t, "test-2", current.Spec.Ports[0].Name)
 
 		// ---
-		ms.Spec.Ingress = []microservicev1beta1.Ingress{}
-		err = r.checkService(ms, currentStatus, logger)
+		ms.Spec.Ingress = []microservicev1.Ingress{}
+		err = r.checkService(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &corev1.Service{}
@@ -191,7 +194,7 @@ func TestAllChecks(t *testing.T) {
 
 	t.Run("ingress", func(t *testing.T) {
 		// ---
-		err := r.checkIngress(ms, currentStatus, logger)
+		err := r.checkIngress(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current := &networking.Ingress{}
@@ -200,14 +203,14 @@ func TestAllChecks(t *testing.T) {
 		assert.True(t, k8sErrors.IsNotFound(err))
 
 		// ---
-		ms.Spec.Ingress = []microservicev1beta1.Ingress{
+		ms.Spec.Ingress = []microservicev1.Ingress{
 			{
 				ContainerPort: 8090,
 				Name:          "test-2",
 				Hosts:         []string{"example.com"},
 			},
 		}
-		err = r.checkIngress(ms, currentStatus, logger)
+		err = r.checkIngress(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &networking.Ingress{}
@@ -219,13 +222,13 @@ func TestAllChecks(t *testing.T) {
 		pathType := networking.PathTypeImplementationSpecific
 		ms.Spec.IngressEnabled = true
 
-		ms.Spec.Ingress = []microservicev1beta1.Ingress{
+		ms.Spec.Ingress = []microservicev1.Ingress{
 			{
 				ContainerPort: 8090,
 				Name:          "test-2",
 			},
 		}
-		err = r.checkIngress(ms, currentStatus, logger)
+		err = r.checkIngress(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &networking.Ingress{}
@@ -253,14 +256,14 @@ func TestAllChecks(t *testing.T) {
 			},
 		}, current.Spec.Rules)
 
-		ms.Spec.Ingress = []microservicev1beta1.Ingress{
+		ms.Spec.Ingress = []microservicev1.Ingress{
 			{
 				ContainerPort: 8090,
 				Name:          "test-2",
 				Hosts:         []string{"example.com", "example2.com"},
 			},
 		}
-		err = r.checkIngress(ms, currentStatus, logger)
+		err = r.checkIngress(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &networking.Ingress{}
@@ -309,7 +312,7 @@ func TestAllChecks(t *testing.T) {
 			},
 		}, current.Spec.Rules)
 
-		ms.Spec.Ingress = []microservicev1beta1.Ingress{
+		ms.Spec.Ingress = []microservicev1.Ingress{
 			{
 				ContainerPort: 8090,
 				Name:          "test-2",
@@ -320,7 +323,7 @@ func TestAllChecks(t *testing.T) {
 				},
 			},
 		}
-		err = r.checkIngress(ms, currentStatus, logger)
+		err = r.checkIngress(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &networking.Ingress{}
@@ -365,7 +368,7 @@ func TestAllChecks(t *testing.T) {
 		// ---
 		ms.Spec.IngressEnabled = false
 
-		err = r.checkIngress(ms, currentStatus, logger)
+		err = r.checkIngress(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &networking.Ingress{}
@@ -375,7 +378,7 @@ func TestAllChecks(t *testing.T) {
 
 		// ---
 		ms.Spec.IngressEnabled = true
-		ms.Spec.Ingress = []microservicev1beta1.Ingress{
+		ms.Spec.Ingress = []microservicev1.Ingress{
 			{
 				ContainerPort: 8090,
 				Name:          "test-2",
@@ -386,7 +389,7 @@ func TestAllChecks(t *testing.T) {
 				},
 			},
 		}
-		err = r.checkIngress(ms, currentStatus, logger)
+		err = r.checkIngress(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &networking.Ingress{}
@@ -428,8 +431,8 @@ func TestAllChecks(t *testing.T) {
 			},
 		}, current.Spec.Rules)
 
-		ms.Spec.Ingress = []microservicev1beta1.Ingress{}
-		err = r.checkIngress(ms, currentStatus, logger)
+		ms.Spec.Ingress = []microservicev1.Ingress{}
+		err = r.checkIngress(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current = &networking.Ingress{}
@@ -461,17 +464,17 @@ func TestAllChecks(t *testing.T) {
 			"test": "env",
 		}
 
-		ingress := []microservicev1beta1.Ingress{
+		ingress := []microservicev1.Ingress{
 			{
 				ContainerPort: 8090,
 				Name:          "test-2",
 			},
 		}
 
-		ms.Spec = microservicev1beta1.MicroserviceSpec{
+		ms.Spec = microservicev1.MicroserviceSpec{
 			Image:          image,
 			Labels:         labels,
-			Replicas:       replicas,
+			Replicas:       &replicas,
 			Env:            env,
 			NodeSelector:   nodeSelector,
 			Tolerations:    tolerations,
@@ -479,7 +482,7 @@ func TestAllChecks(t *testing.T) {
 			Ingress:        ingress,
 		}
 
-		err := r.checkDeployment(ms, currentStatus, logger)
+		err := r.checkDeployment(ms, &currentStatus, logger)
 		assert.NoError(t, err)
 
 		current := &appsv1.Deployment{}
@@ -509,12 +512,12 @@ func TestAllChecks(t *testing.T) {
 		}, current.Spec.Template.Spec.Containers)
 
 		assert.Equal(t, labels, current.Labels)
-		assert.Equal(t, labels, current.Spec.Template.ObjectMeta.Labels)
+		assert.Equal(t, microservice.PodLabels(ms), current.Spec.Template.ObjectMeta.Labels)
 		assert.Equal(t, podAnnotations, current.Spec.Template.ObjectMeta.Annotations)
 		assert.Equal(t, nodeSelector, current.Spec.Template.Spec.NodeSelector)
 		assert.Equal(t, tolerations, current.Spec.Template.Spec.Tolerations)
 		assert.Equal(t, &metav1.LabelSelector{
-			MatchLabels: labels,
+			MatchLabels: microservice.PodLabels(ms),
 		}, current.Spec.Selector)
 		assert.Equal(t, &replicas, current.Spec.Replicas)
 	})
@@ -550,16 +553,16 @@ func TestMicroserviceController(t *testing.T) {
 		"test": "env",
 	}
 
-	ms := &microservicev1beta1.Microservice{
+	ms := &microservicev1.Microservice{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      msName,
 			Namespace: msNamespace,
 			UID:       types.UID("test"),
 		},
-		Spec: microservicev1beta1.MicroserviceSpec{
+		Spec: microservicev1.MicroserviceSpec{
 			Image:          image,
 			Labels:         labels,
-			Replicas:       replicas,
+			Replicas:       &replicas,
 			Env:            env,
 			NodeSelector:   nodeSelector,
 			Tolerations:    tolerations,
@@ -574,7 +577,7 @@ func TestMicroserviceController(t *testing.T) {
 				MinReplicas: &replicas,
 				MaxReplicas: replicas,
 			},
-			Ingress: []microservicev1beta1.Ingress{
+			Ingress: []microservicev1.Ingress{
 				{
 					Hosts:         []string{"example.com"},
 					Name:          "test-1",
@@ -624,16 +627,16 @@ func TestMicroserviceController(t *testing.T) {
 	err = r.Client.Delete(context.TODO(), ms)
 	assert.NoError(t, err)
 
-	ms = &microservicev1beta1.Microservice{
+	ms = &microservicev1.Microservice{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      msName,
 			Namespace: msNamespace,
 			UID:       types.UID("test"),
 		},
-		Spec: microservicev1beta1.MicroserviceSpec{
+		Spec: microservicev1.MicroserviceSpec{
 			Image:                         image,
 			Labels:                        labels,
-			Replicas:                      replicas,
+			Replicas:                      &replicas,
 			DisableServiceAccountCreation: true,
 		},
 	}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// newFakeMicroserviceReconciler returns a reconciler backed by a fake client
+// holding objs, for tests that do not need a full API server.
+func newFakeMicroserviceReconciler(t *testing.T, objs ...client.Object) *MicroserviceReconciler {
+	s := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(s))
+	require.NoError(t, microservicev1.AddToScheme(s))
+
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+
+	return &MicroserviceReconciler{
+		Client:    c,
+		Scheme:    s,
+		Resources: resources.NewResourceHelper(c, s),
+		Recorder:  record.NewFakeRecorder(10),
+	}
+}
+
+// newFakeMicroservice returns a reconciler holding objs and a defaulted
+// Microservice with the given spec whose generation was observed, along with
+// a copy of its status for the checks to update.
+func newFakeMicroservice(t *testing.T, spec microservicev1.MicroserviceSpec, objs ...client.Object) (*MicroserviceReconciler, *microservicev1.Microservice, *microservicev1.MicroserviceStatus) {
+	mic := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "foo",
+			Namespace:  "default",
+			UID:        "uid",
+			Generation: 1,
+		},
+		Spec: spec,
+		Status: microservicev1.MicroserviceStatus{
+			ObservedGeneration: 1,
+		},
+	}
+	mic.SetDefaults()
+
+	r := newFakeMicroserviceReconciler(t, append([]client.Object{mic}, objs...)...)
+	return r, mic, mic.Status.DeepCopy()
+}
+
+func TestCheckDeploymentScale(t *testing.T) {
+	replicas := int32(3)
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image:    "image:latest",
+		Replicas: &replicas,
+	})
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	require.NoError(t, r.checkDeployment(mic, status, log.Log))
+
+	current := &appsv1.Deployment{}
+	require.NoError(t, r.Client.Get(context.TODO(), key, current))
+	assert.Equal(t, int32(3), *current.Spec.Replicas)
+
+	// scaling to zero through the scale subresource
+	zero := int32(0)
+	mic.Spec.Replicas = &zero
+	mic.Generation = 2
+	require.NoError(t, r.checkDeployment(mic, status, log.Log))
+
+	require.NoError(t, r.Client.Get(context.TODO(), key, current))
+	assert.Equal(t, int32(0), *current.Spec.Replicas)
+
+	current.Status.Replicas = 0
+	require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	require.NoError(t, r.checkDeploymentStatus(mic, status, log.Log))
+	assert.Equal(t, int32(0), status.Replicas)
+	assert.Equal(t, "app.kubernetes.io/name=foo,microservice.example.com/track=stable", status.Selector)
+}
+
+func TestCheckDeploymentAutoscaled(t *testing.T) {
+	replicas := int32(1)
+	minReplicas := int32(2)
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image:    "image:latest",
+		Replicas: &replicas,
+		Autoscaling: &autoscalingv2.HorizontalPodAutoscalerSpec{
+			MinReplicas: &minReplicas,
+			MaxReplicas: 6,
+		},
+	})
+	c := r.Client
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	scaleTo := func(replicas int32) int32 {
+		current := &appsv1.Deployment{}
+		require.NoError(t, c.Get(context.TODO(), key, current))
+		current.Spec.Replicas = &replicas
+		require.NoError(t, c.Update(context.TODO(), current))
+
+		require.NoError(t, r.checkDeployment(mic, status, log.Log))
+
+		require.NoError(t, c.Get(context.TODO(), key, current))
+		return *current.Spec.Replicas
+	}
+
+	require.NoError(t, r.checkDeployment(mic, status, log.Log))
+	current := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.TODO(), key, current))
+	assert.Equal(t, minReplicas, *current.Spec.Replicas)
+
+	// scaling by the autoscaler is not drift
+	assert.Equal(t, int32(5), scaleTo(5))
+	assert.Empty(t, status.Drift)
+
+	// but scaling beyond its bounds is
+	assert.Equal(t, int32(6), scaleTo(9))
+	require.Len(t, status.Drift, 1)
+	assert.Equal(t, []string{"spec.replicas"}, status.Drift[0].Paths)
+}
+
+func TestReconcileWorkloadStatusChange(t *testing.T) {
+	replicas := int32(2)
+	r, _, _ := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image:    "image:latest",
+		Replicas: &replicas,
+	})
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+	req := ctrl.Request{NamespacedName: key}
+
+	// rollOut sets the status of the Deployment to the given available
+	// replicas out of two and reconciles the Microservice
+	rollOut := func(available int32) *microservicev1.Microservice {
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		current.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: current.Generation,
+			Replicas:           2,
+			UpdatedReplicas:    2,
+			ReadyReplicas:      available,
+			AvailableReplicas:  available,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+			},
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+
+		_, err := r.Reconcile(context.TODO(), req)
+		require.NoError(t, err)
+
+		mic := &microservicev1.Microservice{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, mic))
+		return mic
+	}
+
+	_, err := r.Reconcile(context.TODO(), req)
+	require.NoError(t, err)
+	mic := rollOut(2)
+	assert.Equal(t, microservicev1.Stable, mic.Status.State)
+
+	// a pod of the stable Deployment becoming unready only refreshes the
+	// status, without passing through reconciling
+	resourceVersion, err := strconv.Atoi(mic.ResourceVersion)
+	require.NoError(t, err)
+	mic = rollOut(1)
+	assert.Equal(t, microservicev1.Ready, mic.Status.State)
+	assert.Equal(t, int32(1), mic.Status.ReadyReplicas)
+	assert.Equal(t, strconv.Itoa(resourceVersion+1), mic.ResourceVersion)
+
+	// and nothing is written when it did not change
+	mic = rollOut(1)
+	assert.Equal(t, strconv.Itoa(resourceVersion+1), mic.ResourceVersion)
+}
+
+func TestCheckDeploymentSelector(t *testing.T) {
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{Image: "image:latest"})
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	// a Deployment created before its pods carried the track label
+	legacy := microservice.GenerateDeployment(mic)
+	legacy.Spec.Selector = &metav1.LabelSelector{MatchLabels: mic.Spec.Labels}
+	legacy.Spec.Template.Labels = mic.Spec.Labels
+	require.NoError(t, r.Resources.Create(mic, legacy, log.Log))
+
+	require.NoError(t, r.checkDeployment(mic, status, log.Log))
+
+	current := &appsv1.Deployment{}
+	require.NoError(t, r.Client.Get(context.TODO(), key, current))
+	assert.Equal(t, mic.Spec.Labels, current.Spec.Selector.MatchLabels)
+	assert.Equal(t, microservicev1.TrackStable, current.Spec.Template.Labels[microservicev1.TrackLabel])
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// checkPreDeploy runs the pre-deploy hook of the Microservice with a new
+// image and records its progress in the status. Deleting the Job of a failed
+// hook runs it again.
+func (r *MicroserviceReconciler) checkPreDeploy(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	name := microservice.PreDeployJobName(mic)
+	if mic.Spec.PreDeploy == nil {
+		status.PreDeploy = nil
+		return r.deleteOwned(mic, &batchv1.Job{}, name, reqLogger)
+	}
+
+	// the Job of the last hook is kept for its logs
+	if !preDeployPending(mic, status) {
+		return nil
+	}
+
+	current := &batchv1.Job{}
+	err := r.getOwned(mic, current, name)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		return r.runPreDeploy(mic, status, reqLogger)
+	} else if err != nil {
+		return err
+	}
+
+	if containerImage(current.Spec.Template, mic.GetName()) == mic.Spec.Image {
+		setPreDeployStatus(mic, status, current)
+		return nil
+	}
+
+	// a running hook of a previous image is not interrupted
+	if current.Status.Active > 0 {
+		reqLogger.Info("Waiting for the pre-deploy hook of the previous image to finish", "name", name)
+		return nil
+	}
+
+	// the pod template of a Job cannot be changed
+	reqLogger.Info("Replacing pre-deploy job", "name", name)
+	err = r.Client.Delete(context.TODO(), current, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete the previous pre-deploy job")
+	}
+
+	return r.runPreDeploy(mic, status, reqLogger)
+}
+
+// runPreDeploy creates the Job of the pre-deploy hook with the image of the
+// Microservice.
+func (r *MicroserviceReconciler) runPreDeploy(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	desired := microservice.GeneratePreDeployJob(mic)
+
+	reqLogger.Info("Running pre-deploy hook", "name", desired.Name, "image", mic.Spec.Image)
+	err := r.Resources.Create(mic, desired, reqLogger)
+	if k8sErrors.IsAlreadyExists(err) {
+		return errors.New("the previous pre-deploy job is still being deleted")
+	} else if err != nil {
+		return errors.Wrap(err, "failed to create the pre-deploy job")
+	}
+
+	status.PreDeploy = &microservicev1.PreDeployStatus{
+		Image:   mic.Spec.Image,
+		JobName: desired.Name,
+		Phase:   microservicev1.PreDeployRunning,
+		Logs:    microservice.PreDeployLogs(mic),
+	}
+
+	return nil
+}
+
+// setPreDeployStatus records the progress of the Job of the pre-deploy hook
+// in the status.
+func setPreDeployStatus(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, job *batchv1.Job) {
+	preDeploy := &microservicev1.PreDeployStatus{
+		Image:          mic.Spec.Image,
+		JobName:        job.Name,
+		Phase:          microservicev1.PreDeployRunning,
+		Logs:           microservice.PreDeployLogs(mic),
+		StartTime:      job.Status.StartTime,
+		CompletionTime: job.Status.CompletionTime,
+	}
+
+	if failed := jobCondition(job, batchv1.JobFailed); failed != nil {
+		preDeploy.Phase = microservicev1.PreDeployFailed
+		preDeploy.Message = fmt.Sprintf("%s: %s", failed.Reason, failed.Message)
+	} else if jobCondition(job, batchv1.JobComplete) != nil {
+		preDeploy.Phase = microservicev1.PreDeploySucceeded
+	}
+
+	status.PreDeploy = preDeploy
+}
+
+// preDeployPending returns true while the image of the Microservice differs
+// from the one the Deployment runs and the pre-deploy hook has not succeeded
+// with it yet. The Deployment keeps running the previous image until then.
+func preDeployPending(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) bool {
+	if mic.Spec.PreDeploy == nil || mic.Spec.Image == status.Image {
+		return false
+	}
+
+	return status.PreDeploy == nil ||
+		status.PreDeploy.Image != mic.Spec.Image ||
+		status.PreDeploy.Phase != microservicev1.PreDeploySucceeded
+}
+
+// waitForPreDeploy keeps the Microservice reconciling while its pre-deploy
+// hook runs, and fails it with the hook.
+func waitForPreDeploy(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	if status.PreDeploy != nil && status.PreDeploy.Image == mic.Spec.Image && status.PreDeploy.Phase == microservicev1.PreDeployFailed {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               microservicev1.ConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			Reason:             microservicev1.ReasonPreDeployFailed,
+			Message:            status.PreDeploy.Message,
+			ObservedGeneration: mic.GetGeneration(),
+		})
+		return errors.Errorf("pre-deploy hook failed: %s, see %s", status.PreDeploy.Message, status.PreDeploy.Logs)
+	}
+
+	status.State = microservicev1.Reconciling
+	reqLogger.Info("Waiting for the pre-deploy hook to complete", "image", mic.Spec.Image)
+
+	return nil
+}
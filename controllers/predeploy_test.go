@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestPreDeploy(t *testing.T) {
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+	jobKey := types.NamespacedName{Name: "foo-predeploy", Namespace: "default"}
+
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image: "image:v1",
+		Env:   map[string]string{"DATABASE_URL": "postgres://db"},
+		PreDeploy: &microservicev1.PreDeployHook{
+			Command: []string{"migrate", "up"},
+		},
+	})
+
+	// reconcile runs the pre-deploy and workload checks in the order of
+	// Reconcile
+	reconcile := func(t *testing.T) error {
+		require.NoError(t, r.checkPreDeploy(mic, status, log.Log))
+		require.NoError(t, r.checkWorkload(mic, status, log.Log))
+		return r.checkWorkloadStatus(mic, status, log.Log)
+	}
+
+	finishJob := func(t *testing.T, conditionType batchv1.JobConditionType) {
+		job := &batchv1.Job{}
+		require.NoError(t, r.Client.Get(context.TODO(), jobKey, job))
+		job.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), job))
+	}
+
+	rollOut := func(t *testing.T) {
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		current.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: current.Generation,
+			Replicas:           *current.Spec.Replicas,
+			UpdatedReplicas:    *current.Spec.Replicas,
+			AvailableReplicas:  *current.Spec.Replicas,
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	}
+
+	deployedImage := func(t *testing.T) string {
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		return containerImage(current.Spec.Template, "foo")
+	}
+
+	// the Deployment is only created once the hook succeeded
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.Reconciling, status.State)
+	err := r.Client.Get(context.TODO(), key, &appsv1.Deployment{})
+	assert.True(t, k8sErrors.IsNotFound(err))
+
+	job := &batchv1.Job{}
+	require.NoError(t, r.Client.Get(context.TODO(), jobKey, job))
+	assert.True(t, metav1.IsControlledBy(job, mic))
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "image:v1", container.Image)
+	assert.Equal(t, []string{"migrate", "up"}, container.Command)
+	assert.Equal(t, []corev1.EnvVar{{Name: "DATABASE_URL", Value: "postgres://db"}}, container.Env)
+	assert.Equal(t, "foo", job.Spec.Template.Spec.ServiceAccountName)
+	assert.Equal(t, microservicev1.PreDeployRunning, status.PreDeploy.Phase)
+	assert.Equal(t, "kubectl logs --namespace default job/foo-predeploy", status.PreDeploy.Logs)
+
+	finishJob(t, batchv1.JobComplete)
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.PreDeploySucceeded, status.PreDeploy.Phase)
+	assert.Equal(t, "image:v1", deployedImage(t))
+	rollOut(t)
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.Stable, status.State)
+	assert.Equal(t, "image:v1", status.Image)
+
+	// a new image runs the hook again, while the previous image keeps serving
+	mic.Spec.Image = "image:v2"
+	mic.Generation = 2
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.Reconciling, status.State)
+	assert.Equal(t, "image:v1", deployedImage(t))
+	require.NoError(t, r.Client.Get(context.TODO(), jobKey, job))
+	assert.Equal(t, "image:v2", containerImage(job.Spec.Template, "foo"))
+	assert.Equal(t, "image:v2", status.PreDeploy.Image)
+
+	// a failed hook leaves the previous image serving
+	finishJob(t, batchv1.JobFailed)
+	err = reconcile(t)
+	assert.EqualError(t, err, "pre-deploy hook failed: BackoffLimitExceeded: , see kubectl logs --namespace default job/foo-predeploy")
+	assert.Equal(t, microservicev1.PreDeployFailed, status.PreDeploy.Phase)
+	assert.Equal(t, "image:v1", deployedImage(t))
+	assert.Equal(t, "image:v1", status.Image)
+	degraded := meta.FindStatusCondition(status.Conditions, microservicev1.ConditionDegraded)
+	require.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionTrue, degraded.Status)
+	assert.Equal(t, microservicev1.ReasonPreDeployFailed, degraded.Reason)
+
+	// deleting the Job of the failed hook runs it again
+	require.NoError(t, r.Client.Delete(context.TODO(), job))
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, microservicev1.PreDeployRunning, status.PreDeploy.Phase)
+
+	finishJob(t, batchv1.JobComplete)
+	require.NoError(t, reconcile(t))
+	assert.Equal(t, "image:v2", deployedImage(t))
+
+	// removing the hook deletes its Job
+	mic.Spec.PreDeploy = nil
+	require.NoError(t, reconcile(t))
+	assert.Nil(t, status.PreDeploy)
+	err = r.Client.Get(context.TODO(), jobKey, &batchv1.Job{})
+	assert.True(t, k8sErrors.IsNotFound(err))
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// persistentVolumeClaimFinalizer keeps a Microservice with
+// PersistentVolumeClaims until their retention policy was applied, so that
+// retained claims are not garbage collected together with it.
+const persistentVolumeClaimFinalizer = "microservice.example.com/persistent-volume-claims"
+
+// checkPersistentVolumeClaims creates and updates the PersistentVolumeClaims
+// of the Microservice and applies the retention policy to owned claims that
+// were removed from the spec.
+func (r *MicroserviceReconciler) checkPersistentVolumeClaims(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	if len(mic.Spec.PersistentVolumeClaims) > 0 {
+		err := r.setFinalizer(mic, persistentVolumeClaimFinalizer, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	desiredNames := map[string]bool{}
+	for _, desired := range microservice.GeneratePersistentVolumeClaims(mic) {
+		desiredNames[desired.Name] = true
+
+		err := r.Resources.CreatePvcIfNotExists(mic, desired, reqLogger)
+		if err != nil {
+			return err
+		}
+		current := &corev1.PersistentVolumeClaim{}
+		err = r.getOwned(mic, current, desired.Name)
+		if err != nil {
+			return err
+		}
+		keepPersistentVolumeClaimSpec(current, desired)
+		err = r.updateResource(mic, status, current, desired, reqLogger)
+		if err != nil {
+			return err
+		}
+	}
+
+	owned, err := r.ownedPersistentVolumeClaims(mic)
+	if err != nil {
+		return err
+	}
+	for i := range owned {
+		if desiredNames[owned[i].Name] {
+			continue
+		}
+		err = r.retirePersistentVolumeClaim(mic, &owned[i], reqLogger)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(mic.Spec.PersistentVolumeClaims) == 0 {
+		return r.setFinalizer(mic, persistentVolumeClaimFinalizer, false)
+	}
+
+	return nil
+}
+
+// finalizePersistentVolumeClaims applies the retention policy to the
+// PersistentVolumeClaims of a Microservice that is being deleted and then
+// lets the deletion proceed.
+func (r *MicroserviceReconciler) finalizePersistentVolumeClaims(mic *microservicev1.Microservice, reqLogger logr.Logger) error {
+	if !controllerutil.ContainsFinalizer(mic, persistentVolumeClaimFinalizer) {
+		return nil
+	}
+
+	owned, err := r.ownedPersistentVolumeClaims(mic)
+	if err != nil {
+		return err
+	}
+	for i := range owned {
+		err = r.retirePersistentVolumeClaim(mic, &owned[i], reqLogger)
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.setFinalizer(mic, persistentVolumeClaimFinalizer, false)
+}
+
+// keepPersistentVolumeClaimSpec keeps the spec of current on desired, apart
+// from a larger storage request. Once a claim is bound, Kubernetes only
+// allows to increase its size.
+func keepPersistentVolumeClaimSpec(current, desired *corev1.PersistentVolumeClaim) {
+	size := desired.Spec.Resources.Requests[corev1.ResourceStorage]
+	if currentSize, ok := current.Spec.Resources.Requests[corev1.ResourceStorage]; ok && size.Cmp(currentSize) < 0 {
+		size = currentSize
+	}
+
+	desired.Spec = *current.Spec.DeepCopy()
+	if desired.Spec.Resources.Requests == nil {
+		desired.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	desired.Spec.Resources.Requests[corev1.ResourceStorage] = size
+}
+
+// ownedPersistentVolumeClaims lists the PersistentVolumeClaims controlled by
+// the Microservice.
+func (r *MicroserviceReconciler) ownedPersistentVolumeClaims(mic *microservicev1.Microservice) ([]corev1.PersistentVolumeClaim, error) {
+	list := &corev1.PersistentVolumeClaimList{}
+	err := r.Client.List(context.TODO(), list,
+		client.InNamespace(mic.GetNamespace()),
+		client.MatchingLabels{microservicev1.ClaimOwnerLabel: mic.GetName()},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list persistent volume claims")
+	}
+
+	owned := []corev1.PersistentVolumeClaim{}
+	for _, pvc := range list.Items {
+		if metav1.IsControlledBy(&pvc, mic) {
+			owned = append(owned, pvc)
+		}
+	}
+
+	return owned, nil
+}
+
+// retirePersistentVolumeClaim deletes a PersistentVolumeClaim that is no
+// longer needed, or releases it by removing the owner reference if it is
+// retained.
+func (r *MicroserviceReconciler) retirePersistentVolumeClaim(mic *microservicev1.Microservice, pvc *corev1.PersistentVolumeClaim, reqLogger logr.Logger) error {
+	if microservicev1.RetentionPolicy(pvc.Annotations[microservicev1.RetentionPolicyAnnotation]) == microservicev1.RetentionPolicyDelete {
+		reqLogger.Info("Deleting persistent volume claim", "name", pvc.Name)
+		err := r.Client.Delete(context.TODO(), pvc)
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete persistent volume claim %s", pvc.Name)
+		}
+		return nil
+	}
+
+	reqLogger.Info("Releasing persistent volume claim", "name", pvc.Name)
+	patch := client.MergeFrom(pvc.DeepCopy())
+	ownerReferences := []metav1.OwnerReference{}
+	for _, ref := range pvc.OwnerReferences {
+		if ref.UID != mic.GetUID() {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	pvc.OwnerReferences = ownerReferences
+	delete(pvc.Labels, microservicev1.ClaimOwnerLabel)
+	err := r.Client.Patch(context.TODO(), pvc, patch)
+	if err != nil {
+		return errors.Wrapf(err, "failed to release persistent volume claim %s", pvc.Name)
+	}
+
+	return nil
+}
+
+// setFinalizer adds or removes a finalizer of the Microservice.
+func (r *MicroserviceReconciler) setFinalizer(mic *microservicev1.Microservice, finalizer string, present bool) error {
+	if controllerutil.ContainsFinalizer(mic, finalizer) == present {
+		return nil
+	}
+
+	patch := client.MergeFrom(mic.DeepCopy())
+	if present {
+		controllerutil.AddFinalizer(mic, finalizer)
+	} else {
+		controllerutil.RemoveFinalizer(mic, finalizer)
+	}
+	err := r.Client.Patch(context.TODO(), mic, patch)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update finalizer %s", finalizer)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestPersistentVolumeClaims(t *testing.T) {
+	dataKey := types.NamespacedName{Name: "data", Namespace: "default"}
+	cacheKey := types.NamespacedName{Name: "cache", Namespace: "default"}
+
+	newMicroservice := func(t *testing.T) (*MicroserviceReconciler, *microservicev1.Microservice, *microservicev1.MicroserviceStatus) {
+		return newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+			Image: "image:latest",
+			PersistentVolumeClaims: []microservicev1.PersistentVolumeClaim{
+				{Name: "data", Size: resource.MustParse("10Gi")},
+				{Name: "cache", Size: resource.MustParse("1Gi"), RetentionPolicy: microservicev1.RetentionPolicyDelete},
+			},
+		})
+	}
+
+	t.Run("created and owned", func(t *testing.T) {
+		r, mic, status := newMicroservice(t)
+
+		require.NoError(t, r.checkPersistentVolumeClaims(mic, status, log.Log))
+		assert.True(t, controllerutil.ContainsFinalizer(mic, persistentVolumeClaimFinalizer))
+
+		data := &corev1.PersistentVolumeClaim{}
+		require.NoError(t, r.Client.Get(context.TODO(), dataKey, data))
+		assert.True(t, metav1.IsControlledBy(data, mic))
+		assert.Equal(t, resource.MustParse("10Gi"), data.Spec.Resources.Requests[corev1.ResourceStorage])
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, data.Spec.AccessModes)
+		assert.Equal(t, "Retain", data.Annotations[microservicev1.RetentionPolicyAnnotation])
+		require.NoError(t, r.Client.Get(context.TODO(), cacheKey, &corev1.PersistentVolumeClaim{}))
+	})
+
+	t.Run("resized", func(t *testing.T) {
+		r, mic, status := newMicroservice(t)
+
+		require.NoError(t, r.checkPersistentVolumeClaims(mic, status, log.Log))
+
+		// the API server fills in the defaults of a bound claim
+		storageClass := "standard"
+		volumeMode := corev1.PersistentVolumeFilesystem
+		data := &corev1.PersistentVolumeClaim{}
+		require.NoError(t, r.Client.Get(context.TODO(), dataKey, data))
+		data.Spec.StorageClassName = &storageClass
+		data.Spec.VolumeMode = &volumeMode
+		data.Spec.VolumeName = "pv-data"
+		require.NoError(t, r.Client.Update(context.TODO(), data))
+
+		// only the storage request of an existing claim is updated
+		mic.Spec.PersistentVolumeClaims[0].Size = resource.MustParse("20Gi")
+		mic.Spec.PersistentVolumeClaims[0].AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+		require.NoError(t, r.checkPersistentVolumeClaims(mic, status, log.Log))
+		require.NoError(t, r.Client.Get(context.TODO(), dataKey, data))
+		assert.Equal(t, resource.MustParse("20Gi"), data.Spec.Resources.Requests[corev1.ResourceStorage])
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, data.Spec.AccessModes)
+		assert.Equal(t, &storageClass, data.Spec.StorageClassName)
+		assert.Equal(t, &volumeMode, data.Spec.VolumeMode)
+		assert.Equal(t, "pv-data", data.Spec.VolumeName)
+
+		// and it never shrinks
+		mic.Spec.PersistentVolumeClaims[0].Size = resource.MustParse("5Gi")
+		require.NoError(t, r.checkPersistentVolumeClaims(mic, status, log.Log))
+		require.NoError(t, r.Client.Get(context.TODO(), dataKey, data))
+		assert.Equal(t, resource.MustParse("20Gi"), data.Spec.Resources.Requests[corev1.ResourceStorage])
+	})
+
+	t.Run("removed from the spec", func(t *testing.T) {
+		r, mic, status := newMicroservice(t)
+
+		require.NoError(t, r.checkPersistentVolumeClaims(mic, status, log.Log))
+		mic.Spec.PersistentVolumeClaims = nil
+		require.NoError(t, r.checkPersistentVolumeClaims(mic, status, log.Log))
+		assert.False(t, controllerutil.ContainsFinalizer(mic, persistentVolumeClaimFinalizer))
+
+		err := r.Client.Get(context.TODO(), cacheKey, &corev1.PersistentVolumeClaim{})
+		assert.True(t, k8sErrors.IsNotFound(err))
+
+		data := &corev1.PersistentVolumeClaim{}
+		require.NoError(t, r.Client.Get(context.TODO(), dataKey, data))
+		assert.Empty(t, data.OwnerReferences)
+		assert.NotContains(t, data.Labels, microservicev1.ClaimOwnerLabel)
+	})
+
+	t.Run("microservice deleted", func(t *testing.T) {
+		r, mic, status := newMicroservice(t)
+
+		require.NoError(t, r.checkPersistentVolumeClaims(mic, status, log.Log))
+		require.NoError(t, r.finalizePersistentVolumeClaims(mic, log.Log))
+		assert.False(t, controllerutil.ContainsFinalizer(mic, persistentVolumeClaimFinalizer))
+
+		err := r.Client.Get(context.TODO(), cacheKey, &corev1.PersistentVolumeClaim{})
+		assert.True(t, k8sErrors.IsNotFound(err))
+
+		data := &corev1.PersistentVolumeClaim{}
+		require.NoError(t, r.Client.Get(context.TODO(), dataKey, data))
+		assert.Empty(t, data.OwnerReferences)
+	})
+
+	t.Run("claim owned by something else", func(t *testing.T) {
+		r, mic, status := newMicroservice(t)
+
+		require.NoError(t, r.Client.Create(context.TODO(), &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+		}))
+		assert.Error(t, r.checkPersistentVolumeClaims(mic, status, log.Log))
+	})
+}
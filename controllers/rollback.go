@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// revisionAnnotation is set by the Deployment controller on a Deployment and
+// its ReplicaSets to the number of the rollout they belong to.
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// rollbackActive returns true while the Deployment runs a pod template that
+// was restored after the rollout of the current generation failed.
+func rollbackActive(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus) bool {
+	return status.Rollback != nil && status.Rollback.Generation == mic.GetGeneration()
+}
+
+// rolloutFailed returns true and the reason when the Deployment controller
+// gave up on the current rollout or, with automatic rollbacks enabled, when a
+// pod of the new ReplicaSet is crash looping.
+func (r *MicroserviceReconciler) rolloutFailed(mic *microservicev1.Microservice, deployment *appsv1.Deployment) (bool, string, error) {
+	if failed, message := deploymentRolloutFailed(deployment); failed {
+		return true, message, nil
+	}
+	if !mic.Spec.AutoRollback || deploymentRolledOut(deployment) {
+		return false, "", nil
+	}
+
+	newReplicaSet, err := r.deploymentReplicaSet(deployment, deployment.Annotations[revisionAnnotation])
+	if err != nil || newReplicaSet == nil {
+		return false, "", err
+	}
+
+	pods := &corev1.PodList{}
+	err = r.Client.List(context.TODO(), pods,
+		client.InNamespace(deployment.Namespace),
+		client.MatchingLabels(newReplicaSet.Spec.Selector.MatchLabels),
+	)
+	if err != nil {
+		return false, "", errors.Wrap(err, "failed to list the pods of the new replica set")
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !metav1.IsControlledBy(pod, newReplicaSet) {
+			continue
+		}
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.State.Waiting != nil && container.State.Waiting.Reason == "CrashLoopBackOff" {
+				return true, fmt.Sprintf("container %s of pod %s is crash looping", container.Name, pod.Name), nil
+			}
+		}
+	}
+
+	return false, "", nil
+}
+
+// rollbackDeployment restores the pod template of the last revision of the
+// Deployment that rolled out successfully and records the rollback in the
+// status.
+func (r *MicroserviceReconciler) rollbackDeployment(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, current *appsv1.Deployment, message string, reqLogger logr.Logger) error {
+	failedRevision := current.Annotations[revisionAnnotation]
+	if status.LastGoodRevision == "" || status.LastGoodRevision == failedRevision {
+		return errors.Errorf("deployment rollout failed: %s; no earlier successful revision to roll back to", message)
+	}
+
+	good, err := r.deploymentReplicaSet(current, status.LastGoodRevision)
+	if err != nil {
+		return err
+	}
+	if good == nil {
+		return errors.Errorf("deployment rollout failed: %s; the replica set of revision %s no longer exists", message, status.LastGoodRevision)
+	}
+
+	desired, err := r.desiredDeployment(mic, status, current)
+	if err != nil {
+		return err
+	}
+	template := good.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	desired.Spec.Template = *template
+
+	failedImage := containerImage(current.Spec.Template, mic.GetName())
+
+	// the rollback is applied regardless of the drift policy
+	err = r.Resources.Update(current, desired, reqLogger)
+	if err != nil {
+		return errors.Wrap(err, "failed to roll back the deployment")
+	}
+
+	status.Rollback = &microservicev1.RollbackStatus{
+		Generation:       mic.GetGeneration(),
+		FailedRevision:   failedRevision,
+		FailedImage:      failedImage,
+		RestoredRevision: status.LastGoodRevision,
+		RestoredImage:    containerImage(*template, mic.GetName()),
+		Message:          message,
+		Time:             metav1.Now(),
+	}
+
+	reqLogger.Info("Rolled back failed deployment rollout",
+		"failedRevision", status.Rollback.FailedRevision,
+		"restoredRevision", status.Rollback.RestoredRevision,
+		"reason", message,
+	)
+	r.Recorder.Eventf(mic, corev1.EventTypeWarning, "RolledBack",
+		"Rollout of revision %s failed, rolled back to revision %s: %s",
+		status.Rollback.FailedRevision, status.Rollback.RestoredRevision, message,
+	)
+
+	return nil
+}
+
+// deploymentReplicaSet returns the ReplicaSet of the Deployment with the given
+// revision, or nil if there is none.
+func (r *MicroserviceReconciler) deploymentReplicaSet(deployment *appsv1.Deployment, revision string) (*appsv1.ReplicaSet, error) {
+	if revision == "" || deployment.Spec.Selector == nil {
+		return nil, nil
+	}
+
+	replicaSets := &appsv1.ReplicaSetList{}
+	err := r.Client.List(context.TODO(), replicaSets,
+		client.InNamespace(deployment.Namespace),
+		client.MatchingLabels(deployment.Spec.Selector.MatchLabels),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the replica sets of the deployment")
+	}
+
+	for i := range replicaSets.Items {
+		replicaSet := &replicaSets.Items[i]
+		if metav1.IsControlledBy(replicaSet, deployment) && replicaSet.Annotations[revisionAnnotation] == revision {
+			return replicaSet, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// containerImage returns the image of the container with the given name.
+func containerImage(template corev1.PodTemplateSpec, name string) string {
+	for _, container := range template.Spec.Containers {
+		if container.Name == name {
+			return container.Image
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestRollback(t *testing.T) {
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	// newFailingRollout returns a reconciler whose Deployment rolls out
+	// image:v2 as revision 2 after image:v1 rolled out as revision 1
+	newFailingRollout := func(t *testing.T, autoRollback bool) (*MicroserviceReconciler, *microservicev1.Microservice, *microservicev1.MicroserviceStatus, *appsv1.Deployment) {
+		r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+			Image:        "image:v2",
+			AutoRollback: autoRollback,
+		})
+		status.LastGoodRevision = "1"
+
+		require.NoError(t, r.checkDeployment(mic, status, log.Log))
+
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		current.Annotations = map[string]string{revisionAnnotation: "2"}
+		require.NoError(t, r.Client.Update(context.TODO(), current))
+
+		good := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "foo-1",
+				Namespace:       "default",
+				Labels:          map[string]string{microservicev1.NameLabel: "foo", microservicev1.TrackLabel: microservicev1.TrackStable, appsv1.DefaultDeploymentUniqueLabelKey: "1"},
+				Annotations:     map[string]string{revisionAnnotation: "1"},
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(current, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+			},
+			Spec: appsv1.ReplicaSetSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{microservicev1.NameLabel: "foo", microservicev1.TrackLabel: microservicev1.TrackStable, appsv1.DefaultDeploymentUniqueLabelKey: "1"},
+				},
+				Template: *current.Spec.Template.DeepCopy(),
+			},
+		}
+		good.Spec.Template.Labels = good.Labels
+		good.Spec.Template.Spec.Containers[0].Image = "image:v1"
+		require.NoError(t, r.Client.Create(context.TODO(), good))
+
+		return r, mic, status, current
+	}
+
+	progressDeadlineExceeded := func(t *testing.T, r *MicroserviceReconciler, current *appsv1.Deployment) {
+		current.Status.ObservedGeneration = current.Generation
+		current.Status.Conditions = []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded", Message: "timed out"},
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		r, mic, status, current := newFailingRollout(t, false)
+		progressDeadlineExceeded(t, r, current)
+
+		err := r.checkDeploymentStatus(mic, status, log.Log)
+		assert.EqualError(t, err, "deployment rollout failed: timed out")
+		assert.Nil(t, status.Rollback)
+	})
+
+	t.Run("progress deadline exceeded", func(t *testing.T) {
+		r, mic, status, current := newFailingRollout(t, true)
+		progressDeadlineExceeded(t, r, current)
+
+		require.NoError(t, r.checkDeploymentStatus(mic, status, log.Log))
+		assert.Equal(t, microservicev1.Reconciling, status.State)
+		require.NotNil(t, status.Rollback)
+		assert.Equal(t, mic.Generation, status.Rollback.Generation)
+		assert.Equal(t, "2", status.Rollback.FailedRevision)
+		assert.Equal(t, "image:v2", status.Rollback.FailedImage)
+		assert.Equal(t, "1", status.Rollback.RestoredRevision)
+		assert.Equal(t, "image:v1", status.Rollback.RestoredImage)
+		assert.Equal(t, "timed out", status.Rollback.Message)
+
+		cond := meta.FindStatusCondition(status.Conditions, microservicev1.ConditionDegraded)
+		require.NotNil(t, cond)
+		assert.Equal(t, metav1.ConditionTrue, cond.Status)
+		assert.Equal(t, microservicev1.ReasonRolledBack, cond.Reason)
+
+		recorder := r.Recorder.(*record.FakeRecorder)
+		require.Len(t, recorder.Events, 1)
+		assert.Contains(t, <-recorder.Events, "Rollout of revision 2 failed, rolled back to revision 1: timed out")
+
+		result := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, result))
+		assert.Equal(t, "image:v1", result.Spec.Template.Spec.Containers[0].Image)
+		assert.NotContains(t, result.Spec.Template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+		// the restored template is kept until the spec changes
+		require.NoError(t, r.checkDeployment(mic, status, log.Log))
+		require.NoError(t, r.Client.Get(context.TODO(), key, result))
+		assert.Equal(t, "image:v1", result.Spec.Template.Spec.Containers[0].Image)
+		assert.Empty(t, status.Drift)
+
+		mic.Spec.Image = "image:v3"
+		mic.Generation = 2
+		require.NoError(t, r.checkDeployment(mic, status, log.Log))
+		require.NoError(t, r.Client.Get(context.TODO(), key, result))
+		assert.Equal(t, "image:v3", result.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("crash looping new replica set", func(t *testing.T) {
+		r, mic, status, current := newFailingRollout(t, true)
+
+		labels := map[string]string{microservicev1.NameLabel: "foo", microservicev1.TrackLabel: microservicev1.TrackStable, appsv1.DefaultDeploymentUniqueLabelKey: "2"}
+		replicaSet := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "foo-2",
+				Namespace:       "default",
+				UID:             "rs-2",
+				Labels:          labels,
+				Annotations:     map[string]string{revisionAnnotation: "2"},
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(current, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+			},
+			Spec: appsv1.ReplicaSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: current.Spec.Template,
+			},
+		}
+		require.NoError(t, r.Client.Create(context.TODO(), replicaSet))
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "foo-2-abcde",
+				Namespace:       "default",
+				Labels:          labels,
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(replicaSet, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{
+						Name: "foo",
+						State: corev1.ContainerState{
+							Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+						},
+					},
+				},
+			},
+		}
+		require.NoError(t, r.Client.Create(context.TODO(), pod))
+
+		require.NoError(t, r.checkDeploymentStatus(mic, status, log.Log))
+		require.NotNil(t, status.Rollback)
+		assert.Equal(t, "container foo of pod foo-2-abcde is crash looping", status.Rollback.Message)
+
+		result := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, result))
+		assert.Equal(t, "image:v1", result.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("failed rollback", func(t *testing.T) {
+		r, mic, status, current := newFailingRollout(t, true)
+		status.Rollback = &microservicev1.RollbackStatus{Generation: mic.Generation, FailedRevision: "1", RestoredRevision: "0"}
+		progressDeadlineExceeded(t, r, current)
+
+		err := r.checkDeploymentStatus(mic, status, log.Log)
+		assert.EqualError(t, err, "deployment rollout failed: timed out")
+	})
+}
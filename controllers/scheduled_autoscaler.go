@@ -4,14 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/go-logr/logr"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	"k8s.io/apimachinery/pkg/types"
 )
 
 // updateStatusReconciling sets the Mattermost state to reconciling.
-func (r *ScheduledAutoscalerReconciler) scale(sa *microservicev1beta1.ScheduledAutoscaler, schedule *microservicev1beta1.Schedule, status microservicev1beta1.ScheduledAutoscalerStatus, reqLogger logr.Logger) func() {
+func (r *ScheduledAutoscalerReconciler) scale(sa *microservicev1.ScheduledAutoscaler, schedule *microservicev1.Schedule, status microservicev1.ScheduledAutoscalerStatus, reqLogger logr.Logger) func() {
 	return func() {
 		l := fmt.Sprintf("autoscaling %s", sa.Spec.MicroserviceName)
 		reqLogger.Info(l)
@@ -32,7 +32,7 @@ func (r *ScheduledAutoscalerReconciler) scale(sa *microservicev1beta1.ScheduledA
 			return
 		}
 
-		mic := &microservicev1beta1.Microservice{}
+		mic := &microservicev1.Microservice{}
 		err = r.Client.Get(context.TODO(), types.NamespacedName{Name: sa.Spec.MicroserviceName, Namespace: sa.Namespace}, mic)
 		if err != nil {
 			reqLogger.Error(err, "failed to get microservice")
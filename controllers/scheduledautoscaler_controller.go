@@ -30,7 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
 )
 
@@ -67,7 +67,7 @@ func NewScheduledAutoscalerReconciler(mgr ctrl.Manager, allcron *cron.Cron) *Sch
 func (r *ScheduledAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := log.FromContext(ctx)
 
-	sa := &microservicev1beta1.ScheduledAutoscaler{}
+	sa := &microservicev1.ScheduledAutoscaler{}
 	err := r.Client.Get(ctx, req.NamespacedName, sa)
 	if err != nil && k8sErrors.IsNotFound(err) {
 		// Request object not found, could have been deleted after reconcile
@@ -119,7 +119,7 @@ func (r *ScheduledAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.
 		return ctrl.Result{}, nil
 	}
 
-	if status.State != microservicev1beta1.Reconciling {
+	if status.State != microservicev1.Reconciling {
 		err = r.updateStatusReconciling(sa, status, reqLogger)
 		if err != nil {
 			return reconcile.Result{}, err
@@ -139,6 +139,12 @@ func (r *ScheduledAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.
 
 		id, err := r.allcron.AddFunc(toSchedule.Cron, r.scale(sa, &toSchedule, status, reqLogger))
 		if err != nil {
+			// don't leave the schedules added so far registered without
+			// being recorded in the status
+			for _, scheduledID := range scheduledStatusUpdate {
+				r.allcron.Remove(cron.EntryID(scheduledID))
+			}
+
 			r.updateStatusReconcilingAndLogError(sa, status, reqLogger, err)
 			return reconcile.Result{}, err
 		}
@@ -146,7 +152,7 @@ func (r *ScheduledAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.
 		scheduledStatusUpdate = append(scheduledStatusUpdate, int(id))
 	}
 
-	status.State = microservicev1beta1.Stable
+	status.State = microservicev1.Stable
 	err = r.updateStatus(sa, status, reqLogger)
 	if err != nil {
 		r.updateStatusReconcilingAndLogError(sa, status, reqLogger, err)
@@ -167,7 +173,7 @@ func (r *ScheduledAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.
 func (r *ScheduledAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	pred := predicate.GenerationChangedPredicate{}
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&microservicev1beta1.ScheduledAutoscaler{}).
+		For(&microservicev1.ScheduledAutoscaler{}).
 		WithEventFilter(pred).
 		Complete(r)
 }
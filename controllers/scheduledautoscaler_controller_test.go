@@ -6,7 +6,7 @@ import (
 	"testing"
 	"time"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
 	"github.com/go-logr/logr"
 	"github.com/robfig/cron/v3"
@@ -51,16 +51,16 @@ func TestScheduledAutoscaler(t *testing.T) {
 	}
 	msReplicas := int32(8)
 
-	ms := &microservicev1beta1.Microservice{
+	ms := &microservicev1.Microservice{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      msName,
 			Namespace: msNamespace,
 			UID:       types.UID("test"),
 		},
-		Spec: microservicev1beta1.MicroserviceSpec{
+		Spec: microservicev1.MicroserviceSpec{
 			Image:    msImage,
 			Labels:   msLabels,
-			Replicas: msReplicas,
+			Replicas: &msReplicas,
 		},
 	}
 
@@ -70,7 +70,7 @@ func TestScheduledAutoscaler(t *testing.T) {
 	saName := "foo"
 	saNamespace := "default"
 
-	sa := &microservicev1beta1.ScheduledAutoscaler{
+	sa := &microservicev1.ScheduledAutoscaler{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      saName,
 			Namespace: saNamespace,
@@ -101,9 +101,9 @@ func TestScheduledAutoscaler(t *testing.T) {
 
 		maxReplicas := int32(10)
 		minReplicas := int32(1)
-		sa.Spec = microservicev1beta1.ScheduledAutoscalerSpec{
+		sa.Spec = microservicev1.ScheduledAutoscalerSpec{
 			MicroserviceName: msName,
-			Schedules: []microservicev1beta1.Schedule{
+			Schedules: []microservicev1.Schedule{
 				{MinReplicas: minReplicas, MaxReplicas: maxReplicas, Cron: "@every 1s", Name: "test"},
 			},
 		}
@@ -119,7 +119,7 @@ func TestScheduledAutoscaler(t *testing.T) {
 		time.Sleep(1 * time.Second)
 		r.allcron.Stop()
 
-		ms := &microservicev1beta1.Microservice{}
+		ms := &microservicev1.Microservice{}
 		err = r.Get(context.TODO(), types.NamespacedName{Name: sa.Name, Namespace: sa.Namespace}, ms)
 		assert.NoError(t, err)
 
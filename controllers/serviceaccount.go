@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
 	corev1 "k8s.io/api/core/v1"
 
@@ -12,7 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 )
 
-func (r *MicroserviceReconciler) checkServiceAccount(mic *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger) error {
+func (r *MicroserviceReconciler) checkServiceAccount(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
 	if mic.Spec.DisableServiceAccountCreation {
 		return r.Resources.DeleteServiceAccount(types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()}, reqLogger)
 	}
@@ -29,10 +29,10 @@ func (r *MicroserviceReconciler) checkServiceAccount(mic *microservicev1beta1.Mi
 		return err
 	}
 
-	return r.Resources.Update(current, desired, reqLogger)
+	return r.updateResource(mic, status, current, desired, reqLogger)
 }
 
-func (r *MicroserviceReconciler) checkServiceAccountSecret(mic *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger) error {
+func (r *MicroserviceReconciler) checkServiceAccountSecret(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
 	if mic.Spec.DisableServiceAccountCreation {
 		secretName := fmt.Sprintf("%s-sa", mic.GetName())
 		return r.Resources.DeleteSecret(types.NamespacedName{Name: secretName, Namespace: mic.GetNamespace()}, reqLogger)
@@ -50,5 +50,5 @@ func (r *MicroserviceReconciler) checkServiceAccountSecret(mic *microservicev1be
 		return err
 	}
 
-	return r.Resources.Update(current, desired, reqLogger)
+	return r.updateResource(mic, status, current, desired, reqLogger)
 }
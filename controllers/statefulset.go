@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// checkStatefulSet creates or updates the StatefulSet of the Microservice and
+// the headless Service that gives its pods their stable DNS names.
+func (r *MicroserviceReconciler) checkStatefulSet(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	err := r.checkHeadlessService(mic, status, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	// the dependency hash is set from the start, so that the new StatefulSet
+	// is not rolled out a second time by the update below
+	created := microservice.GenerateStatefulSet(mic)
+	err = r.setDependencyHash(mic, &created.Spec.Template)
+	if err != nil {
+		return err
+	}
+	err = r.Resources.CreateStatefulSetIfNotExists(mic, created, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	current := &appsv1.StatefulSet{}
+	err = r.getOwned(mic, current, mic.GetName())
+	if err != nil {
+		return err
+	}
+
+	desired, err := r.desiredStatefulSet(mic, current)
+	if err != nil {
+		return err
+	}
+
+	return r.updateResource(mic, status, current, desired, reqLogger)
+}
+
+// desiredStatefulSet generates the StatefulSet of the Microservice, keeping
+// the replicas of current if they are owned by the HorizontalPodAutoscaler.
+func (r *MicroserviceReconciler) desiredStatefulSet(mic *microservicev1.Microservice, current *appsv1.StatefulSet) (*appsv1.StatefulSet, error) {
+	desired := microservice.GenerateStatefulSet(mic)
+	err := r.setDependencyHash(mic, &desired.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	if mic.AutoscalingEnabled() && current.Spec.Replicas != nil {
+		replicas, err := r.autoscaledReplicas(mic, current, *current.Spec.Replicas)
+		if err != nil {
+			return nil, err
+		}
+		desired.Spec.Replicas = replicas
+	}
+
+	return desired, nil
+}
+
+// checkHeadlessService creates or updates the headless Service of the
+// StatefulSet of the Microservice.
+func (r *MicroserviceReconciler) checkHeadlessService(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	desired := microservice.GenerateHeadlessServiceV1(mic)
+
+	err := r.Resources.CreateServiceIfNotExists(mic, desired, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	current := &corev1.Service{}
+	err = r.getOwned(mic, current, desired.Name)
+	if err != nil {
+		return err
+	}
+
+	resources.CopyServiceEmptyAutoAssignedFields(desired, current)
+
+	return r.updateResource(mic, status, current, desired, reqLogger)
+}
+
+// checkStatefulSetStatus copies the rollout progress of the owned
+// StatefulSet into the Microservice status and sets the running state from
+// it.
+func (r *MicroserviceReconciler) checkStatefulSetStatus(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	current := &appsv1.StatefulSet{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: mic.GetName(), Namespace: mic.GetNamespace()}, current)
+	if err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(current.Spec.Selector)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the statefulset selector")
+	}
+
+	status.Replicas = current.Status.Replicas
+	status.Selector = selector.String()
+	status.ReadyReplicas = current.Status.ReadyReplicas
+	status.UpdatedReplicas = current.Status.UpdatedReplicas
+
+	if statefulSetRolledOut(current) {
+		status.Image = containerImage(current.Spec.Template, mic.GetName())
+	}
+
+	setStatefulSetConditions(mic, status, current)
+
+	status.State = statefulSetRunningState(current)
+	if status.State != microservicev1.Stable {
+		reqLogger.Info("Waiting for statefulset rollout to complete",
+			"updated", current.Status.UpdatedReplicas,
+			"available", current.Status.AvailableReplicas,
+			"desired", statefulSetReplicas(current),
+		)
+	}
+
+	return nil
+}
+
+// setStatefulSetConditions derives the Available, Progressing and Degraded
+// conditions from the status of the owned StatefulSet. StatefulSets do not
+// report failed rollouts, so the Microservice is never degraded by one.
+func setStatefulSetConditions(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, statefulSet *appsv1.StatefulSet) {
+	message := fmt.Sprintf("%d of %d replicas are available", statefulSet.Status.AvailableReplicas, statefulSetReplicas(statefulSet))
+
+	available := metav1.Condition{
+		Type:               microservicev1.ConditionAvailable,
+		Status:             metav1.ConditionFalse,
+		Reason:             microservicev1.ReasonRolloutInProgress,
+		Message:            message,
+		ObservedGeneration: mic.GetGeneration(),
+	}
+	if statefulSetAvailable(statefulSet) {
+		available.Status = metav1.ConditionTrue
+		available.Reason = microservicev1.ReasonAsExpected
+	}
+	meta.SetStatusCondition(&status.Conditions, available)
+
+	progressing := metav1.Condition{
+		Type:               microservicev1.ConditionProgressing,
+		Status:             metav1.ConditionTrue,
+		Reason:             microservicev1.ReasonRolloutInProgress,
+		Message:            message,
+		ObservedGeneration: mic.GetGeneration(),
+	}
+	if statefulSetRolledOut(statefulSet) {
+		progressing.Status = metav1.ConditionFalse
+		progressing.Reason = microservicev1.ReasonRolloutComplete
+		progressing.Message = "StatefulSet rollout is complete"
+	}
+	meta.SetStatusCondition(&status.Conditions, progressing)
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               microservicev1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             microservicev1.ReasonAsExpected,
+		ObservedGeneration: mic.GetGeneration(),
+	})
+}
+
+// statefulSetRunningState maps the rollout progress of the StatefulSet to the
+// running state of the Microservice, like deploymentRunningState.
+func statefulSetRunningState(statefulSet *appsv1.StatefulSet) microservicev1.RunningState {
+	if statefulSetRolledOut(statefulSet) {
+		return microservicev1.Stable
+	}
+
+	if statefulSetAvailable(statefulSet) {
+		return microservicev1.Ready
+	}
+
+	return microservicev1.Reconciling
+}
+
+// statefulSetRolledOut returns true once every replica of the StatefulSet
+// runs the current revision and is available, the same way
+// `kubectl rollout status` does.
+func statefulSetRolledOut(statefulSet *appsv1.StatefulSet) bool {
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation {
+		return false
+	}
+
+	replicas := statefulSetReplicas(statefulSet)
+
+	return statefulSet.Status.UpdatedReplicas == replicas &&
+		statefulSet.Status.Replicas == replicas &&
+		statefulSet.Status.AvailableReplicas == replicas &&
+		statefulSet.Status.CurrentRevision == statefulSet.Status.UpdateRevision
+}
+
+// statefulSetAvailable returns true when at most one replica of the
+// StatefulSet is unavailable, which is as many as its rolling update takes
+// down at a time.
+func statefulSetAvailable(statefulSet *appsv1.StatefulSet) bool {
+	replicas := statefulSetReplicas(statefulSet)
+	if replicas == 0 {
+		return true
+	}
+
+	return statefulSet.Status.AvailableReplicas > 0 && statefulSet.Status.AvailableReplicas >= replicas-1
+}
+
+func statefulSetReplicas(statefulSet *appsv1.StatefulSet) int32 {
+	if statefulSet.Spec.Replicas == nil {
+		return 1
+	}
+
+	return *statefulSet.Spec.Replicas
+}
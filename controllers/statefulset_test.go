@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestStatefulSet(t *testing.T) {
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+	headlessKey := types.NamespacedName{Name: "foo-headless", Namespace: "default"}
+
+	replicas := int32(2)
+	r, mic, status := newFakeMicroservice(t, microservicev1.MicroserviceSpec{
+		Image:    "image:v1",
+		Replicas: &replicas,
+		Ingress: []microservicev1.Ingress{
+			{Name: "http", ContainerPort: 8080},
+		},
+	})
+
+	// reconcile runs the workload checks in the order of Reconcile
+	reconcile := func(t *testing.T) {
+		err := r.checkWorkload(mic, status, log.Log)
+		setReconciledCondition(mic, status, workloadConditions[mic.GetWorkloadKind()], err)
+		require.NoError(t, err)
+		require.NoError(t, r.checkWorkloadStatus(mic, status, log.Log))
+		require.NoError(t, r.retireWorkloads(mic, status, log.Log))
+	}
+
+	rollOutDeployment := func(t *testing.T) {
+		current := &appsv1.Deployment{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		current.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: current.Generation,
+			Replicas:           *current.Spec.Replicas,
+			UpdatedReplicas:    *current.Spec.Replicas,
+			AvailableReplicas:  *current.Spec.Replicas,
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	}
+
+	rollOutStatefulSet := func(t *testing.T) {
+		current := &appsv1.StatefulSet{}
+		require.NoError(t, r.Client.Get(context.TODO(), key, current))
+		current.Status = appsv1.StatefulSetStatus{
+			ObservedGeneration: current.Generation,
+			Replicas:           *current.Spec.Replicas,
+			UpdatedReplicas:    *current.Spec.Replicas,
+			ReadyReplicas:      *current.Spec.Replicas,
+			AvailableReplicas:  *current.Spec.Replicas,
+			CurrentRevision:    "foo-1",
+			UpdateRevision:     "foo-1",
+		}
+		require.NoError(t, r.Client.Status().Update(context.TODO(), current))
+	}
+
+	assertNotFound := func(t *testing.T, key types.NamespacedName, obj client.Object) {
+		err := r.Client.Get(context.TODO(), key, obj)
+		assert.True(t, k8sErrors.IsNotFound(err), "%T %s", obj, key.Name)
+	}
+
+	reconcile(t)
+	rollOutDeployment(t)
+	reconcile(t)
+	assert.Equal(t, microservicev1.Stable, status.State)
+
+	// the Deployment keeps serving until the StatefulSet rolled out
+	mic.Spec.WorkloadKind = microservicev1.WorkloadKindStatefulSet
+	mic.Spec.StatefulSet = &microservicev1.StatefulSetSpec{
+		VolumeClaimTemplates: []microservicev1.VolumeClaimTemplate{{Name: "state", Size: resource.MustParse("1Gi")}},
+	}
+	mic.Generation = 2
+	reconcile(t)
+	assert.Equal(t, microservicev1.Reconciling, status.State)
+	require.NoError(t, r.Client.Get(context.TODO(), key, &appsv1.Deployment{}))
+
+	statefulSet := &appsv1.StatefulSet{}
+	require.NoError(t, r.Client.Get(context.TODO(), key, statefulSet))
+	assert.True(t, metav1.IsControlledBy(statefulSet, mic))
+	assert.Equal(t, "foo-headless", statefulSet.Spec.ServiceName)
+	assert.Equal(t, "state", statefulSet.Spec.VolumeClaimTemplates[0].Name)
+
+	headless := &corev1.Service{}
+	require.NoError(t, r.Client.Get(context.TODO(), headlessKey, headless))
+	assert.Equal(t, corev1.ClusterIPNone, headless.Spec.ClusterIP)
+
+	rollOutStatefulSet(t)
+	reconcile(t)
+	assert.Equal(t, microservicev1.Stable, status.State)
+	assert.Equal(t, "image:v1", status.Image)
+	assertNotFound(t, key, &appsv1.Deployment{})
+	assert.Nil(t, meta.FindStatusCondition(status.Conditions, microservicev1.ConditionDeploymentReconciled))
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, microservicev1.ConditionStatefulSetReconciled))
+
+	// and the other way around
+	mic.Spec.WorkloadKind = ""
+	mic.Spec.StatefulSet = nil
+	mic.Generation = 3
+	reconcile(t)
+	require.NoError(t, r.Client.Get(context.TODO(), key, &appsv1.StatefulSet{}))
+
+	rollOutDeployment(t)
+	reconcile(t)
+	assert.Equal(t, microservicev1.Stable, status.State)
+	assertNotFound(t, key, &appsv1.StatefulSet{})
+	assertNotFound(t, headlessKey, &corev1.Service{})
+	assert.Nil(t, meta.FindStatusCondition(status.Conditions, microservicev1.ConditionStatefulSetReconciled))
+}
+
+func TestStatefulSetRunningState(t *testing.T) {
+	newStatefulSet := func(available int32, currentRevision string) *appsv1.StatefulSet {
+		replicas := int32(3)
+		return &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{Replicas: &replicas},
+			Status: appsv1.StatefulSetStatus{
+				Replicas:          3,
+				UpdatedReplicas:   3,
+				ReadyReplicas:     available,
+				AvailableReplicas: available,
+				CurrentRevision:   currentRevision,
+				UpdateRevision:    "foo-2",
+			},
+		}
+	}
+
+	assert.Equal(t, microservicev1.Stable, statefulSetRunningState(newStatefulSet(3, "foo-2")))
+	assert.Equal(t, microservicev1.Ready, statefulSetRunningState(newStatefulSet(3, "foo-1")))
+	assert.Equal(t, microservicev1.Ready, statefulSetRunningState(newStatefulSet(2, "foo-2")))
+	assert.Equal(t, microservicev1.Reconciling, statefulSetRunningState(newStatefulSet(1, "foo-2")))
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/resources"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyTrack creates or updates the Deployment of a canary track and, if the
+// Microservice exposes ports, its Service.
+func (r *MicroserviceReconciler) applyTrack(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, desiredDeployment *appsv1.Deployment, desiredService *corev1.Service, reqLogger logr.Logger) error {
+	err := r.setDependencyHash(mic, &desiredDeployment.Spec.Template)
+	if err != nil {
+		return err
+	}
+	err = r.Resources.CreateDeploymentIfNotExists(mic, desiredDeployment, reqLogger)
+	if err != nil {
+		return err
+	}
+	currentDeployment := &appsv1.Deployment{}
+	err = r.getOwned(mic, currentDeployment, desiredDeployment.Name)
+	if err != nil {
+		return err
+	}
+	err = r.updateResource(mic, status, currentDeployment, desiredDeployment, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	return r.applyTrackService(mic, status, desiredService, reqLogger)
+}
+
+// applyTrackService creates or updates the Service of a canary track or a
+// blue/green preview, or removes it if the Microservice exposes no ports.
+func (r *MicroserviceReconciler) applyTrackService(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, desiredService *corev1.Service, reqLogger logr.Logger) error {
+	if len(mic.Spec.Ingress) == 0 {
+		return r.deleteOwned(mic, &corev1.Service{}, desiredService.Name, reqLogger)
+	}
+
+	err := r.Resources.CreateServiceIfNotExists(mic, desiredService, reqLogger)
+	if err != nil {
+		return err
+	}
+	currentService := &corev1.Service{}
+	err = r.getOwned(mic, currentService, desiredService.Name)
+	if err != nil {
+		return err
+	}
+	resources.CopyServiceEmptyAutoAssignedFields(desiredService, currentService)
+
+	return r.updateResource(mic, status, currentService, desiredService, reqLogger)
+}
+
+// deleteTrack removes the Deployment and Service of a canary track.
+func (r *MicroserviceReconciler) deleteTrack(mic *microservicev1.Microservice, name string, reqLogger logr.Logger) error {
+	err := r.deleteOwned(mic, &appsv1.Deployment{}, name, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	return r.deleteOwned(mic, &corev1.Service{}, name, reqLogger)
+}
+
+// trackAvailable returns true once the named Deployment rolled out image to
+// all of its replicas.
+func (r *MicroserviceReconciler) trackAvailable(mic *microservicev1.Microservice, name, image string) (bool, error) {
+	current := &appsv1.Deployment{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: mic.GetNamespace()}, current)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrapf(err, "failed to get deployment %s", name)
+	}
+
+	return deploymentRolledOut(current) && containerImage(current.Spec.Template, mic.GetName()) == image, nil
+}
+
+// getOwned fetches the named resource, refusing resources with the same name
+// that belong to something else.
+func (r *MicroserviceReconciler) getOwned(mic *microservicev1.Microservice, obj client.Object, name string) error {
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: mic.GetNamespace()}, obj)
+	if err != nil {
+		return err
+	}
+
+	if !metav1.IsControlledBy(obj, mic) {
+		return errors.Errorf("%s already exists and is not owned by the Microservice", name)
+	}
+
+	return nil
+}
+
+// deleteOwned deletes the named resource if it exists and is owned by the
+// Microservice.
+func (r *MicroserviceReconciler) deleteOwned(mic *microservicev1.Microservice, obj client.Object, name string, reqLogger logr.Logger) error {
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: mic.GetNamespace()}, obj)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to check if %s exists", name)
+	}
+
+	if !metav1.IsControlledBy(obj, mic) {
+		return nil
+	}
+
+	// Jobs orphan their pods unless told otherwise
+	reqLogger.Info("Deleting resource", "name", name)
+	err = r.Client.Delete(context.TODO(), obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete %s", name)
+	}
+
+	return nil
+}
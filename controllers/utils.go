@@ -8,16 +8,16 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 )
 
 // updateStatusReconciling sets the Mattermost state to reconciling.
-func (r *MicroserviceReconciler) updateStatusReconciling(deployment *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger) error {
-	status.State = microservicev1beta1.Reconciling
+func (r *MicroserviceReconciler) updateStatusReconciling(deployment *microservicev1.Microservice, status microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	status.State = microservicev1.Reconciling
 	return r.updateStatus(deployment, status, reqLogger)
 }
 
-func (r *MicroserviceReconciler) updateStatus(deployment *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger) error {
+func (r *MicroserviceReconciler) updateStatus(deployment *microservicev1.Microservice, status microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
 	if reflect.DeepEqual(deployment.Status, status) {
 		return nil
 	}
@@ -35,7 +35,7 @@ func (r *MicroserviceReconciler) updateStatus(deployment *microservicev1beta1.Mi
 	return nil
 }
 
-func (r *MicroserviceReconciler) updateStatusReconcilingAndLogError(deployment *microservicev1beta1.Microservice, status microservicev1beta1.MicroserviceStatus, reqLogger logr.Logger, statusErr error) {
+func (r *MicroserviceReconciler) updateStatusReconcilingAndLogError(deployment *microservicev1.Microservice, status microservicev1.MicroserviceStatus, reqLogger logr.Logger, statusErr error) {
 	if statusErr != nil {
 		status.Error = statusErr.Error()
 	}
@@ -49,12 +49,12 @@ func (r *MicroserviceReconciler) updateStatusReconcilingAndLogError(deployment *
 //---
 
 // updateStatusReconciling sets the Mattermost state to reconciling.
-func (r *ScheduledAutoscalerReconciler) updateStatusReconciling(sa *microservicev1beta1.ScheduledAutoscaler, status microservicev1beta1.ScheduledAutoscalerStatus, reqLogger logr.Logger) error {
-	status.State = microservicev1beta1.Reconciling
+func (r *ScheduledAutoscalerReconciler) updateStatusReconciling(sa *microservicev1.ScheduledAutoscaler, status microservicev1.ScheduledAutoscalerStatus, reqLogger logr.Logger) error {
+	status.State = microservicev1.Reconciling
 	return r.updateStatus(sa, status, reqLogger)
 }
 
-func (r *ScheduledAutoscalerReconciler) updateStatus(sa *microservicev1beta1.ScheduledAutoscaler, status microservicev1beta1.ScheduledAutoscalerStatus, reqLogger logr.Logger) error {
+func (r *ScheduledAutoscalerReconciler) updateStatus(sa *microservicev1.ScheduledAutoscaler, status microservicev1.ScheduledAutoscalerStatus, reqLogger logr.Logger) error {
 	if reflect.DeepEqual(sa.Status, status) {
 		return nil
 	}
@@ -72,7 +72,7 @@ func (r *ScheduledAutoscalerReconciler) updateStatus(sa *microservicev1beta1.Sch
 	return nil
 }
 
-func (r *ScheduledAutoscalerReconciler) updateStatusReconcilingAndLogError(sa *microservicev1beta1.ScheduledAutoscaler, status microservicev1beta1.ScheduledAutoscalerStatus, reqLogger logr.Logger, statusErr error) {
+func (r *ScheduledAutoscalerReconciler) updateStatusReconcilingAndLogError(sa *microservicev1.ScheduledAutoscaler, status microservicev1.ScheduledAutoscalerStatus, reqLogger logr.Logger, statusErr error) {
 	if statusErr != nil {
 		status.Error = statusErr.Error()
 	}
@@ -83,11 +83,11 @@ func (r *ScheduledAutoscalerReconciler) updateStatusReconcilingAndLogError(sa *m
 	}
 }
 
-func removeSchedule(slice []microservicev1beta1.Schedule, s int) []microservicev1beta1.Schedule {
+func removeSchedule(slice []microservicev1.Schedule, s int) []microservicev1.Schedule {
 	return append(slice[:s], slice[s+1:]...)
 }
 
-func containsSchedule(slice []microservicev1beta1.Schedule, str string) (int, bool) {
+func containsSchedule(slice []microservicev1.Schedule, str string) (int, bool) {
 	for k, v := range slice {
 		if v.Name == str {
 			return k, true
@@ -98,7 +98,7 @@ func containsSchedule(slice []microservicev1beta1.Schedule, str string) (int, bo
 }
 
 // updateStatusReconciling sets the Mattermost state to reconciling.
-func (r *ScheduledAutoscalerReconciler) updateStatusWithCronID(sa *microservicev1beta1.ScheduledAutoscaler, status microservicev1beta1.ScheduledAutoscalerStatus, reqLogger logr.Logger) error {
+func (r *ScheduledAutoscalerReconciler) updateStatusWithCronID(sa *microservicev1.ScheduledAutoscaler, status microservicev1.ScheduledAutoscalerStatus, reqLogger logr.Logger) error {
 	sa.Status = status
 
 	err := r.Client.Status().Update(context.TODO(), sa)
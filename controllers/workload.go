@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	"github.com/Hunter-Thompson/microservice-operator/pkg/microservice"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// workloadConditions are the conditions that record the result of
+// reconciling the workload of each kind.
+var workloadConditions = map[microservicev1.WorkloadKind]string{
+	microservicev1.WorkloadKindDeployment:  microservicev1.ConditionDeploymentReconciled,
+	microservicev1.WorkloadKindStatefulSet: microservicev1.ConditionStatefulSetReconciled,
+	microservicev1.WorkloadKindDaemonSet:   microservicev1.ConditionDaemonSetReconciled,
+	microservicev1.WorkloadKindCronJob:     microservicev1.ConditionCronJobReconciled,
+	microservicev1.WorkloadKindJob:         microservicev1.ConditionJobReconciled,
+}
+
+// workloadResource is a resource that belongs to the workload of a kind.
+type workloadResource struct {
+	obj  func() client.Object
+	name func(mic *microservicev1.Microservice) string
+}
+
+// workloadResources are the resources that make up the workload of each
+// kind.
+var workloadResources = map[microservicev1.WorkloadKind][]workloadResource{
+	microservicev1.WorkloadKindDeployment: {
+		{obj: func() client.Object { return &appsv1.Deployment{} }, name: microserviceName},
+		{obj: func() client.Object { return &appsv1.Deployment{} }, name: greenDeploymentName},
+	},
+	microservicev1.WorkloadKindStatefulSet: {
+		{obj: func() client.Object { return &appsv1.StatefulSet{} }, name: microserviceName},
+		{obj: func() client.Object { return &corev1.Service{} }, name: microservice.HeadlessServiceName},
+	},
+	microservicev1.WorkloadKindDaemonSet: {
+		{obj: func() client.Object { return &appsv1.DaemonSet{} }, name: microserviceName},
+	},
+	microservicev1.WorkloadKindCronJob: {
+		{obj: func() client.Object { return &batchv1.CronJob{} }, name: microserviceName},
+	},
+	microservicev1.WorkloadKindJob: {
+		{obj: func() client.Object { return &batchv1.Job{} }, name: microserviceName},
+	},
+}
+
+func microserviceName(mic *microservicev1.Microservice) string {
+	return mic.GetName()
+}
+
+func greenDeploymentName(mic *microservicev1.Microservice) string {
+	return microservice.ColorName(mic, microservicev1.BlueGreenGreen)
+}
+
+// checkWorkload creates or updates the workload of the kind selected by the
+// Microservice.
+func (r *MicroserviceReconciler) checkWorkload(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	switch mic.GetWorkloadKind() {
+	case microservicev1.WorkloadKindStatefulSet:
+		return r.checkStatefulSet(mic, status, reqLogger)
+	case microservicev1.WorkloadKindDaemonSet:
+		return r.checkDaemonSet(mic, status, reqLogger)
+	case microservicev1.WorkloadKindCronJob:
+		return r.checkCronJob(mic, status, reqLogger)
+	case microservicev1.WorkloadKindJob:
+		return r.checkJob(mic, status, reqLogger)
+	default:
+		return r.checkDeployment(mic, status, reqLogger)
+	}
+}
+
+// checkWorkloadStatus copies the rollout progress of the workload into the
+// Microservice status and sets the running state from it.
+func (r *MicroserviceReconciler) checkWorkloadStatus(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	if !mic.BatchWorkload() {
+		status.Job = nil
+	}
+
+	switch mic.GetWorkloadKind() {
+	case microservicev1.WorkloadKindStatefulSet:
+		return r.checkStatefulSetStatus(mic, status, reqLogger)
+	case microservicev1.WorkloadKindDaemonSet:
+		return r.checkDaemonSetStatus(mic, status, reqLogger)
+	case microservicev1.WorkloadKindCronJob:
+		return r.checkCronJobStatus(mic, status, reqLogger)
+	case microservicev1.WorkloadKindJob:
+		return r.checkJobStatus(mic, status, reqLogger)
+	default:
+		return r.checkDeploymentStatus(mic, status, reqLogger)
+	}
+}
+
+// retireWorkloads deletes the workloads of the kinds the Microservice no
+// longer uses. It waits until the workload of the current kind is stable, so
+// that the pods of the previous kind keep serving while the new ones start.
+func (r *MicroserviceReconciler) retireWorkloads(mic *microservicev1.Microservice, status *microservicev1.MicroserviceStatus, reqLogger logr.Logger) error {
+	if status.State != microservicev1.Stable {
+		return nil
+	}
+
+	kind := mic.GetWorkloadKind()
+	for workloadKind, resources := range workloadResources {
+		if workloadKind == kind {
+			continue
+		}
+		for _, resource := range resources {
+			err := r.deleteOwned(mic, resource.obj(), resource.name(mic), reqLogger)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for workloadKind, conditionType := range workloadConditions {
+		if workloadKind != kind {
+			meta.RemoveStatusCondition(&status.Conditions, conditionType)
+		}
+	}
+
+	return nil
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -32,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
 	"github.com/Hunter-Thompson/microservice-operator/controllers"
 	//+kubebuilder:scaffold:imports
@@ -46,6 +48,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(microservicev1beta1.AddToScheme(scheme))
+	utilruntime.Must(microservicev1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -53,11 +56,15 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var serverSideApplyKinds string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&serverSideApplyKinds, "server-side-apply", "",
+		"Comma separated list of generated resource kinds, e.g. Deployment,HorizontalPodAutoscaler, "+
+			"that are managed with server-side apply instead of the last applied annotation.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -90,10 +97,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = controllers.NewMicroserviceReconciler(mgr).SetupWithManager(mgr); err != nil {
+	microserviceReconciler := controllers.NewMicroserviceReconciler(mgr)
+	for _, kind := range strings.Split(serverSideApplyKinds, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			microserviceReconciler.Resources.EnableServerSideApply(kind)
+		}
+	}
+	if err = microserviceReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Microservice")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&microservicev1.Microservice{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Microservice")
+			os.Exit(1)
+		}
+	}
 
 	allcron := cron.New()
 	allcron.Start()
@@ -102,6 +121,12 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ScheduledAutoscaler")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&microservicev1.ScheduledAutoscaler{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ScheduledAutoscaler")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
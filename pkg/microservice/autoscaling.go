@@ -2,19 +2,21 @@ package microservice
 
 import (
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 )
 
-func GenerateAutoscalingv2(mic *microservicev1beta1.Microservice) *autoscalingv2.HorizontalPodAutoscaler {
-	if mic.Spec.Autoscaling == nil {
+func GenerateAutoscalingv2(mic *microservicev1.Microservice) *autoscalingv2.HorizontalPodAutoscaler {
+	spec := AutoscalingSpec(mic)
+	if spec == nil {
 		return nil
 	}
-	return newAutoscalingv2(mic)
+	return newAutoscalingv2(mic, spec)
 }
 
-func newAutoscalingv2(mic *microservicev1beta1.Microservice) *autoscalingv2.HorizontalPodAutoscaler {
+func newAutoscalingv2(mic *microservicev1.Microservice, spec *autoscalingv2.HorizontalPodAutoscalerSpec) *autoscalingv2.HorizontalPodAutoscaler {
 	return &autoscalingv2.HorizontalPodAutoscaler{
 		ObjectMeta: v1.ObjectMeta{
 			Name:            mic.Name,
@@ -23,6 +25,127 @@ func newAutoscalingv2(mic *microservicev1beta1.Microservice) *autoscalingv2.Hori
 			Labels:          mic.Spec.Labels,
 			Annotations:     mic.GetAnnotations(),
 		},
-		Spec: *mic.Spec.Autoscaling,
+		Spec: *spec,
 	}
 }
+
+// AutoscalingSpec returns the HorizontalPodAutoscaler spec of the
+// Microservice, either the raw autoscaling spec or the one translated from
+// the scaling block, targeting the Deployment of the Microservice. It returns
+// nil if autoscaling is disabled.
+func AutoscalingSpec(mic *microservicev1.Microservice) *autoscalingv2.HorizontalPodAutoscalerSpec {
+	var spec *autoscalingv2.HorizontalPodAutoscalerSpec
+	switch {
+	case mic.Spec.Autoscaling != nil:
+		spec = mic.Spec.Autoscaling.DeepCopy()
+	case mic.Spec.Scaling != nil:
+		spec = translateScaling(mic.Spec.Scaling)
+	default:
+		return nil
+	}
+
+	spec.ScaleTargetRef = mic.ScaleTargetRef()
+
+	return spec
+}
+
+func translateScaling(scaling *microservicev1.ScalingSpec) *autoscalingv2.HorizontalPodAutoscalerSpec {
+	spec := &autoscalingv2.HorizontalPodAutoscalerSpec{
+		MinReplicas: scaling.MinReplicas,
+		MaxReplicas: scaling.MaxReplicas,
+	}
+
+	if scaling.TargetCPUUtilizationPercentage != nil {
+		spec.Metrics = append(spec.Metrics, resourceUtilizationMetric(corev1.ResourceCPU, *scaling.TargetCPUUtilizationPercentage))
+	}
+	if scaling.TargetMemoryUtilizationPercentage != nil {
+		spec.Metrics = append(spec.Metrics, resourceUtilizationMetric(corev1.ResourceMemory, *scaling.TargetMemoryUtilizationPercentage))
+	}
+
+	scaleUp := scaleUpRules(scaling.ScaleUp)
+	scaleDown := scaleDownRules(scaling.ScaleDown)
+	if scaleUp != nil || scaleDown != nil {
+		spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleUp:   scaleUp,
+			ScaleDown: scaleDown,
+		}
+	}
+
+	return spec
+}
+
+func resourceUtilizationMetric(name corev1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
+		},
+	}
+}
+
+// scaleUpRules returns the scale up rules of a preset. The default rules of
+// the HorizontalPodAutoscaler already add replicas without stabilization, so
+// Fast is the same as Default.
+func scaleUpRules(behavior microservicev1.ScalingBehavior) *autoscalingv2.HPAScalingRules {
+	switch behavior {
+	case microservicev1.ScalingBehaviorConservative:
+		return scalingRules(scaleConservativeStabilizationSeconds, autoscalingv2.PodsScalingPolicy, 1, scaleConservativePeriodSeconds)
+	case microservicev1.ScalingBehaviorDisabled:
+		return disabledScalingRules()
+	default:
+		return nil
+	}
+}
+
+// scaleDownRules returns the scale down rules of a preset.
+func scaleDownRules(behavior microservicev1.ScalingBehavior) *autoscalingv2.HPAScalingRules {
+	switch behavior {
+	case microservicev1.ScalingBehaviorFast:
+		return scalingRules(0, autoscalingv2.PercentScalingPolicy, 100, scaleFastPeriodSeconds)
+	case microservicev1.ScalingBehaviorConservative:
+		return scalingRules(scaleDownConservativeStabilizationSeconds, autoscalingv2.PodsScalingPolicy, 1, scaleConservativePeriodSeconds)
+	case microservicev1.ScalingBehaviorDisabled:
+		return disabledScalingRules()
+	default:
+		return nil
+	}
+}
+
+func scalingRules(stabilizationSeconds int32, policyType autoscalingv2.HPAScalingPolicyType, value, periodSeconds int32) *autoscalingv2.HPAScalingRules {
+	return &autoscalingv2.HPAScalingRules{
+		StabilizationWindowSeconds: &stabilizationSeconds,
+		Policies: []autoscalingv2.HPAScalingPolicy{
+			{Type: policyType, Value: value, PeriodSeconds: periodSeconds},
+		},
+	}
+}
+
+func disabledScalingRules() *autoscalingv2.HPAScalingRules {
+	selectPolicy := autoscalingv2.DisabledPolicySelect
+	return &autoscalingv2.HPAScalingRules{
+		SelectPolicy: &selectPolicy,
+	}
+}
+
+// ReplicasWithinAutoscalingBounds limits replicas to the minimum and maximum
+// number of replicas of the HorizontalPodAutoscaler spec. The minimum
+// defaults to 1 like it does for the HorizontalPodAutoscaler.
+func ReplicasWithinAutoscalingBounds(replicas int32, spec autoscalingv2.HorizontalPodAutoscalerSpec) int32 {
+	minReplicas := int32(1)
+	if spec.MinReplicas != nil {
+		minReplicas = *spec.MinReplicas
+	}
+
+	if replicas < minReplicas {
+		return minReplicas
+	}
+	if replicas > spec.MaxReplicas {
+		return spec.MaxReplicas
+	}
+
+	return replicas
+}
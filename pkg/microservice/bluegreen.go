@@ -0,0 +1,67 @@
+package microservice
+
+import (
+	"time"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PreviewName returns the name of the Service that selects the pods of the
+// blue/green preview of a Microservice.
+func PreviewName(deployment *microservicev1.Microservice) string {
+	return deployment.GetName() + "-preview"
+}
+
+// ColorName returns the name of the Deployment of a blue/green color. The
+// blue Deployment is the Deployment of the Microservice.
+func ColorName(deployment *microservicev1.Microservice, color microservicev1.BlueGreenColor) string {
+	if color == microservicev1.BlueGreenGreen {
+		return deployment.GetName() + "-green"
+	}
+
+	return deployment.GetName()
+}
+
+// ColorLabels returns the labels of the pods of the Deployment of a
+// blue/green color.
+func ColorLabels(deployment *microservicev1.Microservice, color microservicev1.BlueGreenColor) map[string]string {
+	return TrackLabels(deployment, colorTrack(color))
+}
+
+func colorTrack(color microservicev1.BlueGreenColor) string {
+	if color == microservicev1.BlueGreenGreen {
+		return microservicev1.TrackGreen
+	}
+
+	return microservicev1.TrackStable
+}
+
+// GenerateColorDeployment returns the Deployment of a blue/green color. Both
+// are generated from the spec and only differ in their name and the track
+// label of their pods.
+func GenerateColorDeployment(deployment *microservicev1.Microservice, color microservicev1.BlueGreenColor) *appsv1.Deployment {
+	if color != microservicev1.BlueGreenGreen {
+		return GenerateDeployment(deployment)
+	}
+
+	return generateTrackDeployment(deployment, ColorName(deployment, color), microservicev1.TrackGreen, deployment.Spec.Image, desiredReplicas(deployment))
+}
+
+// GeneratePreviewServiceV1 returns the Service that selects the pods of the
+// Deployment of the given color, which runs the preview.
+func GeneratePreviewServiceV1(deployment *microservicev1.Microservice, color microservicev1.BlueGreenColor) *corev1.Service {
+	return generateTrackServiceV1(deployment, PreviewName(deployment), colorTrack(color))
+}
+
+// ScaleDownDelay returns how long the previously active Deployment keeps
+// running after the Service switched away from it during a blue/green
+// rollout.
+func ScaleDownDelay(deployment *microservicev1.Microservice) time.Duration {
+	if strategy := deployment.Spec.Strategy; strategy != nil && strategy.BlueGreen != nil && strategy.BlueGreen.ScaleDownDelay != nil {
+		return strategy.BlueGreen.ScaleDownDelay.Duration
+	}
+
+	return defaultScaleDownDelay
+}
@@ -0,0 +1,30 @@
+package microservice
+
+import (
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CanaryName returns the name of the canary Deployment and Service of a
+// Microservice.
+func CanaryName(deployment *microservicev1.Microservice) string {
+	return deployment.GetName() + "-canary"
+}
+
+// GenerateCanaryDeployment returns the Deployment that runs image next to the
+// Deployment of the Microservice during a canary rollout.
+func GenerateCanaryDeployment(deployment *microservicev1.Microservice, image string) *appsv1.Deployment {
+	replicas := int32(1)
+	if deployment.Spec.Canary != nil && deployment.Spec.Canary.Replicas != nil {
+		replicas = *deployment.Spec.Canary.Replicas
+	}
+
+	return generateTrackDeployment(deployment, CanaryName(deployment), microservicev1.TrackCanary, image, replicas)
+}
+
+// GenerateCanaryServiceV1 returns the Service that selects the pods of the
+// canary Deployment of the Microservice.
+func GenerateCanaryServiceV1(deployment *microservicev1.Microservice) *corev1.Service {
+	return generateTrackServiceV1(deployment, CanaryName(deployment), microservicev1.TrackCanary)
+}
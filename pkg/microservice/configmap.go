@@ -0,0 +1,72 @@
+package microservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConfigMapName returns the name of the ConfigMap of the config files of a
+// Microservice.
+func ConfigMapName(deployment *microservicev1.Microservice) string {
+	return deployment.GetName() + "-config"
+}
+
+// GenerateConfigMap returns the ConfigMap of the config files of the
+// Microservice, or nil if it has none.
+func GenerateConfigMap(deployment *microservicev1.Microservice) *corev1.ConfigMap {
+	if len(deployment.Spec.ConfigFiles) == 0 {
+		return nil
+	}
+
+	data := map[string]string{}
+	for name, content := range deployment.Spec.ConfigFiles {
+		data[name] = content
+	}
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ConfigMapName(deployment),
+			Namespace:       deployment.Namespace,
+			OwnerReferences: DeploymentOwnerReference(deployment),
+			Labels:          deployment.Spec.Labels,
+		},
+		Data: data,
+	}
+}
+
+// ConfigHash returns a hash of the config files of the Microservice, which
+// changes whenever a file is added, removed or edited.
+func ConfigHash(deployment *microservicev1.Microservice) string {
+	names := make([]string, 0, len(deployment.Spec.ConfigFiles))
+	for name := range deployment.Spec.ConfigFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	for _, name := range names {
+		// names cannot contain a NUL byte, so it separates name and content
+		// unambiguously
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write([]byte(deployment.Spec.ConfigFiles[name]))
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// configFilesMountPath returns the directory the config files of the
+// Microservice are mounted at.
+func configFilesMountPath(deployment *microservicev1.Microservice) string {
+	if deployment.Spec.ConfigFilesMountPath != "" {
+		return deployment.Spec.ConfigFilesMountPath
+	}
+
+	return defaultConfigFilesMountPath
+}
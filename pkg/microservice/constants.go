@@ -1,5 +1,7 @@
 package microservice
 
+import "time"
+
 const (
 	// sizeMB is the number of bytes that make a megabyte
 	sizeMB = 1048576
@@ -9,6 +11,13 @@ const (
 	// that will be used unless nginx annotation is set
 	defaultMaxFileSize = 1000
 
+	// nginxCanaryAnnotation marks an Ingress as the canary of the Ingress
+	// with the same host and path for ingress-nginx.
+	nginxCanaryAnnotation = "nginx.ingress.kubernetes.io/canary"
+	// nginxCanaryWeightAnnotation is the percentage of the requests that
+	// ingress-nginx routes to a canary Ingress.
+	nginxCanaryWeightAnnotation = "nginx.ingress.kubernetes.io/canary-weight"
+
 	// defaultRevHistoryLimit is the default RevisionHistoryLimit - number of
 	// possible roll-back points.
 	// More details:
@@ -28,4 +37,39 @@ const (
 	// Recommended not to be too high in order to have not too many extra pods
 	// over requested `Replicas` number.
 	defaultMaxSurge = 1
+	// defaultDaemonSetMaxUnavailable is the default number of nodes whose
+	// pod is replaced at a time during an update of a DaemonSet.
+	defaultDaemonSetMaxUnavailable = 1
+	// defaultPreDeployBackoffLimit is the default number of retries of a
+	// failed pre-deploy hook. Migrations are not retried unless asked to.
+	defaultPreDeployBackoffLimit = 0
+	// defaultSuccessfulJobsHistoryLimit is the default number of successful
+	// Jobs a CronJob keeps.
+	defaultSuccessfulJobsHistoryLimit = 3
+	// defaultFailedJobsHistoryLimit is the default number of failed Jobs a
+	// CronJob keeps.
+	defaultFailedJobsHistoryLimit = 1
+	// defaultScaleDownDelay is how long pods keep running after the Service
+	// switched away from them during a blue/green rollout, so that requests
+	// in flight can complete.
+	defaultScaleDownDelay = 30 * time.Second
+
+	// defaultConfigFilesMountPath is the directory the config files of a
+	// Microservice are mounted at unless configured otherwise.
+	defaultConfigFilesMountPath = "/etc/config"
+
+	// scaleFastPeriodSeconds is the period of the scaling policies of the
+	// Fast scaling behavior.
+	scaleFastPeriodSeconds = 15
+	// scaleConservativePeriodSeconds is the period of the scaling policies
+	// of the Conservative scaling behavior, which change one replica per
+	// period.
+	scaleConservativePeriodSeconds = 60
+	// scaleConservativeStabilizationSeconds is the stabilization window of
+	// the Conservative scale up behavior.
+	scaleConservativeStabilizationSeconds = 120
+	// scaleDownConservativeStabilizationSeconds is the stabilization window
+	// of the Conservative scale down behavior. The default of the
+	// HorizontalPodAutoscaler is 300 seconds.
+	scaleDownConservativeStabilizationSeconds = 600
 )
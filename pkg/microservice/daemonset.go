@@ -0,0 +1,51 @@
+package microservice
+
+import (
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// GenerateDaemonSet returns the DaemonSet of a Microservice with the
+// DaemonSet workload kind. It runs the same pods as the Deployment would, one
+// on each node selected by the node selector and tolerations.
+func GenerateDaemonSet(deployment *microservicev1.Microservice) *appsv1.DaemonSet {
+	maxUnavailable := intstr.FromInt(defaultDaemonSetMaxUnavailable)
+	if deployment.Spec.DaemonSet != nil && deployment.Spec.DaemonSet.MaxUnavailable != nil {
+		maxUnavailable = *deployment.Spec.DaemonSet.MaxUnavailable
+	}
+
+	strategy := microservicev1.StrategySpec{}
+	if deployment.Spec.Strategy != nil {
+		strategy = *deployment.Spec.Strategy
+	}
+	revHistoryLimit := int32(defaultRevHistoryLimit)
+	if strategy.RevisionHistoryLimit != nil {
+		revHistoryLimit = *strategy.RevisionHistoryLimit
+	}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment.Name,
+			Namespace:       deployment.Namespace,
+			OwnerReferences: DeploymentOwnerReference(deployment),
+			Labels:          deployment.Spec.Labels,
+			Annotations:     deployment.GetAnnotations(),
+		},
+		Spec: appsv1.DaemonSetSpec{
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+					MaxUnavailable: &maxUnavailable,
+				},
+			},
+			MinReadySeconds:      strategy.MinReadySeconds,
+			RevisionHistoryLimit: &revHistoryLimit,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: deployment.Spec.Labels,
+			},
+			Template: generatePodTemplate(deployment),
+		},
+	}
+}
@@ -0,0 +1,97 @@
+package microservice
+
+import (
+	"sort"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Dependencies returns the Secrets and ConfigMaps that the env and volumes of
+// the Microservice reference, sorted by kind and name, leaving out those
+// whose changes are ignored.
+func Dependencies(deployment *microservicev1.Microservice) []microservicev1.DependencyReference {
+	found := map[microservicev1.DependencyReference]bool{}
+	add := func(kind microservicev1.DependencyKind, name string) {
+		if name != "" {
+			found[microservicev1.DependencyReference{Kind: kind, Name: name}] = true
+		}
+	}
+	addEnv := func(env []corev1.EnvVar, envFrom []corev1.EnvFromSource) {
+		for _, v := range env {
+			if v.ValueFrom == nil {
+				continue
+			}
+			if v.ValueFrom.SecretKeyRef != nil {
+				add(microservicev1.DependencyKindSecret, v.ValueFrom.SecretKeyRef.Name)
+			}
+			if v.ValueFrom.ConfigMapKeyRef != nil {
+				add(microservicev1.DependencyKindConfigMap, v.ValueFrom.ConfigMapKeyRef.Name)
+			}
+		}
+		for _, source := range envFrom {
+			if source.SecretRef != nil {
+				add(microservicev1.DependencyKindSecret, source.SecretRef.Name)
+			}
+			if source.ConfigMapRef != nil {
+				add(microservicev1.DependencyKindConfigMap, source.ConfigMapRef.Name)
+			}
+		}
+	}
+
+	spec := deployment.Spec
+	addEnv(spec.EnvVars, spec.EnvFrom)
+	for _, container := range append(append([]microservicev1.Container{}, spec.Sidecars...), spec.InitContainers...) {
+		addEnv(container.Env, container.EnvFrom)
+	}
+
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil {
+			add(microservicev1.DependencyKindSecret, volume.Secret.SecretName)
+		}
+		if volume.ConfigMap != nil {
+			add(microservicev1.DependencyKindConfigMap, volume.ConfigMap.Name)
+		}
+		if volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			if source.Secret != nil {
+				add(microservicev1.DependencyKindSecret, source.Secret.Name)
+			}
+			if source.ConfigMap != nil {
+				add(microservicev1.DependencyKindConfigMap, source.ConfigMap.Name)
+			}
+		}
+	}
+
+	for _, ignored := range spec.IgnoreChanges {
+		delete(found, ignored)
+	}
+
+	dependencies := make([]microservicev1.DependencyReference, 0, len(found))
+	for dependency := range found {
+		dependencies = append(dependencies, dependency)
+	}
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].Kind != dependencies[j].Kind {
+			return dependencies[i].Kind < dependencies[j].Kind
+		}
+		return dependencies[i].Name < dependencies[j].Name
+	})
+
+	return dependencies
+}
+
+// DependencyNames returns the names of the dependencies of the Microservice
+// of one kind.
+func DependencyNames(deployment *microservicev1.Microservice, kind microservicev1.DependencyKind) []string {
+	names := []string{}
+	for _, dependency := range Dependencies(deployment) {
+		if dependency.Kind == kind {
+			names = append(names, dependency.Name)
+		}
+	}
+
+	return names
+}
@@ -1,20 +1,49 @@
 package microservice
 
 import (
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	"sort"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func GenerateDeployment(deployment *microservicev1beta1.Microservice) *appsv1.Deployment {
+func GenerateDeployment(deployment *microservicev1.Microservice) *appsv1.Deployment {
 	desired := newDeployment(deployment)
 
 	return configureDeployment(deployment, desired)
 }
 
-func newDeployment(deployment *microservicev1beta1.Microservice) *appsv1.Deployment {
+// generateTrackDeployment returns a Deployment with the given name that runs
+// image on one track of the Microservice, next to its Deployment.
+func generateTrackDeployment(micdeployment *microservicev1.Microservice, name, track, image string, replicas int32) *appsv1.Deployment {
+	deployment := GenerateDeployment(micdeployment)
+	labels := TrackLabels(micdeployment, track)
+
+	deployment.Name = name
+	deployment.Labels = labels
+	deployment.Spec.Replicas = &replicas
+	deployment.Spec.Selector = &metav1.LabelSelector{
+		MatchLabels: labels,
+	}
+	deployment.Spec.Template.Labels = labels
+	SetContainerImage(&deployment.Spec.Template, micdeployment.GetName(), image)
+
+	return deployment
+}
+
+// SetContainerImage sets the image of the container with the given name.
+func SetContainerImage(template *v1.PodTemplateSpec, name, image string) {
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == name {
+			template.Spec.Containers[i].Image = image
+		}
+	}
+}
+
+func newDeployment(deployment *microservicev1.Microservice) *appsv1.Deployment {
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            deployment.Name,
@@ -26,61 +55,202 @@ func newDeployment(deployment *microservicev1beta1.Microservice) *appsv1.Deploym
 	}
 }
 
-func configureDeployment(micdeployment *microservicev1beta1.Microservice, deployment *appsv1.Deployment) *appsv1.Deployment {
+func configureDeployment(micdeployment *microservicev1.Microservice, deployment *appsv1.Deployment) *appsv1.Deployment {
+	replicas := desiredReplicas(micdeployment)
+
+	strategy := microservicev1.StrategySpec{}
+	if micdeployment.Spec.Strategy != nil {
+		strategy = *micdeployment.Spec.Strategy
+	}
 	revHistoryLimit := int32(defaultRevHistoryLimit)
-	maxUnavailable := intstr.FromInt(defaultMaxUnavailable)
-	maxSurge := intstr.FromInt(defaultMaxSurge)
+	if strategy.RevisionHistoryLimit != nil {
+		revHistoryLimit = *strategy.RevisionHistoryLimit
+	}
+
+	deployment.Spec = appsv1.DeploymentSpec{
+		Strategy:                deploymentStrategy(strategy),
+		MinReadySeconds:         strategy.MinReadySeconds,
+		ProgressDeadlineSeconds: strategy.ProgressDeadlineSeconds,
+		RevisionHistoryLimit:    &revHistoryLimit,
+		Replicas:                &replicas,
+		Selector: &metav1.LabelSelector{
+			MatchLabels: PodLabels(micdeployment),
+		},
+		Template: generatePodTemplate(micdeployment),
+	}
+
+	return deployment
+}
+
+// desiredReplicas returns the number of replicas of the workload of the
+// Microservice, within the bounds of its autoscaling.
+func desiredReplicas(micdeployment *microservicev1.Microservice) int32 {
+	replicas := microservicev1.DefaultReplicas
+	if micdeployment.Spec.Replicas != nil {
+		replicas = *micdeployment.Spec.Replicas
+	}
+	if autoscaling := AutoscalingSpec(micdeployment); autoscaling != nil {
+		replicas = ReplicasWithinAutoscalingBounds(replicas, *autoscaling)
+	}
+
+	return replicas
+}
+
+// generatePodTemplate returns the pod template of the workload of the
+// Microservice.
+func generatePodTemplate(micdeployment *microservicev1.Microservice) v1.PodTemplateSpec {
+	// map order is random, so variables are sorted to render the same
+	// Deployment every time
+	envNames := make([]string, 0, len(micdeployment.Spec.Env))
+	for envName := range micdeployment.Spec.Env {
+		envNames = append(envNames, envName)
+	}
+	sort.Strings(envNames)
 
 	envVar := []v1.EnvVar{}
-	for envName, env := range micdeployment.Spec.Env {
+	for _, envName := range envNames {
 		envVar = append(envVar, v1.EnvVar{
 			Name:  envName,
-			Value: env,
+			Value: micdeployment.Spec.Env[envName],
 		})
 	}
+	envVar = append(envVar, micdeployment.Spec.EnvVars...)
 
 	ports := []v1.ContainerPort{}
 	for _, ingress := range micdeployment.Spec.Ingress {
+		// ports of sidecars are declared by the sidecar
+		if ingress.Container != "" {
+			continue
+		}
 		ports = append(ports, v1.ContainerPort{
 			Name:          ingress.Name,
 			ContainerPort: ingress.ContainerPort,
 		})
 	}
 
-	deployment.Spec = appsv1.DeploymentSpec{
-		Strategy: appsv1.DeploymentStrategy{
-			Type: appsv1.RollingUpdateDeploymentStrategyType,
-			RollingUpdate: &appsv1.RollingUpdateDeployment{
-				MaxUnavailable: &maxUnavailable,
-				MaxSurge:       &maxSurge,
-			},
-		},
-		RevisionHistoryLimit: &revHistoryLimit,
-		Replicas:             &micdeployment.Spec.Replicas,
-		Selector: &metav1.LabelSelector{
-			MatchLabels: micdeployment.Spec.Labels,
+	containers := []v1.Container{
+		{
+			Name:           micdeployment.Name,
+			Image:          micdeployment.Spec.Image,
+			Resources:      micdeployment.Spec.Resources,
+			Env:            envVar,
+			EnvFrom:        micdeployment.Spec.EnvFrom,
+			VolumeMounts:   micdeployment.Spec.VolumeMounts,
+			Ports:          ports,
+			LivenessProbe:  micdeployment.Spec.LivenessProbe,
+			ReadinessProbe: micdeployment.Spec.ReadinessProbe,
 		},
-		Template: v1.PodTemplateSpec{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels:      micdeployment.Spec.Labels,
-				Annotations: micdeployment.Spec.PodAnnotations,
-			},
-			Spec: v1.PodSpec{
-				ServiceAccountName: micdeployment.Name,
-				Tolerations:        micdeployment.Spec.Tolerations,
-				NodeSelector:       micdeployment.Spec.NodeSelector,
-				Containers: []v1.Container{
-					{
-						Name:      micdeployment.Name,
-						Image:     micdeployment.Spec.Image,
-						Resources: micdeployment.Spec.Resources,
-						Env:       envVar,
-						Ports:     ports,
-					},
+	}
+	for _, sidecar := range micdeployment.Spec.Sidecars {
+		containers = append(containers, generateContainer(sidecar))
+	}
+
+	var volumes []v1.Volume
+	for _, volume := range micdeployment.Spec.Volumes {
+		volumes = append(volumes, generateVolume(volume))
+	}
+
+	// the hash of the config files restarts the pods when a file changes,
+	// which they would otherwise only see with a delay
+	podAnnotations := micdeployment.Spec.PodAnnotations
+	if configMap := GenerateConfigMap(micdeployment); configMap != nil {
+		volumes = append(volumes, v1.Volume{
+			Name: microservicev1.ConfigFilesVolume,
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: configMap.Name},
 				},
 			},
+		})
+		mounts := append([]v1.VolumeMount{}, micdeployment.Spec.VolumeMounts...)
+		containers[0].VolumeMounts = append(mounts, v1.VolumeMount{
+			Name:      microservicev1.ConfigFilesVolume,
+			MountPath: configFilesMountPath(micdeployment),
+			ReadOnly:  true,
+		})
+
+		podAnnotations = map[string]string{}
+		for key, value := range micdeployment.Spec.PodAnnotations {
+			podAnnotations[key] = value
+		}
+		podAnnotations[microservicev1.ConfigHashAnnotation] = ConfigHash(micdeployment)
+	}
+
+	var initContainers []v1.Container
+	for _, initContainer := range micdeployment.Spec.InitContainers {
+		initContainers = append(initContainers, generateContainer(initContainer))
+	}
+
+	return v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      PodLabels(micdeployment),
+			Annotations: podAnnotations,
+		},
+		Spec: v1.PodSpec{
+			ServiceAccountName: micdeployment.Name,
+			Tolerations:        micdeployment.Spec.Tolerations,
+			NodeSelector:       micdeployment.Spec.NodeSelector,
+			InitContainers:     initContainers,
+			Containers:         containers,
+			Volumes:            volumes,
 		},
 	}
+}
 
-	return deployment
+// generateContainer returns the pod container of a sidecar or init container.
+func generateContainer(container microservicev1.Container) v1.Container {
+	return v1.Container{
+		Name:           container.Name,
+		Image:          container.Image,
+		Command:        container.Command,
+		Args:           container.Args,
+		Env:            container.Env,
+		EnvFrom:        container.EnvFrom,
+		VolumeMounts:   container.VolumeMounts,
+		Ports:          container.Ports,
+		LivenessProbe:  container.LivenessProbe,
+		ReadinessProbe: container.ReadinessProbe,
+		Resources:      container.Resources,
+	}
+}
+
+// generateVolume returns the pod volume of a volume of the Microservice.
+func generateVolume(volume microservicev1.Volume) v1.Volume {
+	return v1.Volume{
+		Name: volume.Name,
+		VolumeSource: v1.VolumeSource{
+			ConfigMap:             volume.ConfigMap,
+			Secret:                volume.Secret,
+			EmptyDir:              volume.EmptyDir,
+			Projected:             volume.Projected,
+			PersistentVolumeClaim: volume.PersistentVolumeClaim,
+		},
+	}
+}
+
+// deploymentStrategy returns the Deployment strategy of a Microservice
+// strategy, falling back to the default surge and unavailability limits.
+func deploymentStrategy(strategy microservicev1.StrategySpec) appsv1.DeploymentStrategy {
+	if strategy.Type == microservicev1.RecreateStrategyType {
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+
+	maxUnavailable := intstr.FromInt(defaultMaxUnavailable)
+	maxSurge := intstr.FromInt(defaultMaxSurge)
+	if rollingUpdate := strategy.RollingUpdate; rollingUpdate != nil {
+		if rollingUpdate.MaxUnavailable != nil {
+			maxUnavailable = *rollingUpdate.MaxUnavailable
+		}
+		if rollingUpdate.MaxSurge != nil {
+			maxSurge = *rollingUpdate.MaxSurge
+		}
+	}
+
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: &maxUnavailable,
+			MaxSurge:       &maxSurge,
+		},
+	}
 }
@@ -2,13 +2,14 @@ package microservice
 
 import (
 	"fmt"
+	"strconv"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func GenerateIngressesV1(deployment *microservicev1beta1.Microservice) []*networking.Ingress {
+func GenerateIngressesV1(deployment *microservicev1.Microservice) []*networking.Ingress {
 	ingresses := []*networking.Ingress{}
 	for _, ing := range deployment.Spec.Ingress {
 		ingName := fmt.Sprintf("%s-%s", deployment.GetName(), ing.Name)
@@ -18,7 +19,38 @@ func GenerateIngressesV1(deployment *microservicev1beta1.Microservice) []*networ
 	return ingresses
 }
 
-func newNetworkingV1Ingress(deployment *microservicev1beta1.Microservice, name string, annotations map[string]string) *networking.Ingress {
+// GenerateCanaryIngressesV1 returns an ingress-nginx canary Ingress for every
+// Ingress of the Microservice, routing weight percent of its requests to the
+// canary Service.
+func GenerateCanaryIngressesV1(deployment *microservicev1.Microservice, weight int32) []*networking.Ingress {
+	ingresses := []*networking.Ingress{}
+	for _, ing := range deployment.Spec.Ingress {
+		annotations := map[string]string{}
+		for key, value := range ing.Annotations {
+			annotations[key] = value
+		}
+		annotations[nginxCanaryAnnotation] = "true"
+		annotations[nginxCanaryWeightAnnotation] = strconv.Itoa(int(weight))
+
+		ingress := configureIngressRules(deployment, &ing, newNetworkingV1Ingress(deployment, CanaryIngressName(deployment, ing.Name), annotations))
+		for _, rule := range ingress.Spec.Rules {
+			for i := range rule.HTTP.Paths {
+				rule.HTTP.Paths[i].Backend.Service.Name = CanaryName(deployment)
+			}
+		}
+		ingresses = append(ingresses, ingress)
+	}
+
+	return ingresses
+}
+
+// CanaryIngressName returns the name of the canary Ingress of an Ingress of
+// the Microservice.
+func CanaryIngressName(deployment *microservicev1.Microservice, ingressName string) string {
+	return fmt.Sprintf("%s-%s-canary", deployment.GetName(), ingressName)
+}
+
+func newNetworkingV1Ingress(deployment *microservicev1.Microservice, name string, annotations map[string]string) *networking.Ingress {
 	return &networking.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            name,
@@ -30,7 +62,7 @@ func newNetworkingV1Ingress(deployment *microservicev1beta1.Microservice, name s
 	}
 }
 
-func configureIngressRules(deployment *microservicev1beta1.Microservice, ing *microservicev1beta1.Ingress, ingress *networking.Ingress) *networking.Ingress {
+func configureIngressRules(deployment *microservicev1.Microservice, ing *microservicev1.Ingress, ingress *networking.Ingress) *networking.Ingress {
 	paths := []networking.HTTPIngressPath{}
 	pathType := networking.PathTypeImplementationSpecific
 
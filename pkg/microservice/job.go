@@ -0,0 +1,91 @@
+package microservice
+
+import (
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GenerateJob returns the Job of a Microservice with the Job workload kind.
+func GenerateJob(deployment *microservicev1.Microservice) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment.Name,
+			Namespace:       deployment.Namespace,
+			OwnerReferences: DeploymentOwnerReference(deployment),
+			Labels:          deployment.Spec.Labels,
+			Annotations:     deployment.GetAnnotations(),
+		},
+		Spec: generateJobSpec(deployment),
+	}
+}
+
+// GenerateCronJob returns the CronJob of a Microservice with the CronJob
+// workload kind.
+func GenerateCronJob(deployment *microservicev1.Microservice) *batchv1.CronJob {
+	cronJobSpec := microservicev1.CronJobSpec{}
+	if deployment.Spec.CronJob != nil {
+		cronJobSpec = *deployment.Spec.CronJob
+	}
+	concurrencyPolicy := cronJobSpec.ConcurrencyPolicy
+	if concurrencyPolicy == "" {
+		concurrencyPolicy = batchv1.AllowConcurrent
+	}
+	successfulJobsHistoryLimit := int32(defaultSuccessfulJobsHistoryLimit)
+	if cronJobSpec.SuccessfulJobsHistoryLimit != nil {
+		successfulJobsHistoryLimit = *cronJobSpec.SuccessfulJobsHistoryLimit
+	}
+	failedJobsHistoryLimit := int32(defaultFailedJobsHistoryLimit)
+	if cronJobSpec.FailedJobsHistoryLimit != nil {
+		failedJobsHistoryLimit = *cronJobSpec.FailedJobsHistoryLimit
+	}
+	suspend := cronJobSpec.Suspend
+
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment.Name,
+			Namespace:       deployment.Namespace,
+			OwnerReferences: DeploymentOwnerReference(deployment),
+			Labels:          deployment.Spec.Labels,
+			Annotations:     deployment.GetAnnotations(),
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   cronJobSpec.Schedule,
+			ConcurrencyPolicy:          concurrencyPolicy,
+			Suspend:                    &suspend,
+			StartingDeadlineSeconds:    cronJobSpec.StartingDeadlineSeconds,
+			SuccessfulJobsHistoryLimit: &successfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     &failedJobsHistoryLimit,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: deployment.Spec.Labels,
+				},
+				Spec: generateJobSpec(deployment),
+			},
+		},
+	}
+}
+
+// generateJobSpec returns the spec of the Job, or of the Jobs of the
+// CronJob, of the Microservice. Its pods are the same as those of the
+// Deployment, but run to completion.
+func generateJobSpec(deployment *microservicev1.Microservice) batchv1.JobSpec {
+	jobSpec := microservicev1.JobSpec{}
+	if deployment.Spec.Job != nil {
+		jobSpec = *deployment.Spec.Job
+	}
+	restartPolicy := jobSpec.RestartPolicy
+	if restartPolicy == "" {
+		restartPolicy = corev1.RestartPolicyOnFailure
+	}
+
+	template := generatePodTemplate(deployment)
+	template.Spec.RestartPolicy = restartPolicy
+
+	return batchv1.JobSpec{
+		BackoffLimit:          jobSpec.BackoffLimit,
+		ActiveDeadlineSeconds: jobSpec.ActiveDeadlineSeconds,
+		Template:              template,
+	}
+}
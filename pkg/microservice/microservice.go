@@ -1,17 +1,41 @@
 package microservice
 
 import (
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
-func DeploymentOwnerReference(deployment *microservicev1beta1.Microservice) []metav1.OwnerReference {
+func DeploymentOwnerReference(deployment *microservicev1.Microservice) []metav1.OwnerReference {
 	return []metav1.OwnerReference{
 		*metav1.NewControllerRef(deployment, schema.GroupVersionKind{
-			Group:   microservicev1beta1.GroupVersion.Group,
-			Version: microservicev1beta1.GroupVersion.Version,
+			Group:   microservicev1.GroupVersion.Group,
+			Version: microservicev1.GroupVersion.Version,
 			Kind:    "Microservice",
 		}),
 	}
 }
+
+// PodLabels returns the labels of the pods of the workload of the
+// Microservice. The pods of a Deployment always carry the stable track label,
+// so that its selector does not match the pods of the canary or preview
+// Deployment, and enabling either does not roll out its pods again.
+func PodLabels(deployment *microservicev1.Microservice) map[string]string {
+	if deployment.GetWorkloadKind() != microservicev1.WorkloadKindDeployment {
+		return deployment.Spec.Labels
+	}
+
+	return TrackLabels(deployment, microservicev1.TrackStable)
+}
+
+// TrackLabels returns the labels of the Microservice with the track label
+// set to track.
+func TrackLabels(deployment *microservicev1.Microservice, track string) map[string]string {
+	labels := make(map[string]string, len(deployment.Spec.Labels)+1)
+	for k, v := range deployment.Spec.Labels {
+		labels[k] = v
+	}
+	labels[microservicev1.TrackLabel] = track
+
+	return labels
+}
@@ -3,13 +3,17 @@ package microservice
 import (
 	"fmt"
 	"testing"
+	"time"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
 	v2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -21,6 +25,10 @@ func TestGenerate(t *testing.T) {
 	labels := map[string]string{
 		"app": "test",
 	}
+	podLabels := map[string]string{
+		"app":                     "test",
+		microservicev1.TrackLabel: microservicev1.TrackStable,
+	}
 	svcName1 := "test-1"
 	svcPort1 := 8080
 	ingHost1 := "dsa.example.com"
@@ -52,7 +60,7 @@ func TestGenerate(t *testing.T) {
 		"test": "env",
 	}
 
-	ms := &microservicev1beta1.Microservice{
+	ms := &microservicev1.Microservice{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      msName,
 			Namespace: msNamespace,
@@ -70,15 +78,15 @@ func TestGenerate(t *testing.T) {
 		MaxReplicas: replicas,
 	}
 
-	ms.Spec = microservicev1beta1.MicroserviceSpec{
+	ms.Spec = microservicev1.MicroserviceSpec{
 		Image:          image,
 		Labels:         labels,
-		Replicas:       replicas,
+		Replicas:       &replicas,
 		Env:            env,
 		NodeSelector:   nodeSelector,
 		Tolerations:    tolerations,
 		PodAnnotations: podAnnotations,
-		Ingress: []microservicev1beta1.Ingress{
+		Ingress: []microservicev1.Ingress{
 			{
 				ContainerPort: int32(svcPort1),
 				Name:          svcName1,
@@ -97,7 +105,7 @@ func TestGenerate(t *testing.T) {
 	t.Run("service", func(t *testing.T) {
 		svc := GenerateServiceV1(ms)
 		assert.Equal(t, corev1.ServiceSpec{
-			Selector: labels,
+			Selector: podLabels,
 			Type:     corev1.ServiceTypeNodePort,
 			Ports: []corev1.ServicePort{
 				{
@@ -182,15 +190,15 @@ func TestGenerate(t *testing.T) {
 			},
 		}, ing[1].Spec)
 
-		ms.Spec = microservicev1beta1.MicroserviceSpec{
+		ms.Spec = microservicev1.MicroserviceSpec{
 			Image:          image,
 			Labels:         labels,
-			Replicas:       replicas,
+			Replicas:       &replicas,
 			Env:            env,
 			NodeSelector:   nodeSelector,
 			Tolerations:    tolerations,
 			PodAnnotations: podAnnotations,
-			Ingress: []microservicev1beta1.Ingress{
+			Ingress: []microservicev1.Ingress{
 				{
 					ContainerPort: int32(svcPort1),
 					Name:          svcName1,
@@ -278,12 +286,12 @@ func TestGenerate(t *testing.T) {
 		}, deployment.Spec.Template.Spec.Containers)
 
 		assert.Equal(t, labels, deployment.Labels)
-		assert.Equal(t, labels, deployment.Spec.Template.ObjectMeta.Labels)
+		assert.Equal(t, podLabels, deployment.Spec.Template.ObjectMeta.Labels)
 		assert.Equal(t, podAnnotations, deployment.Spec.Template.ObjectMeta.Annotations)
 		assert.Equal(t, nodeSelector, deployment.Spec.Template.Spec.NodeSelector)
 		assert.Equal(t, tolerations, deployment.Spec.Template.Spec.Tolerations)
 		assert.Equal(t, &metav1.LabelSelector{
-			MatchLabels: labels,
+			MatchLabels: podLabels,
 		}, deployment.Spec.Selector)
 		assert.Equal(t, &replicas, deployment.Spec.Replicas)
 	})
@@ -300,6 +308,20 @@ func TestGenerate(t *testing.T) {
 		assert.Nil(t, as2)
 	})
 
+	t.Run("autoscaled deployment replicas", func(t *testing.T) {
+		minReplicas := int32(3)
+		// spec.replicas is 8
+		ms.Spec.Autoscaling = &v2.HorizontalPodAutoscalerSpec{
+			MinReplicas: &minReplicas,
+			MaxReplicas: 5,
+		}
+		defer func() { ms.Spec.Autoscaling = nil }()
+
+		deployment := GenerateDeployment(ms)
+		assert.Equal(t, int32(5), *deployment.Spec.Replicas)
+		assert.Equal(t, replicas, *ms.Spec.Replicas)
+	})
+
 	t.Run("service account", func(t *testing.T) {
 		sa := GenerateServiceAccount(ms)
 		assert.NotNil(t, sa)
@@ -312,3 +334,743 @@ func TestGenerate(t *testing.T) {
 		assert.Equal(t, secretName, saSecret.Name)
 	})
 }
+
+func TestReplicasWithinAutoscalingBounds(t *testing.T) {
+	minReplicas := int32(2)
+	bounds := v2.HorizontalPodAutoscalerSpec{
+		MinReplicas: &minReplicas,
+		MaxReplicas: 4,
+	}
+
+	assert.Equal(t, int32(2), ReplicasWithinAutoscalingBounds(1, bounds))
+	assert.Equal(t, int32(3), ReplicasWithinAutoscalingBounds(3, bounds))
+	assert.Equal(t, int32(4), ReplicasWithinAutoscalingBounds(7, bounds))
+
+	bounds.MinReplicas = nil
+	assert.Equal(t, int32(1), ReplicasWithinAutoscalingBounds(0, bounds))
+}
+
+func TestAutoscalingSpec(t *testing.T) {
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+	}
+	target := v2.CrossVersionObjectReference{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "foo",
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Nil(t, AutoscalingSpec(ms))
+		assert.Nil(t, GenerateAutoscalingv2(ms))
+	})
+
+	t.Run("raw spec targets the deployment", func(t *testing.T) {
+		ms.Spec.Autoscaling = &v2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: v2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       "php-apache",
+			},
+			MaxReplicas: 3,
+		}
+		defer func() { ms.Spec.Autoscaling = nil }()
+
+		hpa := GenerateAutoscalingv2(ms)
+		assert.Equal(t, target, hpa.Spec.ScaleTargetRef)
+		assert.Equal(t, int32(3), hpa.Spec.MaxReplicas)
+		assert.Equal(t, "php-apache", ms.Spec.Autoscaling.ScaleTargetRef.Name)
+	})
+
+	t.Run("scaling", func(t *testing.T) {
+		minReplicas := int32(2)
+		cpu := int32(70)
+		memory := int32(80)
+		ms.Spec.Scaling = &microservicev1.ScalingSpec{
+			MinReplicas:                       &minReplicas,
+			MaxReplicas:                       6,
+			TargetCPUUtilizationPercentage:    &cpu,
+			TargetMemoryUtilizationPercentage: &memory,
+		}
+		defer func() { ms.Spec.Scaling = nil }()
+
+		spec := AutoscalingSpec(ms)
+		assert.Equal(t, target, spec.ScaleTargetRef)
+		assert.Equal(t, &minReplicas, spec.MinReplicas)
+		assert.Equal(t, int32(6), spec.MaxReplicas)
+		assert.Nil(t, spec.Behavior)
+		assert.Equal(t, []v2.MetricSpec{
+			{
+				Type: v2.ResourceMetricSourceType,
+				Resource: &v2.ResourceMetricSource{
+					Name:   corev1.ResourceCPU,
+					Target: v2.MetricTarget{Type: v2.UtilizationMetricType, AverageUtilization: &cpu},
+				},
+			},
+			{
+				Type: v2.ResourceMetricSourceType,
+				Resource: &v2.ResourceMetricSource{
+					Name:   corev1.ResourceMemory,
+					Target: v2.MetricTarget{Type: v2.UtilizationMetricType, AverageUtilization: &memory},
+				},
+			},
+		}, spec.Metrics)
+	})
+
+	t.Run("scaling behavior presets", func(t *testing.T) {
+		ms.Spec.Scaling = &microservicev1.ScalingSpec{
+			MaxReplicas: 6,
+			ScaleUp:     microservicev1.ScalingBehaviorDisabled,
+			ScaleDown:   microservicev1.ScalingBehaviorConservative,
+		}
+		defer func() { ms.Spec.Scaling = nil }()
+
+		spec := AutoscalingSpec(ms)
+		assert.NotNil(t, spec.Behavior)
+		assert.Equal(t, v2.DisabledPolicySelect, *spec.Behavior.ScaleUp.SelectPolicy)
+		assert.Equal(t, int32(scaleDownConservativeStabilizationSeconds), *spec.Behavior.ScaleDown.StabilizationWindowSeconds)
+		assert.Equal(t, []v2.HPAScalingPolicy{
+			{Type: v2.PodsScalingPolicy, Value: 1, PeriodSeconds: scaleConservativePeriodSeconds},
+		}, spec.Behavior.ScaleDown.Policies)
+
+		ms.Spec.Scaling.ScaleUp = microservicev1.ScalingBehaviorFast
+		ms.Spec.Scaling.ScaleDown = microservicev1.ScalingBehaviorDefault
+		assert.Nil(t, AutoscalingSpec(ms).Behavior)
+	})
+}
+
+func TestDeploymentStrategy(t *testing.T) {
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image: "image:latest",
+		},
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		deployment := GenerateDeployment(ms)
+		assert.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, deployment.Spec.Strategy.Type)
+		assert.Equal(t, intstr.FromInt(1), *deployment.Spec.Strategy.RollingUpdate.MaxSurge)
+		assert.Equal(t, intstr.FromInt(0), *deployment.Spec.Strategy.RollingUpdate.MaxUnavailable)
+		assert.Equal(t, int32(5), *deployment.Spec.RevisionHistoryLimit)
+		assert.Equal(t, int32(0), deployment.Spec.MinReadySeconds)
+		assert.Nil(t, deployment.Spec.ProgressDeadlineSeconds)
+	})
+
+	t.Run("rolling update", func(t *testing.T) {
+		maxSurge := intstr.FromString("25%")
+		progressDeadline := int32(300)
+		revisionHistoryLimit := int32(2)
+		ms.Spec.Strategy = &microservicev1.StrategySpec{
+			RollingUpdate: &microservicev1.RollingUpdateStrategy{
+				MaxSurge: &maxSurge,
+			},
+			MinReadySeconds:         10,
+			ProgressDeadlineSeconds: &progressDeadline,
+			RevisionHistoryLimit:    &revisionHistoryLimit,
+		}
+		defer func() { ms.Spec.Strategy = nil }()
+
+		deployment := GenerateDeployment(ms)
+		assert.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, deployment.Spec.Strategy.Type)
+		assert.Equal(t, maxSurge, *deployment.Spec.Strategy.RollingUpdate.MaxSurge)
+		assert.Equal(t, intstr.FromInt(0), *deployment.Spec.Strategy.RollingUpdate.MaxUnavailable)
+		assert.Equal(t, int32(2), *deployment.Spec.RevisionHistoryLimit)
+		assert.Equal(t, int32(10), deployment.Spec.MinReadySeconds)
+		assert.Equal(t, int32(300), *deployment.Spec.ProgressDeadlineSeconds)
+	})
+
+	t.Run("recreate", func(t *testing.T) {
+		ms.Spec.Strategy = &microservicev1.StrategySpec{Type: microservicev1.RecreateStrategyType}
+		defer func() { ms.Spec.Strategy = nil }()
+
+		deployment := GenerateDeployment(ms)
+		assert.Equal(t, appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}, deployment.Spec.Strategy)
+		assert.Equal(t, int32(5), *deployment.Spec.RevisionHistoryLimit)
+	})
+}
+
+func TestGenerateCanary(t *testing.T) {
+	canaryReplicas := int32(2)
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:  "image:v1",
+			Labels: map[string]string{"app": "test"},
+			Ingress: []microservicev1.Ingress{
+				{
+					Name:          "http",
+					ContainerPort: 8080,
+					Hosts:         []string{"foo.example.com"},
+					Annotations:   map[string]string{"kubernetes.io/ingress.class": "nginx"},
+				},
+			},
+			IngressEnabled: true,
+			Canary: &microservicev1.CanarySpec{
+				Replicas: &canaryReplicas,
+				Steps:    []microservicev1.CanaryStep{{Weight: 10}},
+			},
+		},
+	}
+	stableLabels := map[string]string{"app": "test", microservicev1.TrackLabel: microservicev1.TrackStable}
+	canaryLabels := map[string]string{"app": "test", microservicev1.TrackLabel: microservicev1.TrackCanary}
+
+	t.Run("stable", func(t *testing.T) {
+		deployment := GenerateDeployment(ms)
+		assert.Equal(t, stableLabels, deployment.Spec.Selector.MatchLabels)
+		assert.Equal(t, stableLabels, deployment.Spec.Template.Labels)
+		assert.Equal(t, stableLabels, GenerateServiceV1(ms).Spec.Selector)
+
+		// enabling the canary does not change the stable pods
+		withoutCanary := ms.DeepCopy()
+		withoutCanary.Spec.Canary = nil
+		assert.Equal(t, deployment.Spec.Template, GenerateDeployment(withoutCanary).Spec.Template)
+	})
+
+	t.Run("canary", func(t *testing.T) {
+		deployment := GenerateCanaryDeployment(ms, "image:v2")
+		assert.Equal(t, "foo-canary", deployment.Name)
+		assert.Equal(t, canaryLabels, deployment.Spec.Selector.MatchLabels)
+		assert.Equal(t, canaryLabels, deployment.Spec.Template.Labels)
+		assert.Equal(t, canaryReplicas, *deployment.Spec.Replicas)
+		assert.Equal(t, "image:v2", deployment.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, "foo", deployment.Spec.Template.Spec.ServiceAccountName)
+
+		service := GenerateCanaryServiceV1(ms)
+		assert.Equal(t, "foo-canary", service.Name)
+		assert.Equal(t, canaryLabels, service.Spec.Selector)
+		assert.Equal(t, int32(8080), service.Spec.Ports[0].Port)
+
+		ingresses := GenerateCanaryIngressesV1(ms, 25)
+		assert.Len(t, ingresses, 1)
+		assert.Equal(t, "foo-http-canary", ingresses[0].Name)
+		assert.Equal(t, map[string]string{
+			"kubernetes.io/ingress.class":               "nginx",
+			"nginx.ingress.kubernetes.io/canary":        "true",
+			"nginx.ingress.kubernetes.io/canary-weight": "25",
+		}, ingresses[0].Annotations)
+		assert.Equal(t, "foo.example.com", ingresses[0].Spec.Rules[0].Host)
+		assert.Equal(t, "foo-canary", ingresses[0].Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+
+		// the annotations of the Ingress of the Microservice are not changed
+		assert.Len(t, ms.Spec.Ingress[0].Annotations, 1)
+	})
+}
+
+func TestGenerateBlueGreen(t *testing.T) {
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:  "image:v1",
+			Labels: map[string]string{"app": "test"},
+			Ingress: []microservicev1.Ingress{
+				{Name: "http", ContainerPort: 8080},
+			},
+			Strategy: &microservicev1.StrategySpec{Type: microservicev1.BlueGreenStrategyType},
+		},
+	}
+	stableLabels := map[string]string{"app": "test", microservicev1.TrackLabel: microservicev1.TrackStable}
+	greenLabels := map[string]string{"app": "test", microservicev1.TrackLabel: microservicev1.TrackGreen}
+
+	deployment := GenerateDeployment(ms)
+	assert.Equal(t, stableLabels, deployment.Spec.Template.Labels)
+	assert.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, deployment.Spec.Strategy.Type)
+	assert.Equal(t, stableLabels, GenerateServiceV1(ms).Spec.Selector)
+
+	// the blue Deployment is the Deployment of the Microservice
+	assert.Equal(t, deployment, GenerateColorDeployment(ms, microservicev1.BlueGreenBlue))
+	assert.Equal(t, "foo", ColorName(ms, microservicev1.BlueGreenBlue))
+	assert.Equal(t, stableLabels, ColorLabels(ms, microservicev1.BlueGreenBlue))
+
+	// the green one only differs in its name and pod labels
+	green := GenerateColorDeployment(ms, microservicev1.BlueGreenGreen)
+	assert.Equal(t, "foo-green", green.Name)
+	assert.Equal(t, "foo-green", ColorName(ms, microservicev1.BlueGreenGreen))
+	assert.Equal(t, greenLabels, green.Spec.Selector.MatchLabels)
+	assert.Equal(t, greenLabels, green.Spec.Template.Labels)
+	assert.Equal(t, greenLabels, ColorLabels(ms, microservicev1.BlueGreenGreen))
+	assert.Equal(t, deployment.Spec.Replicas, green.Spec.Replicas)
+	assert.Equal(t, deployment.Spec.Template.Spec, green.Spec.Template.Spec)
+
+	service := GeneratePreviewServiceV1(ms, microservicev1.BlueGreenGreen)
+	assert.Equal(t, "foo-preview", service.Name)
+	assert.Equal(t, greenLabels, service.Spec.Selector)
+	assert.Equal(t, int32(8080), service.Spec.Ports[0].Port)
+	assert.Equal(t, stableLabels, GeneratePreviewServiceV1(ms, microservicev1.BlueGreenBlue).Spec.Selector)
+
+	assert.Equal(t, 30*time.Second, ScaleDownDelay(ms))
+	ms.Spec.Strategy.BlueGreen = &microservicev1.BlueGreenStrategy{ScaleDownDelay: &metav1.Duration{Duration: time.Minute}}
+	assert.Equal(t, time.Minute, ScaleDownDelay(ms))
+}
+
+func TestGenerateSidecars(t *testing.T) {
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image: "image:latest",
+			Ingress: []microservicev1.Ingress{
+				{Name: "http", ContainerPort: 8080},
+				{Name: "metrics", ContainerPort: 9090, Container: "exporter", Paths: []string{"/metrics"}},
+			},
+			Sidecars: []microservicev1.Container{
+				{
+					Name:  "exporter",
+					Image: "exporter:latest",
+					Args:  []string{"--listen=:9090"},
+					Env:   []corev1.EnvVar{{Name: "TARGET", Value: "localhost:8080"}},
+					Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9090}},
+				},
+			},
+			InitContainers: []microservicev1.Container{
+				{Name: "migrate", Image: "image:latest", Command: []string{"migrate", "up"}},
+			},
+		},
+	}
+
+	deployment := GenerateDeployment(ms)
+	containers := deployment.Spec.Template.Spec.Containers
+	assert.Len(t, containers, 2)
+	assert.Equal(t, "foo", containers[0].Name)
+	assert.Equal(t, []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}}, containers[0].Ports)
+	assert.Equal(t, corev1.Container{
+		Name:  "exporter",
+		Image: "exporter:latest",
+		Args:  []string{"--listen=:9090"},
+		Env:   []corev1.EnvVar{{Name: "TARGET", Value: "localhost:8080"}},
+		Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9090}},
+	}, containers[1])
+
+	initContainers := deployment.Spec.Template.Spec.InitContainers
+	assert.Len(t, initContainers, 1)
+	assert.Equal(t, "migrate", initContainers[0].Name)
+	assert.Equal(t, []string{"migrate", "up"}, initContainers[0].Command)
+
+	// canary images only replace the container of the Microservice
+	canary := GenerateCanaryDeployment(ms, "image:v2")
+	assert.Equal(t, "image:v2", canary.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "exporter:latest", canary.Spec.Template.Spec.Containers[1].Image)
+
+	service := GenerateServiceV1(ms)
+	assert.Len(t, service.Spec.Ports, 2)
+	assert.Equal(t, intstr.FromInt(9090), service.Spec.Ports[1].TargetPort)
+
+	ingresses := GenerateIngressesV1(ms)
+	assert.Equal(t, int32(9090), ingresses[1].Spec.Rules[0].HTTP.Paths[0].Backend.Service.Port.Number)
+}
+
+func TestGenerateEnv(t *testing.T) {
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image: "image:latest",
+			Env: map[string]string{
+				"C": "3",
+				"A": "1",
+				"B": "2",
+			},
+			EnvVars: []corev1.EnvVar{
+				{Name: "URL", Value: "http://$(A)"},
+				{
+					Name: "PASSWORD",
+					ValueFrom: &corev1.EnvVarSource{
+						SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "db"},
+							Key:                  "password",
+						},
+					},
+				},
+			},
+			EnvFrom: []corev1.EnvFromSource{
+				{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}},
+			},
+		},
+	}
+
+	container := GenerateDeployment(ms).Spec.Template.Spec.Containers[0]
+	names := []string{}
+	for _, env := range container.Env {
+		names = append(names, env.Name)
+	}
+	assert.Equal(t, []string{"A", "B", "C", "URL", "PASSWORD"}, names)
+	assert.Equal(t, "password", container.Env[4].ValueFrom.SecretKeyRef.Key)
+	assert.Equal(t, ms.Spec.EnvFrom, container.EnvFrom)
+
+	// the map is rendered in the same order every time
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, container.Env, GenerateDeployment(ms).Spec.Template.Spec.Containers[0].Env)
+	}
+}
+
+func TestGenerateVolumes(t *testing.T) {
+	storageClass := "fast"
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:  "image:latest",
+			Labels: map[string]string{"app": "test"},
+			Volumes: []microservicev1.Volume{
+				{Name: "config", ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}},
+				{Name: "data", PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"}},
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/foo"}},
+			Sidecars: []microservicev1.Container{
+				{Name: "backup", Image: "backup:latest", VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data", ReadOnly: true}}},
+			},
+			PersistentVolumeClaims: []microservicev1.PersistentVolumeClaim{
+				{
+					Name:             "data",
+					Size:             resource.MustParse("5Gi"),
+					AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+					StorageClassName: &storageClass,
+					RetentionPolicy:  microservicev1.RetentionPolicyDelete,
+				},
+			},
+		},
+	}
+
+	pod := GenerateDeployment(ms).Spec.Template.Spec
+	assert.Equal(t, []corev1.Volume{
+		{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: ms.Spec.Volumes[0].ConfigMap}},
+		{Name: "data", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: ms.Spec.Volumes[1].PersistentVolumeClaim}},
+	}, pod.Volumes)
+	assert.Equal(t, ms.Spec.VolumeMounts, pod.Containers[0].VolumeMounts)
+	assert.Equal(t, ms.Spec.Sidecars[0].VolumeMounts, pod.Containers[1].VolumeMounts)
+
+	claims := GeneratePersistentVolumeClaims(ms)
+	assert.Len(t, claims, 1)
+	assert.Equal(t, "data", claims[0].Name)
+	assert.Equal(t, map[string]string{"app": "test", microservicev1.ClaimOwnerLabel: "foo"}, claims[0].Labels)
+	assert.Equal(t, "Delete", claims[0].Annotations[microservicev1.RetentionPolicyAnnotation])
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, claims[0].Spec.AccessModes)
+	assert.Equal(t, &storageClass, claims[0].Spec.StorageClassName)
+	assert.Equal(t, resource.MustParse("5Gi"), claims[0].Spec.Resources.Requests[corev1.ResourceStorage])
+}
+
+func TestGenerateConfigFiles(t *testing.T) {
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:          "image:latest",
+			Labels:         map[string]string{"app": "test"},
+			PodAnnotations: map[string]string{"team": "a"},
+			VolumeMounts:   []corev1.VolumeMount{{Name: "tmp", MountPath: "/tmp"}},
+		},
+	}
+
+	t.Run("without config files", func(t *testing.T) {
+		assert.Nil(t, GenerateConfigMap(ms))
+		deployment := GenerateDeployment(ms)
+		assert.Equal(t, map[string]string{"team": "a"}, deployment.Spec.Template.Annotations)
+		assert.Empty(t, deployment.Spec.Template.Spec.Volumes)
+	})
+
+	ms.Spec.ConfigFiles = map[string]string{
+		"app.yaml":           "level: info\n",
+		"logging.properties": "root=INFO\n",
+	}
+
+	t.Run("config map", func(t *testing.T) {
+		configMap := GenerateConfigMap(ms)
+		assert.Equal(t, "foo-config", configMap.Name)
+		assert.Equal(t, map[string]string{"app": "test"}, configMap.Labels)
+		assert.Equal(t, ms.Spec.ConfigFiles, configMap.Data)
+	})
+
+	t.Run("mounted with hash", func(t *testing.T) {
+		deployment := GenerateDeployment(ms)
+		pod := deployment.Spec.Template
+		assert.Equal(t, "a", pod.Annotations["team"])
+		assert.Equal(t, ConfigHash(ms), pod.Annotations[microservicev1.ConfigHashAnnotation])
+		assert.NotContains(t, ms.Spec.PodAnnotations, microservicev1.ConfigHashAnnotation)
+
+		assert.Equal(t, "foo-config", pod.Spec.Volumes[0].ConfigMap.Name)
+		assert.Equal(t, []corev1.VolumeMount{
+			{Name: "tmp", MountPath: "/tmp"},
+			{Name: microservicev1.ConfigFilesVolume, MountPath: "/etc/config", ReadOnly: true},
+		}, pod.Spec.Containers[0].VolumeMounts)
+		assert.Len(t, ms.Spec.VolumeMounts, 1)
+
+		ms.Spec.ConfigFilesMountPath = "/app/config"
+		defer func() { ms.Spec.ConfigFilesMountPath = "" }()
+		assert.Equal(t, "/app/config", GenerateDeployment(ms).Spec.Template.Spec.Containers[0].VolumeMounts[1].MountPath)
+	})
+
+	t.Run("hash follows the content", func(t *testing.T) {
+		hash := ConfigHash(ms)
+		assert.Equal(t, hash, ConfigHash(ms.DeepCopy()))
+
+		edited := ms.DeepCopy()
+		edited.Spec.ConfigFiles["app.yaml"] = "level: debug\n"
+		assert.NotEqual(t, hash, ConfigHash(edited))
+
+		// moving content between files changes the hash as well
+		moved := ms.DeepCopy()
+		moved.Spec.ConfigFiles = map[string]string{"app.yaml": "", "logging.properties": "level: info\nroot=INFO\n"}
+		assert.NotEqual(t, hash, ConfigHash(moved))
+	})
+}
+
+func TestDependencies(t *testing.T) {
+	secretEnv := func(name string) corev1.EnvVar {
+		return corev1.EnvVar{Name: "SECRET", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: "key"},
+		}}
+	}
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:   "image:latest",
+			EnvVars: []corev1.EnvVar{secretEnv("db"), {Name: "PLAIN", Value: "value"}},
+			EnvFrom: []corev1.EnvFromSource{
+				{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}}},
+			},
+			Sidecars: []microservicev1.Container{
+				{Name: "proxy", Image: "proxy:latest", Env: []corev1.EnvVar{secretEnv("tls")}},
+			},
+			InitContainers: []microservicev1.Container{
+				{Name: "migrate", Image: "image:latest", Env: []corev1.EnvVar{secretEnv("db")}},
+			},
+			Volumes: []microservicev1.Volume{
+				{Name: "certs", Secret: &corev1.SecretVolumeSource{SecretName: "ca"}},
+				{Name: "all", Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+					{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "extra"}}},
+				}}},
+				{Name: "tmp", EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			},
+			ConfigFiles: map[string]string{"app.yaml": ""},
+		},
+	}
+
+	assert.Equal(t, []microservicev1.DependencyReference{
+		{Kind: microservicev1.DependencyKindConfigMap, Name: "extra"},
+		{Kind: microservicev1.DependencyKindConfigMap, Name: "settings"},
+		{Kind: microservicev1.DependencyKindSecret, Name: "ca"},
+		{Kind: microservicev1.DependencyKindSecret, Name: "db"},
+		{Kind: microservicev1.DependencyKindSecret, Name: "tls"},
+	}, Dependencies(ms))
+
+	ms.Spec.IgnoreChanges = []microservicev1.DependencyReference{
+		{Kind: microservicev1.DependencyKindSecret, Name: "tls"},
+		{Kind: microservicev1.DependencyKindConfigMap, Name: "db"},
+	}
+	assert.Equal(t, []string{"ca", "db"}, DependencyNames(ms, microservicev1.DependencyKindSecret))
+	assert.Equal(t, []string{"extra", "settings"}, DependencyNames(ms, microservicev1.DependencyKindConfigMap))
+}
+
+func TestGenerateStatefulSet(t *testing.T) {
+	replicas := int32(3)
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:        "image:latest",
+			Replicas:     &replicas,
+			Labels:       map[string]string{"app": "test"},
+			Ingress:      []microservicev1.Ingress{{Name: "http", ContainerPort: 8080}},
+			WorkloadKind: microservicev1.WorkloadKindStatefulSet,
+			StatefulSet: &microservicev1.StatefulSetSpec{
+				PodManagementPolicy: appsv1.ParallelPodManagement,
+				VolumeClaimTemplates: []microservicev1.VolumeClaimTemplate{
+					{Name: "state", Size: resource.MustParse("1Gi")},
+				},
+			},
+			VolumeMounts: []corev1.VolumeMount{{Name: "state", MountPath: "/state"}},
+		},
+	}
+
+	statefulSet := GenerateStatefulSet(ms)
+	assert.Equal(t, "foo", statefulSet.Name)
+	assert.Equal(t, "foo-headless", statefulSet.Spec.ServiceName)
+	assert.Equal(t, appsv1.ParallelPodManagement, statefulSet.Spec.PodManagementPolicy)
+	assert.Equal(t, appsv1.RollingUpdateStatefulSetStrategyType, statefulSet.Spec.UpdateStrategy.Type)
+	assert.Equal(t, int32(3), *statefulSet.Spec.Replicas)
+	assert.Equal(t, map[string]string{"app": "test"}, statefulSet.Spec.Selector.MatchLabels)
+	// the pods are the same as those of the Deployment
+	assert.Equal(t, GenerateDeployment(ms).Spec.Template, statefulSet.Spec.Template)
+
+	assert.Len(t, statefulSet.Spec.VolumeClaimTemplates, 1)
+	claim := statefulSet.Spec.VolumeClaimTemplates[0]
+	assert.Equal(t, "state", claim.Name)
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, claim.Spec.AccessModes)
+	assert.Equal(t, resource.MustParse("1Gi"), claim.Spec.Resources.Requests[corev1.ResourceStorage])
+
+	ms.Spec.StatefulSet = nil
+	assert.Equal(t, appsv1.OrderedReadyPodManagement, GenerateStatefulSet(ms).Spec.PodManagementPolicy)
+
+	service := GenerateHeadlessServiceV1(ms)
+	assert.Equal(t, "foo-headless", service.Name)
+	assert.Equal(t, corev1.ClusterIPNone, service.Spec.ClusterIP)
+	assert.Equal(t, corev1.ServiceTypeClusterIP, service.Spec.Type)
+	assert.Equal(t, map[string]string{"app": "test"}, service.Spec.Selector)
+	assert.Equal(t, int32(8080), service.Spec.Ports[0].Port)
+
+	ms.Spec.Scaling = &microservicev1.ScalingSpec{MaxReplicas: 5}
+	assert.Equal(t, "StatefulSet", GenerateAutoscalingv2(ms).Spec.ScaleTargetRef.Kind)
+}
+
+func TestGenerateJob(t *testing.T) {
+	backoffLimit := int32(2)
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:        "image:latest",
+			Labels:       map[string]string{"app": "test"},
+			WorkloadKind: microservicev1.WorkloadKindJob,
+			Job: &microservicev1.JobSpec{
+				BackoffLimit:  &backoffLimit,
+				RestartPolicy: corev1.RestartPolicyNever,
+			},
+		},
+	}
+
+	job := GenerateJob(ms)
+	assert.Equal(t, "foo", job.Name)
+	assert.Equal(t, int32(2), *job.Spec.BackoffLimit)
+	assert.Nil(t, job.Spec.Selector)
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+	// the pods are the same as those of the Deployment, but run to completion
+	template := GenerateDeployment(ms).Spec.Template
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	assert.Equal(t, template, job.Spec.Template)
+
+	ms.Spec.Job = nil
+	assert.Equal(t, corev1.RestartPolicyOnFailure, GenerateJob(ms).Spec.Template.Spec.RestartPolicy)
+	assert.Nil(t, GenerateJob(ms).Spec.BackoffLimit)
+}
+
+func TestGenerateCronJob(t *testing.T) {
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:        "image:latest",
+			Labels:       map[string]string{"app": "test"},
+			WorkloadKind: microservicev1.WorkloadKindCronJob,
+			CronJob: &microservicev1.CronJobSpec{
+				Schedule:          "*/5 * * * *",
+				ConcurrencyPolicy: batchv1.ForbidConcurrent,
+				Suspend:           true,
+			},
+		},
+	}
+
+	cronJob := GenerateCronJob(ms)
+	assert.Equal(t, "foo", cronJob.Name)
+	assert.Equal(t, "*/5 * * * *", cronJob.Spec.Schedule)
+	assert.Equal(t, batchv1.ForbidConcurrent, cronJob.Spec.ConcurrencyPolicy)
+	assert.True(t, *cronJob.Spec.Suspend)
+	assert.Equal(t, int32(3), *cronJob.Spec.SuccessfulJobsHistoryLimit)
+	assert.Equal(t, int32(1), *cronJob.Spec.FailedJobsHistoryLimit)
+	assert.Equal(t, map[string]string{"app": "test"}, cronJob.Spec.JobTemplate.Labels)
+	assert.Equal(t, GenerateJob(ms).Spec, cronJob.Spec.JobTemplate.Spec)
+
+	ms.Spec.CronJob.ConcurrencyPolicy = ""
+	assert.Equal(t, batchv1.AllowConcurrent, GenerateCronJob(ms).Spec.ConcurrencyPolicy)
+}
+
+func TestGenerateDaemonSet(t *testing.T) {
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:        "image:latest",
+			Labels:       map[string]string{"app": "test"},
+			NodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+			Tolerations:  []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			WorkloadKind: microservicev1.WorkloadKindDaemonSet,
+		},
+	}
+
+	daemonSet := GenerateDaemonSet(ms)
+	assert.Equal(t, "foo", daemonSet.Name)
+	assert.Equal(t, appsv1.RollingUpdateDaemonSetStrategyType, daemonSet.Spec.UpdateStrategy.Type)
+	assert.Equal(t, intstr.FromInt(1), *daemonSet.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable)
+	assert.Equal(t, map[string]string{"app": "test"}, daemonSet.Spec.Selector.MatchLabels)
+	// the pods are the same as those of the Deployment
+	assert.Equal(t, GenerateDeployment(ms).Spec.Template, daemonSet.Spec.Template)
+	assert.Equal(t, map[string]string{"kubernetes.io/os": "linux"}, daemonSet.Spec.Template.Spec.NodeSelector)
+	assert.Len(t, daemonSet.Spec.Template.Spec.Tolerations, 1)
+
+	maxUnavailable := intstr.FromString("10%")
+	ms.Spec.DaemonSet = &microservicev1.DaemonSetSpec{MaxUnavailable: &maxUnavailable}
+	assert.Equal(t, maxUnavailable, *GenerateDaemonSet(ms).Spec.UpdateStrategy.RollingUpdate.MaxUnavailable)
+}
+
+func TestGeneratePreDeployJob(t *testing.T) {
+	deadline := int64(300)
+	ms := &microservicev1.Microservice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: microservicev1.MicroserviceSpec{
+			Image:          "image:v2",
+			Labels:         map[string]string{"app": "test"},
+			Env:            map[string]string{"DATABASE_URL": "postgres://db"},
+			Ingress:        []microservicev1.Ingress{{Name: "http", ContainerPort: 8080}},
+			ReadinessProbe: &corev1.Probe{},
+			Sidecars:       []microservicev1.Container{{Name: "proxy", Image: "proxy:latest"}},
+			PreDeploy: &microservicev1.PreDeployHook{
+				Command:               []string{"migrate"},
+				Args:                  []string{"up"},
+				ActiveDeadlineSeconds: &deadline,
+			},
+		},
+	}
+
+	job := GeneratePreDeployJob(ms)
+	assert.Equal(t, "foo-predeploy", job.Name)
+	assert.Equal(t, int32(0), *job.Spec.BackoffLimit)
+	assert.Equal(t, int64(300), *job.Spec.ActiveDeadlineSeconds)
+
+	// the pod of the hook receives no traffic
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, map[string]string{microservicev1.PreDeployLabel: "foo"}, job.Spec.Template.Labels)
+	assert.Equal(t, corev1.RestartPolicyNever, podSpec.RestartPolicy)
+	assert.Equal(t, "foo", podSpec.ServiceAccountName)
+	assert.Len(t, podSpec.Containers, 1)
+
+	container := podSpec.Containers[0]
+	assert.Equal(t, "foo", container.Name)
+	assert.Equal(t, "image:v2", container.Image)
+	assert.Equal(t, []string{"migrate"}, container.Command)
+	assert.Equal(t, []string{"up"}, container.Args)
+	assert.Equal(t, GenerateDeployment(ms).Spec.Template.Spec.Containers[0].Env, container.Env)
+	assert.Empty(t, container.Ports)
+	assert.Nil(t, container.ReadinessProbe)
+
+	assert.Equal(t, "kubectl logs --namespace default job/foo-predeploy", PreDeployLogs(ms))
+}
@@ -0,0 +1,71 @@
+package microservice
+
+import (
+	"fmt"
+
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PreDeployJobName returns the name of the Job of the pre-deploy hook of a
+// Microservice.
+func PreDeployJobName(deployment *microservicev1.Microservice) string {
+	return deployment.GetName() + "-predeploy"
+}
+
+// PreDeployLogs returns the command that prints the logs of the pre-deploy
+// hook of a Microservice.
+func PreDeployLogs(deployment *microservicev1.Microservice) string {
+	return fmt.Sprintf("kubectl logs --namespace %s job/%s", deployment.GetNamespace(), PreDeployJobName(deployment))
+}
+
+// GeneratePreDeployJob returns the Job that runs the pre-deploy hook of a
+// Microservice with its current image. The pod runs the container of the
+// Microservice, with its env, volumes and service account, but without its
+// sidecars, ports and probes.
+func GeneratePreDeployJob(deployment *microservicev1.Microservice) *batchv1.Job {
+	hook := microservicev1.PreDeployHook{}
+	if deployment.Spec.PreDeploy != nil {
+		hook = *deployment.Spec.PreDeploy
+	}
+	backoffLimit := int32(defaultPreDeployBackoffLimit)
+	if hook.BackoffLimit != nil {
+		backoffLimit = *hook.BackoffLimit
+	}
+
+	labels := map[string]string{
+		microservicev1.PreDeployLabel: deployment.GetName(),
+	}
+
+	template := generatePodTemplate(deployment)
+	template.Labels = labels
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	container := template.Spec.Containers[0]
+	container.Ports = nil
+	container.LivenessProbe = nil
+	container.ReadinessProbe = nil
+	if len(hook.Command) > 0 {
+		container.Command = hook.Command
+	}
+	if len(hook.Args) > 0 {
+		container.Args = hook.Args
+	}
+	template.Spec.Containers = []corev1.Container{container}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            PreDeployJobName(deployment),
+			Namespace:       deployment.Namespace,
+			OwnerReferences: DeploymentOwnerReference(deployment),
+			Labels:          labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: hook.ActiveDeadlineSeconds,
+			Template:              template,
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package microservice
+
+import (
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GeneratePersistentVolumeClaims returns the PersistentVolumeClaims of the
+// Microservice.
+func GeneratePersistentVolumeClaims(deployment *microservicev1.Microservice) []*corev1.PersistentVolumeClaim {
+	claims := []*corev1.PersistentVolumeClaim{}
+	for _, pvc := range deployment.Spec.PersistentVolumeClaims {
+		labels := map[string]string{microservicev1.ClaimOwnerLabel: deployment.GetName()}
+		for k, v := range deployment.Spec.Labels {
+			labels[k] = v
+		}
+
+		claims = append(claims, &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            pvc.Name,
+				Namespace:       deployment.Namespace,
+				OwnerReferences: DeploymentOwnerReference(deployment),
+				Labels:          labels,
+				Annotations: map[string]string{
+					microservicev1.RetentionPolicyAnnotation: string(pvc.GetRetentionPolicy()),
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      pvc.GetAccessModes(),
+				StorageClassName: pvc.StorageClassName,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: pvc.Size,
+					},
+				},
+			},
+		})
+	}
+
+	return claims
+}
@@ -1,19 +1,29 @@
 package microservice
 
 import (
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
-func GenerateServiceV1(deployment *microservicev1beta1.Microservice) *corev1.Service {
+func GenerateServiceV1(deployment *microservicev1.Microservice) *corev1.Service {
 	service := newServiceV1Beta(deployment)
 
-	return configureService(deployment, service)
+	return configureService(deployment, service, PodLabels(deployment))
 }
 
-func newServiceV1Beta(deployment *microservicev1beta1.Microservice) *corev1.Service {
+// generateTrackServiceV1 returns a Service with the given name that selects
+// the pods of one track of the Microservice.
+func generateTrackServiceV1(deployment *microservicev1.Microservice, name, track string) *corev1.Service {
+	service := newServiceV1Beta(deployment)
+	service.Name = name
+	service.Labels = TrackLabels(deployment, track)
+
+	return configureService(deployment, service, service.Labels)
+}
+
+func newServiceV1Beta(deployment *microservicev1.Microservice) *corev1.Service {
 	return &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            deployment.Name,
@@ -25,7 +35,7 @@ func newServiceV1Beta(deployment *microservicev1beta1.Microservice) *corev1.Serv
 	}
 }
 
-func configureService(deployment *microservicev1beta1.Microservice, service *corev1.Service) *corev1.Service {
+func configureService(deployment *microservicev1.Microservice, service *corev1.Service, selector map[string]string) *corev1.Service {
 	ports := []corev1.ServicePort{}
 	for _, ingress := range deployment.Spec.Ingress {
 		ports = append(ports, corev1.ServicePort{
@@ -36,7 +46,7 @@ func configureService(deployment *microservicev1beta1.Microservice, service *cor
 		})
 	}
 
-	service.Spec.Selector = deployment.Spec.Labels
+	service.Spec.Selector = selector
 	service.Spec.Ports = ports
 	service.Spec.Type = corev1.ServiceTypeNodePort
 
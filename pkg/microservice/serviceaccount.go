@@ -3,16 +3,16 @@ package microservice
 import (
 	"fmt"
 
-	microservicev1beta1 "github.com/Hunter-Thompson/microservice-operator/api/v1beta1"
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func GenerateServiceAccount(mic *microservicev1beta1.Microservice) *corev1.ServiceAccount {
+func GenerateServiceAccount(mic *microservicev1.Microservice) *corev1.ServiceAccount {
 	return newServiceAccount(mic)
 }
 
-func newServiceAccount(mic *microservicev1beta1.Microservice) *corev1.ServiceAccount {
+func newServiceAccount(mic *microservicev1.Microservice) *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            mic.Name,
@@ -24,11 +24,11 @@ func newServiceAccount(mic *microservicev1beta1.Microservice) *corev1.ServiceAcc
 	}
 }
 
-func GenerateServiceAccountSecret(mic *microservicev1beta1.Microservice) *corev1.Secret {
+func GenerateServiceAccountSecret(mic *microservicev1.Microservice) *corev1.Secret {
 	return newServiceAccountSecret(mic)
 }
 
-func newServiceAccountSecret(mic *microservicev1beta1.Microservice) *corev1.Secret {
+func newServiceAccountSecret(mic *microservicev1.Microservice) *corev1.Secret {
 	annotations := map[string]string{}
 
 	for k, v := range mic.GetAnnotations() {
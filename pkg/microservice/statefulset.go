@@ -0,0 +1,107 @@
+package microservice
+
+import (
+	microservicev1 "github.com/Hunter-Thompson/microservice-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HeadlessServiceName returns the name of the headless Service that gives
+// the pods of the StatefulSet of a Microservice their stable DNS names.
+func HeadlessServiceName(deployment *microservicev1.Microservice) string {
+	return deployment.GetName() + "-headless"
+}
+
+// GenerateStatefulSet returns the StatefulSet of a Microservice with the
+// StatefulSet workload kind. It runs the same pods as the Deployment would.
+func GenerateStatefulSet(deployment *microservicev1.Microservice) *appsv1.StatefulSet {
+	statefulSetSpec := microservicev1.StatefulSetSpec{}
+	if deployment.Spec.StatefulSet != nil {
+		statefulSetSpec = *deployment.Spec.StatefulSet
+	}
+	podManagementPolicy := statefulSetSpec.PodManagementPolicy
+	if podManagementPolicy == "" {
+		podManagementPolicy = appsv1.OrderedReadyPodManagement
+	}
+
+	strategy := microservicev1.StrategySpec{}
+	if deployment.Spec.Strategy != nil {
+		strategy = *deployment.Spec.Strategy
+	}
+	revHistoryLimit := int32(defaultRevHistoryLimit)
+	if strategy.RevisionHistoryLimit != nil {
+		revHistoryLimit = *strategy.RevisionHistoryLimit
+	}
+
+	replicas := desiredReplicas(deployment)
+
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment.Name,
+			Namespace:       deployment.Namespace,
+			OwnerReferences: DeploymentOwnerReference(deployment),
+			Labels:          deployment.Spec.Labels,
+			Annotations:     deployment.GetAnnotations(),
+		},
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName:         HeadlessServiceName(deployment),
+			PodManagementPolicy: podManagementPolicy,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+			},
+			MinReadySeconds:      strategy.MinReadySeconds,
+			RevisionHistoryLimit: &revHistoryLimit,
+			Replicas:             &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: deployment.Spec.Labels,
+			},
+			Template:             generatePodTemplate(deployment),
+			VolumeClaimTemplates: generateVolumeClaimTemplates(deployment, statefulSetSpec.VolumeClaimTemplates),
+		},
+	}
+}
+
+// generateVolumeClaimTemplates returns the PersistentVolumeClaims that the
+// StatefulSet creates for each of its pods.
+func generateVolumeClaimTemplates(deployment *microservicev1.Microservice, templates []microservicev1.VolumeClaimTemplate) []corev1.PersistentVolumeClaim {
+	var claims []corev1.PersistentVolumeClaim
+	for _, template := range templates {
+		accessModes := template.AccessModes
+		if len(accessModes) == 0 {
+			accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+		}
+
+		claims = append(claims, corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   template.Name,
+				Labels: deployment.Spec.Labels,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      accessModes,
+				StorageClassName: template.StorageClassName,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: template.Size,
+					},
+				},
+			},
+		})
+	}
+
+	return claims
+}
+
+// GenerateHeadlessServiceV1 returns the headless Service of the StatefulSet
+// of a Microservice. It publishes the addresses of the pods under their
+// stable names, <pod>.<service>, next to the Service of the Microservice.
+func GenerateHeadlessServiceV1(deployment *microservicev1.Microservice) *corev1.Service {
+	service := newServiceV1Beta(deployment)
+	service.Name = HeadlessServiceName(deployment)
+
+	service = configureService(deployment, service, PodLabels(deployment))
+	service.Spec.Type = corev1.ServiceTypeClusterIP
+	service.Spec.ClusterIP = corev1.ClusterIPNone
+
+	return service
+}
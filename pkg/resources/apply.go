@@ -0,0 +1,312 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// FieldManager is the field manager of the server-side apply requests sent
+// by the operator.
+const FieldManager = "microservice-operator"
+
+// EnableServerSideApply makes Create and Update manage resources of the given
+// kinds, e.g. Deployment or HorizontalPodAutoscaler, with server-side apply.
+// The operator then only owns the fields set by the generators and leaves
+// fields set by other controllers and tools alone.
+func (r *ResourceHelper) EnableServerSideApply(kinds ...string) {
+	if r.serverSideApply == nil {
+		r.serverSideApply = map[string]bool{}
+	}
+
+	for _, kind := range kinds {
+		r.serverSideApply[kind] = true
+	}
+}
+
+func (r *ResourceHelper) usesServerSideApply(obj Object) (bool, error) {
+	if len(r.serverSideApply) == 0 {
+		return false, nil
+	}
+
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to determine the resource kind")
+	}
+
+	return r.serverSideApply[gvk.Kind], nil
+}
+
+// apply sends desired as a server-side apply request, taking over fields that
+// are owned by other managers.
+func (r *ResourceHelper) apply(desired Object, reqLogger logr.Logger) error {
+	gvk, err := apiutil.GVKForObject(desired, r.scheme)
+	if err != nil {
+		return errors.Wrap(err, "failed to determine the resource kind")
+	}
+	desired.GetObjectKind().SetGroupVersionKind(gvk)
+	desired.SetResourceVersion("")
+	desired.SetManagedFields(nil)
+
+	// the generators share the annotations of the Microservice, which may
+	// carry the last applied annotation of another resource
+	if _, ok := desired.GetAnnotations()[lastAppliedConfig]; ok {
+		annotations := map[string]string{}
+		for k, v := range desired.GetAnnotations() {
+			if k != lastAppliedConfig {
+				annotations[k] = v
+			}
+		}
+		desired.SetAnnotations(annotations)
+	}
+
+	applied, err := appliedConfiguration(desired)
+	if err != nil {
+		return err
+	}
+
+	reqLogger.V(1).Info("Applying resource", "name", desired.GetName(), "kind", gvk.Kind, "namespace", desired.GetNamespace())
+
+	err = r.client.Patch(context.TODO(), applied, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership)
+	if err != nil {
+		return errors.Wrap(err, "failed to apply resource")
+	}
+
+	// desired is filled with the resource returned by the API server
+	value := reflect.ValueOf(desired).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(applied.Object, desired)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode the applied resource")
+	}
+
+	return nil
+}
+
+// appliedConfiguration returns the fields of desired that are sent with a
+// server-side apply request. A typed object also serializes fields the
+// generators do not set, like its status, a null creationTimestamp or empty
+// structs, which FieldManager would otherwise own.
+func appliedConfiguration(desired Object) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert the resource")
+	}
+
+	delete(content, "status")
+	pruneUnset(content)
+
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// pruneUnset removes the null values and empty objects from content. Lists
+// are not descended into, an empty object in a list item, like the emptyDir
+// source of a volume, is set on purpose.
+func pruneUnset(content map[string]interface{}) {
+	for key, value := range content {
+		if value == nil {
+			delete(content, key)
+			continue
+		}
+
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pruneUnset(nested)
+		if len(nested) == 0 {
+			delete(content, key)
+		}
+	}
+}
+
+// DriftPaths returns the paths of the fields of current that were changed
+// since the operator last wrote it, in the format of PatchPaths. For a
+// resource with the last applied annotation these are the fields that differ
+// from it, for a resource managed with server-side apply the fields that are
+// owned by other field managers.
+func (r *ResourceHelper) DriftPaths(current Object) ([]string, error) {
+	if _, ok := current.GetAnnotations()[lastAppliedConfig]; ok {
+		drift, err := r.Drift(current)
+		if err != nil || drift == nil {
+			return nil, err
+		}
+		return PatchPaths(drift)
+	}
+
+	serverSideApply, err := r.usesServerSideApply(current)
+	if err != nil || !serverSideApply {
+		return nil, err
+	}
+
+	managers, err := fieldManagers(current)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for path, owners := range managers {
+		for _, owner := range owners {
+			if owner != FieldManager {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// OwnedByOthers returns true if current is managed with server-side apply and
+// the field at path, in the format of PatchPaths, is only owned by other
+// field managers, e.g. the replicas of a Deployment that were scaled by a
+// HorizontalPodAutoscaler. Leaving such a field out of desired keeps its
+// value without claiming it again.
+func (r *ResourceHelper) OwnedByOthers(current Object, path string) (bool, error) {
+	if _, ok := current.GetAnnotations()[lastAppliedConfig]; ok {
+		return false, nil
+	}
+
+	serverSideApply, err := r.usesServerSideApply(current)
+	if err != nil || !serverSideApply {
+		return false, err
+	}
+
+	managers, err := fieldManagers(current)
+	if err != nil {
+		return false, err
+	}
+
+	owners := managers[path]
+	for _, owner := range owners {
+		if owner == FieldManager {
+			return false, nil
+		}
+	}
+
+	return len(owners) > 0, nil
+}
+
+// fieldManagers returns the managers that own the fields of obj, keyed by the
+// path of the field in the format of PatchPaths. Lists are not descended
+// into.
+func fieldManagers(obj Object) (map[string][]string, error) {
+	managers := map[string][]string{}
+	for _, entry := range obj.GetManagedFields() {
+		if entry.FieldsV1 == nil {
+			continue
+		}
+
+		fields := map[string]interface{}{}
+		err := json.Unmarshal(entry.FieldsV1.Raw, &fields)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode managed fields")
+		}
+
+		found := map[string]bool{}
+		collectFieldPaths("", fields, found)
+		for path := range found {
+			managers[path] = append(managers[path], entry.Manager)
+		}
+	}
+
+	return managers, nil
+}
+
+func collectFieldPaths(prefix string, fields map[string]interface{}, found map[string]bool) {
+	for key, value := range fields {
+		// "." stands for the field itself
+		if !strings.HasPrefix(key, "f:") {
+			continue
+		}
+
+		path := joinPatchPath(prefix, strings.TrimPrefix(key, "f:"))
+		nested, _ := value.(map[string]interface{})
+
+		descend := false
+		for nestedKey := range nested {
+			if strings.HasPrefix(nestedKey, "f:") {
+				descend = true
+			} else if nestedKey != "." {
+				// the items of lists are keyed by k:, v: or i:
+				descend = false
+				break
+			}
+		}
+		if !descend {
+			found[path] = true
+			continue
+		}
+
+		collectFieldPaths(path, nested, found)
+	}
+}
+
+// migrateToServerSideApply moves a resource that was managed with the last
+// applied annotation over to server-side apply. Fields that are no longer
+// generated are removed with the last applied annotation first, then desired
+// is applied and finally the annotation and the ownership of the manager that
+// wrote it are dropped, so that FieldManager is the only owner of the
+// generated fields.
+func (r *ResourceHelper) migrateToServerSideApply(current, desired Object, reqLogger logr.Logger) error {
+	reqLogger.Info("Migrating resource to server-side apply", "name", current.GetName(), "namespace", current.GetNamespace())
+
+	legacy, ok := desired.DeepCopyObject().(Object)
+	if !ok {
+		return errors.Errorf("unsupported resource type %T", desired)
+	}
+	err := r.updateLastApplied(current, legacy, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	err = r.apply(desired, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	before, ok := desired.DeepCopyObject().(client.Object)
+	if !ok {
+		return errors.Errorf("unsupported resource type %T", desired)
+	}
+	annotations := desired.GetAnnotations()
+	delete(annotations, lastAppliedConfig)
+	desired.SetAnnotations(annotations)
+	desired.SetManagedFields(withoutLastAppliedManagers(desired.GetManagedFields()))
+
+	err = r.client.Patch(context.TODO(), desired, client.MergeFrom(before))
+	if err != nil {
+		return errors.Wrap(err, "failed to remove the last applied annotation")
+	}
+
+	return nil
+}
+
+// withoutLastAppliedManagers drops the update entries of the managers that own
+// the last applied annotation, i.e. the operator before it used server-side
+// apply.
+func withoutLastAppliedManagers(entries []v1.ManagedFieldsEntry) []v1.ManagedFieldsEntry {
+	annotationField := `"f:` + lastAppliedConfig + `"`
+
+	kept := []v1.ManagedFieldsEntry{}
+	for _, entry := range entries {
+		if entry.Operation == v1.ManagedFieldsOperationUpdate && entry.FieldsV1 != nil &&
+			strings.Contains(string(entry.FieldsV1.Raw), annotationField) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	return kept
+}
@@ -0,0 +1,314 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// applyClient emulates server-side apply requests, which the fake client does
+// not support, by merging the applied configuration into the stored resource
+// and recording FieldManager as one of its managers.
+type applyClient struct {
+	client.Client
+	applied []map[string]interface{}
+}
+
+// Update keeps the managed fields of the stored resource if obj has none,
+// like the API server does.
+func (c *applyClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if len(obj.GetManagedFields()) == 0 {
+		current := obj.DeepCopyObject().(client.Object)
+		err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), current)
+		if err != nil {
+			return err
+		}
+		obj.SetManagedFields(current.GetManagedFields())
+	}
+
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	applied := map[string]interface{}{}
+	err = json.Unmarshal(data, &applied)
+	if err != nil {
+		return err
+	}
+	c.applied = append(c.applied, applied)
+
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	err = c.Client.Get(ctx, client.ObjectKeyFromObject(obj), current)
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+
+	managedFields := []v1.ManagedFieldsEntry{}
+	for _, entry := range current.GetManagedFields() {
+		if entry.Manager != FieldManager {
+			managedFields = append(managedFields, entry)
+		}
+	}
+	obj.SetManagedFields(append(managedFields, v1.ManagedFieldsEntry{Manager: FieldManager, Operation: v1.ManagedFieldsOperationApply}))
+
+	if k8sErrors.IsNotFound(err) {
+		return c.Client.Create(ctx, obj)
+	}
+
+	merge, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, merge))
+}
+
+func newApplyTestDeployment() *appsv1.Deployment {
+	replicas := int32(2)
+	return &appsv1.Deployment{
+		ObjectMeta: v1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &v1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{Labels: map[string]string{"app": "foo"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "foo", Image: "image:v1"}},
+					Volumes: []corev1.Volume{{
+						Name:         "cache",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestApply(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(s))
+
+	c := &applyClient{Client: fake.NewClientBuilder().WithScheme(s).Build()}
+	r := NewResourceHelper(c, s)
+	r.EnableServerSideApply("Deployment")
+	owner := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "owner", Namespace: "default", UID: "uid"}}
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	desired := newApplyTestDeployment()
+	desired.Annotations = map[string]string{"foo": "bar", lastAppliedConfig: "{}"}
+	require.NoError(t, r.Create(owner, desired, log.Log))
+
+	// only the fields set by the generator are applied
+	require.Len(t, c.applied, 1)
+	applied := &unstructured.Unstructured{Object: c.applied[0]}
+	assert.Equal(t, "apps/v1", applied.GetAPIVersion())
+	assert.Equal(t, "Deployment", applied.GetKind())
+	assert.Equal(t, map[string]string{"foo": "bar"}, applied.GetAnnotations())
+	assert.Len(t, applied.GetOwnerReferences(), 1)
+	assert.NotContains(t, applied.Object, "status")
+	assert.NotContains(t, applied.Object["metadata"], "creationTimestamp")
+	_, found, err := unstructured.NestedFieldNoCopy(applied.Object, "spec", "template", "metadata", "creationTimestamp")
+	assert.NoError(t, err)
+	assert.False(t, found)
+	_, found, err = unstructured.NestedFieldNoCopy(applied.Object, "spec", "strategy")
+	assert.NoError(t, err)
+	assert.False(t, found)
+	replicas, _, err := unstructured.NestedFieldNoCopy(applied.Object, "spec", "replicas")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), replicas)
+	volumes, _, err := unstructured.NestedSlice(applied.Object, "spec", "template", "spec", "volumes")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "cache", "emptyDir": map[string]interface{}{}}}, volumes)
+
+	// desired holds the resource returned by the API server
+	assert.NotEmpty(t, desired.ResourceVersion)
+	assert.Equal(t, FieldManager, desired.ManagedFields[0].Manager)
+
+	current := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.TODO(), key, current))
+	assert.NotContains(t, current.Annotations, lastAppliedConfig)
+
+	// a resource without the last applied annotation is applied again
+	desired = newApplyTestDeployment()
+	desired.Spec.Template.Spec.Containers[0].Image = "image:v2"
+	require.NoError(t, r.Update(current, desired, log.Log))
+	assert.Len(t, c.applied, 2)
+
+	require.NoError(t, c.Get(context.TODO(), key, current))
+	assert.Equal(t, "image:v2", current.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestMigrateToServerSideApply(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(s))
+
+	c := &applyClient{Client: fake.NewClientBuilder().WithScheme(s).Build()}
+	r := NewResourceHelper(c, s)
+	owner := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "owner", Namespace: "default", UID: "uid"}}
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	desired := newApplyTestDeployment()
+	desired.Labels = map[string]string{"app": "foo", "retired": "true"}
+	require.NoError(t, r.Create(owner, desired, log.Log))
+	assert.Empty(t, c.applied)
+
+	current := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.TODO(), key, current))
+	require.Contains(t, current.Annotations, lastAppliedConfig)
+	current.ManagedFields = []v1.ManagedFieldsEntry{
+		{
+			Manager:   "manager",
+			Operation: v1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &v1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{".":{},"f:microservice.example.com/last-applied":{}}},"f:spec":{"f:replicas":{}}}`)},
+		},
+		{
+			Manager:   "kube-controller-manager",
+			Operation: v1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &v1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{"f:deployment.kubernetes.io/revision":{}}}}`)},
+		},
+	}
+	require.NoError(t, c.Update(context.TODO(), current))
+
+	r.EnableServerSideApply("Deployment")
+	desired = newApplyTestDeployment()
+	desired.Labels = map[string]string{"app": "foo"}
+	require.NoError(t, r.Update(current, desired, log.Log))
+	assert.Len(t, c.applied, 1)
+
+	require.NoError(t, c.Get(context.TODO(), key, current))
+	// fields that are no longer generated are removed before applying
+	assert.Equal(t, map[string]string{"app": "foo"}, current.Labels)
+	assert.NotContains(t, current.Annotations, lastAppliedConfig)
+
+	managers := []string{}
+	for _, entry := range current.ManagedFields {
+		managers = append(managers, entry.Manager)
+	}
+	assert.Equal(t, []string{"kube-controller-manager", FieldManager}, managers)
+
+	// once migrated the resource is applied
+	require.NoError(t, r.Update(current, newApplyTestDeployment(), log.Log))
+	assert.Len(t, c.applied, 2)
+}
+
+func TestDriftPaths(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(s))
+
+	r := NewResourceHelper(fake.NewClientBuilder().WithScheme(s).Build(), s)
+	current := newApplyTestDeployment()
+	current.ManagedFields = []v1.ManagedFieldsEntry{
+		{
+			Manager:   FieldManager,
+			Operation: v1.ManagedFieldsOperationApply,
+			FieldsV1:  &v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{},"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"foo\"}":{".":{},"f:image":{},"f:name":{}}}}}}}`)},
+		},
+		{
+			Manager:   "kubectl-edit",
+			Operation: v1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &v1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{".":{},"f:app.kubernetes.io/name":{}}},"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"foo\"}":{"f:image":{}}}}}}}`)},
+		},
+		{
+			Manager:     "kube-controller-manager",
+			Operation:   v1.ManagedFieldsOperationUpdate,
+			Subresource: "scale",
+			FieldsV1:    &v1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+	}
+
+	// drift is not detected from the field managers of resources that are
+	// not managed with server-side apply
+	paths, err := r.DriftPaths(current)
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+	ownedByOthers, err := r.OwnedByOthers(current, "spec.replicas")
+	assert.NoError(t, err)
+	assert.False(t, ownedByOthers)
+
+	r.EnableServerSideApply("Deployment")
+	paths, err = r.DriftPaths(current)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata.labels[app.kubernetes.io/name]", "spec.replicas", "spec.template.spec.containers"}, paths)
+
+	// replicas that are also owned by the operator are still applied
+	ownedByOthers, err = r.OwnedByOthers(current, "spec.replicas")
+	assert.NoError(t, err)
+	assert.False(t, ownedByOthers)
+
+	current.ManagedFields[0].FieldsV1.Raw = []byte(`{"f:spec":{"f:template":{}}}`)
+	ownedByOthers, err = r.OwnedByOthers(current, "spec.replicas")
+	assert.NoError(t, err)
+	assert.True(t, ownedByOthers)
+}
+
+func TestUsesServerSideApply(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(s))
+
+	r := NewResourceHelper(fake.NewClientBuilder().WithScheme(s).Build(), s)
+
+	ssa, err := r.usesServerSideApply(&appsv1.Deployment{})
+	assert.NoError(t, err)
+	assert.False(t, ssa)
+
+	r.EnableServerSideApply("Deployment")
+
+	ssa, err = r.usesServerSideApply(&appsv1.Deployment{})
+	assert.NoError(t, err)
+	assert.True(t, ssa)
+
+	ssa, err = r.usesServerSideApply(&corev1.Service{})
+	assert.NoError(t, err)
+	assert.False(t, ssa)
+}
+
+func TestWithoutLastAppliedManagers(t *testing.T) {
+	entries := []v1.ManagedFieldsEntry{
+		{
+			Manager:   "manager",
+			Operation: v1.ManagedFieldsOperationUpdate,
+			FieldsV1: &v1.FieldsV1{
+				Raw: []byte(`{"f:metadata":{"f:annotations":{".":{},"f:microservice.example.com/last-applied":{}}},"f:spec":{"f:replicas":{}}}`),
+			},
+		},
+		{
+			Manager:   FieldManager,
+			Operation: v1.ManagedFieldsOperationApply,
+			FieldsV1: &v1.FieldsV1{
+				Raw: []byte(`{"f:spec":{"f:replicas":{}}}`),
+			},
+		},
+		{
+			Manager:   "kube-controller-manager",
+			Operation: v1.ManagedFieldsOperationUpdate,
+			FieldsV1: &v1.FieldsV1{
+				Raw: []byte(`{"f:metadata":{"f:annotations":{"f:deployment.kubernetes.io/revision":{}}}}`),
+			},
+		},
+	}
+
+	kept := withoutLastAppliedManagers(entries)
+	assert.Equal(t, []v1.ManagedFieldsEntry{entries[1], entries[2]}, kept)
+}
@@ -0,0 +1,70 @@
+package resources
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const deleteFromPrimitiveListDirective = "$deleteFromPrimitiveList/"
+
+// PatchPaths returns the sorted paths of the fields changed by a strategic
+// merge patch, e.g. spec.replicas or metadata.labels[app.kubernetes.io/name].
+// Lists are not descended into.
+func PatchPaths(patch []byte) ([]string, error) {
+	content := map[string]interface{}{}
+	err := json.Unmarshal(patch, &content)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode patch")
+	}
+
+	found := map[string]bool{}
+	collectPatchPaths("", content, found)
+
+	paths := make([]string, 0, len(found))
+	for path := range found {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+func collectPatchPaths(prefix string, content map[string]interface{}, found map[string]bool) {
+	for key, value := range content {
+		if strings.HasPrefix(key, deleteFromPrimitiveListDirective) {
+			found[joinPatchPath(prefix, strings.TrimPrefix(key, deleteFromPrimitiveListDirective))] = true
+			continue
+		}
+		if strings.HasPrefix(key, "$") {
+			// $patch, $retainKeys and $setElementOrder only carry
+			// information about how to merge the sibling fields
+			if key == "$patch" && prefix != "" {
+				found[prefix] = true
+			}
+			continue
+		}
+
+		path := joinPatchPath(prefix, key)
+		nested, ok := value.(map[string]interface{})
+		if !ok || len(nested) == 0 {
+			found[path] = true
+			continue
+		}
+
+		collectPatchPaths(path, nested, found)
+	}
+}
+
+func joinPatchPath(prefix, key string) string {
+	if strings.ContainsAny(key, "./") {
+		return prefix + "[" + key + "]"
+	}
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
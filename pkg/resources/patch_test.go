@@ -0,0 +1,52 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			patch:    `{}`,
+			expected: []string{},
+		},
+		{
+			name:     "nested fields",
+			patch:    `{"spec":{"replicas":5,"template":{"spec":{"containers":[{"name":"foo","image":"bar"}]}}}}`,
+			expected: []string{"spec.replicas", "spec.template.spec.containers"},
+		},
+		{
+			name:     "keys with dots",
+			patch:    `{"metadata":{"labels":{"app.kubernetes.io/name":null}}}`,
+			expected: []string{"metadata.labels[app.kubernetes.io/name]"},
+		},
+		{
+			name:     "directives",
+			patch:    `{"metadata":{"$deleteFromPrimitiveList/finalizers":["foo"]},"spec":{"$setElementOrder/ports":[{"port":80}],"ports":[{"port":80,"targetPort":8080}]}}`,
+			expected: []string{"metadata.finalizers", "spec.ports"},
+		},
+		{
+			name:     "replaced map",
+			patch:    `{"spec":{"selector":{"$patch":"replace","app":"foo"}}}`,
+			expected: []string{"spec.selector", "spec.selector.app"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, err := PatchPaths([]byte(tc.patch))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+
+	_, err := PatchPaths([]byte(`[]`))
+	assert.Error(t, err)
+}
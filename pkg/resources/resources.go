@@ -2,6 +2,8 @@ package resources
 
 import (
 	"context"
+	"encoding/json"
+	"reflect"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -13,6 +15,7 @@ import (
 	objectMatcher "github.com/banzaicloud/k8s-objectmatcher/patch"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
@@ -36,6 +39,9 @@ type Object interface {
 type ResourceHelper struct {
 	client client.Client
 	scheme *runtime.Scheme
+	// serverSideApply holds the kinds that are managed with server-side
+	// apply instead of the last applied annotation
+	serverSideApply map[string]bool
 }
 
 func NewResourceHelper(client client.Client, scheme *runtime.Scheme) *ResourceHelper {
@@ -47,9 +53,22 @@ func NewResourceHelper(client client.Client, scheme *runtime.Scheme) *ResourceHe
 
 // Create creates the provided resource and sets the owner
 func (r *ResourceHelper) Create(owner v1.Object, desired Object, reqLogger logr.Logger) error {
+	serverSideApply, err := r.usesServerSideApply(desired)
+	if err != nil {
+		return err
+	}
+	if serverSideApply {
+		err = controllerutil.SetControllerReference(owner, desired, r.scheme)
+		if err != nil {
+			return errors.Wrap(err, "failed to set owner reference")
+		}
+
+		return r.apply(desired, reqLogger)
+	}
+
 	// adding the last applied annotation to use the object matcher later
 	// see: https://github.com/banzaicloud/k8s-objectmatcher
-	err := defaultAnnotator.SetLastAppliedAnnotation(desired)
+	err = defaultAnnotator.SetLastAppliedAnnotation(desired)
 	if err != nil {
 		return errors.Wrap(err, "failed to apply annotation to the resource")
 	}
@@ -62,17 +81,78 @@ func (r *ResourceHelper) Create(owner v1.Object, desired Object, reqLogger logr.
 	return r.client.Create(context.TODO(), desired)
 }
 
+// Diff returns the patch that would be applied to current by Update, or nil
+// if current already matches desired.
+func (r *ResourceHelper) Diff(current, desired Object) ([]byte, error) {
+	// the status of StatefulSets and of their claim templates is serialized
+	// even when empty, which would otherwise always differ
+	patchResult, err := objectMatcher.NewPatchMaker(defaultAnnotator).Calculate(current, desired,
+		objectMatcher.IgnoreStatusFields(),
+		objectMatcher.IgnoreVolumeClaimTemplateTypeMetaAndStatus(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to determine if resources differ")
+	}
+	if patchResult.IsEmpty() {
+		return nil, nil
+	}
+
+	return patchResult.Patch, nil
+}
+
+// Drift returns the patch that reverts the changes made to current since it
+// was last applied, or nil if none of the applied fields were changed.
+func (r *ResourceHelper) Drift(current Object) ([]byte, error) {
+	original, err := defaultAnnotator.GetOriginalConfiguration(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read last applied configuration")
+	}
+	if original == nil {
+		return nil, nil
+	}
+
+	lastApplied, ok := reflect.New(reflect.TypeOf(current).Elem()).Interface().(Object)
+	if !ok {
+		return nil, errors.Errorf("unsupported resource type %T", current)
+	}
+	err = json.Unmarshal(original, lastApplied)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode last applied configuration")
+	}
+
+	return r.Diff(current, lastApplied)
+}
+
+// Update brings current in line with desired, either with server-side apply
+// or with a patch computed from the last applied annotation, depending on the
+// kind of the resource.
 func (r *ResourceHelper) Update(current, desired Object, reqLogger logr.Logger) error {
-	patchResult, err := objectMatcher.NewPatchMaker(defaultAnnotator).Calculate(current, desired)
+	serverSideApply, err := r.usesServerSideApply(desired)
+	if err != nil {
+		return err
+	}
+	if !serverSideApply {
+		return r.updateLastApplied(current, desired, reqLogger)
+	}
+
+	if _, ok := current.GetAnnotations()[lastAppliedConfig]; ok {
+		return r.migrateToServerSideApply(current, desired, reqLogger)
+	}
+
+	return r.apply(desired, reqLogger)
+}
+
+func (r *ResourceHelper) updateLastApplied(current, desired Object, reqLogger logr.Logger) error {
+	patch, err := r.Diff(current, desired)
 	if err != nil {
-		return errors.Wrap(err, "failed to determine if resources differ")
+		return err
 	}
-	if !patchResult.IsEmpty() {
+	if patch != nil {
 		if err := defaultAnnotator.SetLastAppliedAnnotation(desired); err != nil {
 			return errors.Wrap(err, "failed to apply annotation to the resource")
 		}
 
-		reqLogger.Info("Updating resource", "name", desired.GetName(), "kind", desired.GetObjectKind(), "namespace", desired.GetNamespace(), "patch", string(patchResult.Patch))
+		reqLogger.Info("Updating resource", "name", desired.GetName(), "kind", desired.GetObjectKind(), "namespace", desired.GetNamespace(), "patch", string(patch))
 
 		// Resource version is required for the update, but need to be set after
 		// the last applied annotation to avoid unnecessary diffs
@@ -111,6 +191,19 @@ func (r *ResourceHelper) CreateSecretIfNotExists(owner v1.Object, secret *corev1
 	return nil
 }
 
+func (r *ResourceHelper) CreateConfigMapIfNotExists(owner v1.Object, configMap *corev1.ConfigMap, reqLogger logr.Logger) error {
+	foundConfigMap := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: configMap.Name, Namespace: configMap.Namespace}, foundConfigMap)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		reqLogger.Info("Creating config map", "name", configMap.Name)
+		return r.Create(owner, configMap, reqLogger)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to check if config map exists")
+	}
+
+	return nil
+}
+
 func (r *ResourceHelper) CreateRoleBindingIfNotExists(owner v1.Object, roleBinding *rbacv1.RoleBinding, reqLogger logr.Logger) error {
 	foundRoleBinding := &rbacv1.RoleBinding{}
 	err := r.client.Get(context.TODO(), types.NamespacedName{Name: roleBinding.Name, Namespace: roleBinding.Namespace}, foundRoleBinding)
@@ -190,6 +283,58 @@ func (r *ResourceHelper) CreateDeploymentIfNotExists(owner v1.Object, deployment
 	return nil
 }
 
+func (r *ResourceHelper) CreateStatefulSetIfNotExists(owner v1.Object, statefulSet *appsv1.StatefulSet, reqLogger logr.Logger) error {
+	foundStatefulSet := &appsv1.StatefulSet{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: statefulSet.Name, Namespace: statefulSet.Namespace}, foundStatefulSet)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		reqLogger.Info("Creating statefulset", "name", statefulSet.Name)
+		return r.Create(owner, statefulSet, reqLogger)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to check if statefulset exists")
+	}
+
+	return nil
+}
+
+func (r *ResourceHelper) CreateDaemonSetIfNotExists(owner v1.Object, daemonSet *appsv1.DaemonSet, reqLogger logr.Logger) error {
+	foundDaemonSet := &appsv1.DaemonSet{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: daemonSet.Name, Namespace: daemonSet.Namespace}, foundDaemonSet)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		reqLogger.Info("Creating daemonset", "name", daemonSet.Name)
+		return r.Create(owner, daemonSet, reqLogger)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to check if daemonset exists")
+	}
+
+	return nil
+}
+
+func (r *ResourceHelper) CreateJobIfNotExists(owner v1.Object, job *batchv1.Job, reqLogger logr.Logger) error {
+	foundJob := &batchv1.Job{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, foundJob)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		reqLogger.Info("Creating job", "name", job.Name)
+		return r.Create(owner, job, reqLogger)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to check if job exists")
+	}
+
+	return nil
+}
+
+func (r *ResourceHelper) CreateCronJobIfNotExists(owner v1.Object, cronJob *batchv1.CronJob, reqLogger logr.Logger) error {
+	foundCronJob := &batchv1.CronJob{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, foundCronJob)
+	if err != nil && k8sErrors.IsNotFound(err) {
+		reqLogger.Info("Creating cronjob", "name", cronJob.Name)
+		return r.Create(owner, cronJob, reqLogger)
+	} else if err != nil {
+		return errors.Wrap(err, "failed to check if cronjob exists")
+	}
+
+	return nil
+}
+
 func (r *ResourceHelper) CreateRoleIfNotExists(owner v1.Object, role *rbacv1.Role, reqLogger logr.Logger) error {
 	foundRole := &rbacv1.Role{}
 	err := r.client.Get(context.TODO(), types.NamespacedName{Name: role.Name, Namespace: role.Namespace}, foundRole)
@@ -0,0 +1,77 @@
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestDiff(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(s))
+
+	owner := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "owner", Namespace: "default", UID: "uid"}}
+	key := types.NamespacedName{Name: "foo", Namespace: "default"}
+
+	newStatefulSet := func() *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: v1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: appsv1.StatefulSetSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "foo", Image: "image:v1"}}},
+				},
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+					ObjectMeta: v1.ObjectMeta{Name: "data"},
+					Spec: corev1.PersistentVolumeClaimSpec{
+						AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+						},
+					},
+				}},
+			},
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(s).Build()
+	r := NewResourceHelper(c, s)
+	require.NoError(t, r.Create(owner, newStatefulSet(), log.Log))
+
+	current := &appsv1.StatefulSet{}
+	require.NoError(t, c.Get(context.TODO(), key, current))
+
+	// the empty status and claim template status of the generated object
+	// are not a change
+	patch, err := r.Diff(current, newStatefulSet())
+	assert.NoError(t, err)
+	assert.Nil(t, patch)
+
+	// neither are the status and the claim template type and status the
+	// API server fills in
+	current.Status = appsv1.StatefulSetStatus{Replicas: 1, ReadyReplicas: 1}
+	current.Spec.VolumeClaimTemplates[0].TypeMeta = v1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"}
+	current.Spec.VolumeClaimTemplates[0].Status.Phase = corev1.ClaimPending
+	patch, err = r.Diff(current, newStatefulSet())
+	assert.NoError(t, err)
+	assert.Nil(t, patch)
+
+	// while changes to the spec are
+	desired := newStatefulSet()
+	desired.Spec.Template.Spec.Containers[0].Image = "image:v2"
+	patch, err = r.Diff(current, desired)
+	require.NoError(t, err)
+	paths, err := PatchPaths(patch)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spec.template.spec.containers"}, paths)
+}